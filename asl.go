@@ -3,22 +3,36 @@ package aslconv
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
-	"github.com/agext/levenshtein"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 // https://states-language.net/spec.html#toplevelfields
 type AmazonStatesLanguage struct {
-	Version        *string `hcl:"version"`
-	Comment        *string `hcl:"comment"`
-	StartAt        string  `hcl:"start_at"`
-	TimeoutSeconds *int64  `hcl:"timeout_seconds"`
-	States         States  `hcl:"state,block"`
+	Version *string `hcl:"version"`
+	Comment *string `hcl:"comment"`
+	// QueryLanguage is "JSONPath" or "JSONata", the default is "JSONPath"
+	QueryLanguage  *string  `hcl:"query_language"`
+	StartAt        string   `hcl:"start_at"`
+	TimeoutSeconds *Integer `hcl:"timeout_seconds"`
+	States         States   `hcl:"state,block"`
+	// ProcessorConfig is used only in ItemProcessor of Map state.
+	ProcessorConfig *ProcessorConfig `hcl:"processor_config,block"`
+	// Extra holds the unknown top-level fields.
+	Extra map[string]RawMessage `json:"-" hcl:"extra,optional"`
+
+	ranges sourceRanges
+	// moduleSources are the sources of the modules being loaded, when the state machine is a module.
+	moduleSources []string
+	// sharedModules are the module blocks declared at the top level of the project, that are shared by the state machines.
+	sharedModules hcl.Blocks
 }
 
 type States []*State
@@ -27,23 +41,26 @@ func (top *AmazonStatesLanguage) MarshalJSON() ([]byte, error) {
 	data := map[string]interface{}{
 		"StartAt": top.StartAt,
 	}
-	states := make(map[string]json.RawMessage, len(top.States))
-	for _, s := range top.States {
-		bs, err := json.Marshal(s)
-		if err != nil {
-			return nil, fmt.Errorf("States[\"%s\"]:%w", s.Name, err)
-		}
-		states[s.Name] = bs
-	}
-	data["States"] = states
+	data["States"] = orderedStates(top.States)
 	if top.Version != nil {
 		data["Version"] = *top.Version
 	}
 	if top.Comment != nil {
 		data["Comment"] = top.Comment
 	}
+	if top.QueryLanguage != nil {
+		data["QueryLanguage"] = *top.QueryLanguage
+	}
 	if top.TimeoutSeconds != nil {
-		data["TimeSeconds"] = *top.TimeoutSeconds
+		data["TimeoutSeconds"] = top.TimeoutSeconds
+	}
+	if top.ProcessorConfig != nil {
+		data["ProcessorConfig"] = top.ProcessorConfig
+	}
+	for key, value := range top.Extra {
+		if _, ok := data[key]; !ok {
+			data[key] = value
+		}
 	}
 	return json.Marshal(data)
 }
@@ -59,11 +76,34 @@ func (top *AmazonStatesLanguage) UnmarshalJSON(bs []byte) error {
 	if err := json.Unmarshal(bs, &data); err != nil {
 		return err
 	}
+	var raw struct {
+		States json.RawMessage
+	}
+	if err := json.Unmarshal(bs, &raw); err != nil {
+		return err
+	}
 	top.States = make([]*State, 0, len(data.States))
-	for name, state := range data.States {
-		state.Name = name
-		top.States = append(top.States, state)
+	if len(raw.States) > 0 && string(raw.States) != "null" {
+		// keep the order of the states in the source
+		names, err := jsonObjectKeys(raw.States)
+		if err != nil {
+			return fmt.Errorf("States:%w", err)
+		}
+		for _, name := range names {
+			state, ok := data.States[name]
+			if !ok || state == nil {
+				continue
+			}
+			state.Name = name
+			top.States = append(top.States, state)
+			delete(data.States, name)
+		}
 	}
+	extra, err := unmarshalExtra(bs, top)
+	if err != nil {
+		return err
+	}
+	top.Extra = extra
 	return nil
 }
 
@@ -100,10 +140,22 @@ func (top *AmazonStatesLanguage) provideBodySchema() (*hcl.BodySchema, bool) {
 		{
 			Type: "locals",
 		},
+		variableBlockSchema,
+		moduleBlockSchema,
 	})...)
 	return schema, partial
 }
 
+// blocks returns the blocks of the content followed by the shared module blocks.
+func (top *AmazonStatesLanguage) blocks(content *hcl.BodyContent) hcl.Blocks {
+	if len(top.sharedModules) == 0 {
+		return content.Blocks
+	}
+	blocks := make(hcl.Blocks, 0, len(content.Blocks)+len(top.sharedModules))
+	blocks = append(blocks, content.Blocks...)
+	return append(blocks, top.sharedModules...)
+}
+
 func (top *AmazonStatesLanguage) evaluteVariables(content *hcl.BodyContent, ctx *hcl.EvalContext) (map[string]cty.Value, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	variables := make(map[string]map[string]cty.Value)
@@ -114,15 +166,36 @@ func (top *AmazonStatesLanguage) evaluteVariables(content *hcl.BodyContent, ctx
 			locals[key] = value
 		}
 	}
-	for _, block := range content.Blocks {
+	var modules map[string]cty.Value
+	// the states generated by for_each are not known until the locals referred by for_each are evaluated
+	statesKnown := true
+	for _, block := range top.blocks(content) {
 		switch block.Type {
+		case "module":
+			if modules == nil {
+				modules = make(map[string]cty.Value)
+			}
+			// the module is loaded only to know the first state, the diagnostics are reported by unmarshalHCLContent
+			start := cty.NullVal(cty.String)
+			if m, moduleDiags := loadModule(block, ctx, top.moduleSources); !moduleDiags.HasErrors() {
+				start = cty.StringVal(m.start())
+			}
+			modules[block.Labels[0]] = cty.ObjectVal(map[string]cty.Value{
+				"start": start,
+			})
 		case "state":
 			types, ok := variables[block.Labels[0]]
 			if !ok {
 				types = make(map[string]cty.Value)
 				variables[block.Labels[0]] = types
 			}
-			types[block.Labels[1]] = cty.StringVal(block.Labels[1])
+			instances, known, _ := expandStateBlock(block, ctx)
+			if !known {
+				statesKnown = false
+			}
+			for _, instance := range instances {
+				types[instance.name] = cty.StringVal(instance.name)
+			}
 		case "locals":
 			attrs, attrDiags := block.Body.JustAttributes()
 			diags = append(diags, attrDiags...)
@@ -136,51 +209,76 @@ func (top *AmazonStatesLanguage) evaluteVariables(content *hcl.BodyContent, ctx
 	for key, value := range variables {
 		typeVariabls[key] = cty.ObjectVal(value)
 	}
-	return map[string]cty.Value{
+	values := map[string]cty.Value{
 		"state": cty.ObjectVal(typeVariabls),
 		"local": cty.ObjectVal(locals),
-	}, nil
+	}
+	if !statesKnown {
+		values["state"] = cty.DynamicVal
+	}
+	if modules != nil {
+		values["module"] = cty.ObjectVal(modules)
+	}
+	return values, nil
+}
+
+// stateTypes are the state types keyed by the label of the state block.
+var stateTypes = map[string]string{
+	"task":     "Task",
+	"choice":   "Choice",
+	"fail":     "Fail",
+	"parallel": "Parallel",
+	"map":      "Map",
+	"succeed":  "Succeed",
+	"wait":     "Wait",
+	"pass":     "Pass",
+}
+
+// StateTypeOfLabel returns the state type of the label of the state block, e.g. "Task" for "task".
+func StateTypeOfLabel(label string) (string, bool) {
+	t, ok := stateTypes[label]
+	return t, ok
 }
 
 func (top *AmazonStatesLanguage) unmarshalHCLContent(content *hcl.BodyContent, _ hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
 	var diags hcl.Diagnostics
-	typeMap := map[string]string{
-		"task":     "Task",
-		"choice":   "Choice",
-		"fail":     "Fail",
-		"parallel": "Parallel",
-		"map":      "Map",
-		"succeed":  "Succeed",
-		"wait":     "Wait",
-		"pass":     "Pass",
-	}
-	typeList := make([]string, 0, len(typeMap))
-	for t := range typeMap {
+	typeList := make([]string, 0, len(stateTypes))
+	for t := range stateTypes {
 		typeList = append(typeList, t)
 	}
+	sort.Strings(typeList)
 	stateRange := make(map[string]*hcl.Range, len(content.Blocks))
-	for _, block := range content.Blocks {
+	moduleRange := make(map[string]*hcl.Range)
+	localRange := make(map[string]*hcl.Range)
+	for _, block := range top.blocks(content) {
 		switch block.Type {
+		case "locals":
+			// the values are evaluated by evaluteVariables, only the names across the files are checked here
+			attrs, _ := block.Body.JustAttributes()
+			for name, attr := range attrs {
+				if r, ok := localRange[name]; ok {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Duplicate local value",
+						Detail:   fmt.Sprintf(`A local value named "%s" was already declared at %s. Local value names must unique`, name, r.String()),
+						Subject:  attr.NameRange.Ptr(),
+					})
+					continue
+				}
+				localRange[name] = attr.NameRange.Ptr()
+			}
 		case "state":
-			var state State
 			label := block.Labels[0]
-			t, ok := typeMap[label]
+			t, ok := stateTypes[label]
 			if !ok {
-				var isSuggested bool
-				for _, suggestion := range typeList {
-					dist := levenshtein.Distance(label, suggestion, nil)
-					if dist < 3 {
-						diags = append(diags, &hcl.Diagnostic{
-							Severity: hcl.DiagError,
-							Summary:  "Invalid state type",
-							Detail:   fmt.Sprintf(`The state type "%s" is invalid. Did you mean "%s"?`, label, suggestion),
-							Subject:  block.DefRange.Ptr(),
-						})
-						isSuggested = true
-						break
-					}
-				}
-				if !isSuggested {
+				if suggestion, ok := nameSuggestion(label, typeList); ok {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid state type",
+						Detail:   fmt.Sprintf(`The state type "%s" is invalid. Did you mean "%s"?`, label, suggestion),
+						Subject:  block.DefRange.Ptr(),
+					})
+				} else {
 					diags = append(diags, &hcl.Diagnostic{
 						Severity: hcl.DiagError,
 						Summary:  "Invalid state type",
@@ -190,23 +288,81 @@ func (top *AmazonStatesLanguage) unmarshalHCLContent(content *hcl.BodyContent, _
 				}
 				continue
 			}
-			state.Type = t
-			state.Name = block.Labels[1]
-			if r, ok := stateRange[state.Name]; ok {
+			instances, _, expandDiags := expandStateBlock(block, ctx)
+			diags = append(diags, expandDiags...)
+			for _, instance := range instances {
+				state := State{
+					Type:   t,
+					Name:   instance.name,
+					ranges: sourceRanges{"": block.DefRange.Ptr()},
+				}
+				if r, ok := stateRange[state.Name]; ok {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  `Duplicate "state" name`,
+						Detail:   fmt.Sprintf(`A state named "%s" was already declared at %s. State names must unique`, state.Name, r.String()),
+						Subject:  block.DefRange.Ptr(),
+					})
+				} else {
+					stateRange[state.Name] = block.DefRange.Ptr()
+				}
+				diags = append(diags, unmarshalHCLBody(instance.body, instance.ctx, &state)...)
+				top.States = append(top.States, &state)
+			}
+		case "module":
+			name := block.Labels[0]
+			if r, ok := moduleRange[name]; ok {
 				diags = append(diags, &hcl.Diagnostic{
 					Severity: hcl.DiagError,
-					Summary:  `Duplicate "state" name`,
-					Detail:   fmt.Sprintf(`A state named "%s" was already declared at %s. State names must unique`, state.Name, r.String()),
+					Summary:  `Duplicate "module" name`,
+					Detail:   fmt.Sprintf(`A module named "%s" was already declared at %s. Module names must unique`, name, r.String()),
 					Subject:  block.DefRange.Ptr(),
 				})
-			} else {
+				continue
+			}
+			moduleRange[name] = block.DefRange.Ptr()
+			m, moduleDiags := loadModule(block, ctx, top.moduleSources)
+			diags = append(diags, moduleDiags...)
+			if moduleDiags.HasErrors() {
+				continue
+			}
+			for _, state := range m.states() {
+				if r, ok := stateRange[state.Name]; ok {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  `Duplicate "state" name`,
+						Detail:   fmt.Sprintf(`The module "%s" declares the state "%s", that was already declared at %s. State names must unique`, name, state.Name, r.String()),
+						Subject:  block.DefRange.Ptr(),
+					})
+					continue
+				}
 				stateRange[state.Name] = block.DefRange.Ptr()
+				top.States = append(top.States, state)
 			}
-			diags = append(diags, unmarshalHCLBody(block.Body, ctx, &state)...)
-			top.States = append(top.States, &state)
+		case "processor_config":
+			if top.ProcessorConfig != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  `Duplicate "processor_config" block`,
+					Detail:   fmt.Sprintf(`Only one "processor_config" block is allowed. Another was defined at %s`, top.ranges.get("processor_config").String()),
+					Subject:  block.DefRange.Ptr(),
+				})
+				continue
+			}
+			if top.ranges == nil {
+				top.ranges = make(sourceRanges)
+			}
+			top.ranges["processor_config"] = block.DefRange.Ptr()
+			var config ProcessorConfig
+			diags = append(diags, decodeBody(block.Body, ctx, &config)...)
+			top.ProcessorConfig = &config
 		}
 	}
+	if top.ranges == nil {
+		top.ranges = make(sourceRanges)
+	}
 	for _, attr := range content.Attributes {
+		top.ranges[attr.Name] = attr.Expr.Range().Ptr()
 		switch attr.Name {
 		case "comment":
 			decodeDiags := decodeExpression(attr.Expr, ctx, &top.Comment)
@@ -214,12 +370,18 @@ func (top *AmazonStatesLanguage) unmarshalHCLContent(content *hcl.BodyContent, _
 		case "version":
 			decodeDiags := decodeExpression(attr.Expr, ctx, &top.Version)
 			diags = append(diags, decodeDiags...)
+		case "query_language":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &top.QueryLanguage)
+			diags = append(diags, decodeDiags...)
 		case "timeout_seconds":
-			decodeDiags := decodeExpression(attr.Expr, ctx, &top.TimeoutSeconds)
+			decodeDiags := decodeInteger(attr.Expr, ctx, &top.TimeoutSeconds)
 			diags = append(diags, decodeDiags...)
 		case "start_at":
 			decodeDiags := decodeExpression(attr.Expr, ctx, &top.StartAt)
 			diags = append(diags, decodeDiags...)
+		case "extra":
+			decodeDiags := decodeExtra(attr.Expr, ctx, knownJSONNames(top), &top.Extra)
+			diags = append(diags, decodeDiags...)
 		}
 	}
 	return diags
@@ -232,14 +394,32 @@ func (top *AmazonStatesLanguage) EncodeBody(body *hclwrite.Body) error {
 	if top.Comment != nil {
 		body.SetAttributeValue("comment", cty.StringVal(*top.Comment))
 	}
+	if top.QueryLanguage != nil {
+		body.SetAttributeValue("query_language", cty.StringVal(*top.QueryLanguage))
+	}
 	if top.TimeoutSeconds != nil {
-		body.SetAttributeValue("timeout_seconds", cty.NumberIntVal(*top.TimeoutSeconds))
+		body.SetAttributeValue("timeout_seconds", top.TimeoutSeconds.ctyValue())
 	}
 	startAtTraversal, err := top.States.getTraversal(top.StartAt)
 	if err != nil {
 		return fmt.Errorf("start_at:%w", err)
 	}
 	body.SetAttributeTraversal("start_at", startAtTraversal)
+	if len(top.Extra) > 0 {
+		value, err := extraCtyValue(top.Extra)
+		if err != nil {
+			return fmt.Errorf("extra:%w", err)
+		}
+		body.SetAttributeValue("extra", value)
+	}
+	if top.ProcessorConfig != nil {
+		block, err := encodeAsBlock(top.ProcessorConfig, "processor_config")
+		if err != nil {
+			return fmt.Errorf("processor_config:%w", err)
+		}
+		body.AppendNewline()
+		body.AppendBlock(block)
+	}
 	for _, state := range top.States {
 		block, err := state.EncodeAsBlock(top.States)
 		if err != nil {
@@ -271,34 +451,104 @@ func (states States) getTraversal(stateName string) (hcl.Traversal, error) {
 }
 
 type State struct {
-	Type           string                  `json:"Type,omitempty" hcl:"type,label"`
-	Name           string                  `json:"-" hcl:"name,label"`
-	Comment        *string                 `json:"Comment,omitempty" hcl:"comment"`
-	Resource       *string                 `json:"Resource,omitempty" hcl:"resource"`
-	Default        *string                 `json:"Default,omitempty" hcl:"default"`
-	Seconds        *int64                  `json:"Seconds,omitempty" hcl:"seconds"`
-	MaxConcurrency *int64                  `json:"MaxConcurrency,omitempty" hcl:"max_concurrency"`
-	Next           *string                 `json:"Next,omitempty" hcl:"next"`
-	ItemsPath      *string                 `json:"ItemsPath,omitempty" hcl:"items_path"`
-	InputPath      *string                 `json:"InputPath,omitempty" hcl:"input_path"`
-	OutputPath     *string                 `json:"OutputPath,omitempty" hcl:"output_path"`
-	ResultPath     *string                 `json:"ResultPath,omitempty" hcl:"result_path"`
-	End            *bool                   `json:"End,omitempty" hcl:"end"`
-	Error          *string                 `json:"Error,omitempty" hcl:"error"`
-	Cause          *string                 `json:"Cause,omitempty" hcl:"cause"`
-	Retry          RawMessages             `json:"Retry,omitempty" hcl:"retry,optional"`
-	Catch          RawMessages             `json:"Catch,omitempty" hcl:"catch,optional"`
-	Parameters     RawMessage              `json:"Parameters,omitempty" hcl:"parameters,optional"`
-	Result         RawMessage              `json:"Result,omitempty" hcl:"result,optional"`
-	ResultSelector RawMessage              `json:"ResultSelector,omitempty" hcl:"result_selector,optional"`
-	Choices        RawMessages             `json:"Choices,omitempty" hcl:"choices,optional"`
-	Branches       []*AmazonStatesLanguage `json:"Branches,omitempty" hcl:"branch,block"`
-	Iterator       *AmazonStatesLanguage   `json:"Iterator,omitempty" hcl:"iterator,block"`
+	Type                           string                  `json:"Type,omitempty" hcl:"type,label"`
+	Name                           string                  `json:"-" hcl:"name,label"`
+	Comment                        *string                 `json:"Comment,omitempty" hcl:"comment"`
+	QueryLanguage                  *string                 `json:"QueryLanguage,omitempty" hcl:"query_language"`
+	Resource                       *string                 `json:"Resource,omitempty" hcl:"resource"`
+	TimeoutSeconds                 *Integer                `json:"TimeoutSeconds,omitempty" hcl:"timeout_seconds"`
+	TimeoutSecondsPath             *string                 `json:"TimeoutSecondsPath,omitempty" hcl:"timeout_seconds_path"`
+	HeartbeatSeconds               *Integer                `json:"HeartbeatSeconds,omitempty" hcl:"heartbeat_seconds"`
+	HeartbeatSecondsPath           *string                 `json:"HeartbeatSecondsPath,omitempty" hcl:"heartbeat_seconds_path"`
+	Credentials                    RawMessage              `json:"Credentials,omitempty" hcl:"credentials,optional"`
+	Default                        *string                 `json:"Default,omitempty" hcl:"default"`
+	Seconds                        *Integer                `json:"Seconds,omitempty" hcl:"seconds"`
+	Timestamp                      *string                 `json:"Timestamp,omitempty" hcl:"timestamp"`
+	SecondsPath                    *string                 `json:"SecondsPath,omitempty" hcl:"seconds_path"`
+	TimestampPath                  *string                 `json:"TimestampPath,omitempty" hcl:"timestamp_path"`
+	MaxConcurrency                 *Integer                `json:"MaxConcurrency,omitempty" hcl:"max_concurrency"`
+	MaxConcurrencyPath             *string                 `json:"MaxConcurrencyPath,omitempty" hcl:"max_concurrency_path"`
+	ToleratedFailureCount          *Integer                `json:"ToleratedFailureCount,omitempty" hcl:"tolerated_failure_count"`
+	ToleratedFailureCountPath      *string                 `json:"ToleratedFailureCountPath,omitempty" hcl:"tolerated_failure_count_path"`
+	ToleratedFailurePercentage     *Number                 `json:"ToleratedFailurePercentage,omitempty" hcl:"tolerated_failure_percentage"`
+	ToleratedFailurePercentagePath *string                 `json:"ToleratedFailurePercentagePath,omitempty" hcl:"tolerated_failure_percentage_path"`
+	Label                          *string                 `json:"Label,omitempty" hcl:"label"`
+	Next                           *string                 `json:"Next,omitempty" hcl:"next"`
+	ItemsPath                      *string                 `json:"ItemsPath,omitempty" hcl:"items_path"`
+	ItemSelector                   RawMessage              `json:"ItemSelector,omitempty" hcl:"item_selector,optional"`
+	Items                          RawMessage              `json:"Items,omitempty" hcl:"items,optional"`
+	InputPath                      *string                 `json:"InputPath,omitempty" hcl:"input_path"`
+	OutputPath                     *string                 `json:"OutputPath,omitempty" hcl:"output_path"`
+	ResultPath                     *string                 `json:"ResultPath,omitempty" hcl:"result_path"`
+	End                            *bool                   `json:"End,omitempty" hcl:"end"`
+	Error                          *string                 `json:"Error,omitempty" hcl:"error"`
+	Cause                          *string                 `json:"Cause,omitempty" hcl:"cause"`
+	Retry                          Retriers                `json:"Retry,omitempty" hcl:"retry,block"`
+	Catch                          Catchers                `json:"Catch,omitempty" hcl:"catch,block"`
+	Parameters                     RawMessage              `json:"Parameters,omitempty" hcl:"parameters,optional"`
+	Arguments                      RawMessage              `json:"Arguments,omitempty" hcl:"arguments,optional"`
+	Result                         RawMessage              `json:"Result,omitempty" hcl:"result,optional"`
+	ResultSelector                 RawMessage              `json:"ResultSelector,omitempty" hcl:"result_selector,optional"`
+	Output                         RawMessage              `json:"Output,omitempty" hcl:"output,optional"`
+	Assign                         RawMessage              `json:"Assign,omitempty" hcl:"assign,optional"`
+	Choices                        ChoiceRules             `json:"Choices,omitempty" hcl:"choice,block"`
+	Branches                       []*AmazonStatesLanguage `json:"Branches,omitempty" hcl:"branch,block"`
+	Iterator                       *AmazonStatesLanguage   `json:"Iterator,omitempty" hcl:"iterator,block"`
+	ItemProcessor                  *AmazonStatesLanguage   `json:"ItemProcessor,omitempty" hcl:"item_processor,block"`
+	ItemReader                     *ItemReader             `json:"ItemReader,omitempty" hcl:"item_reader,block"`
+	ItemBatcher                    *ItemBatcher            `json:"ItemBatcher,omitempty" hcl:"item_batcher,block"`
+	ResultWriter                   *ResultWriter           `json:"ResultWriter,omitempty" hcl:"result_writer,block"`
+	// Extra holds the unknown fields of the state.
+	Extra map[string]RawMessage `json:"-" hcl:"extra,optional"`
+
+	ranges sourceRanges
+}
+
+func (state *State) MarshalJSON() ([]byte, error) {
+	type alias State
+	bs, err := json.Marshal((*alias)(state))
+	if err != nil {
+		return nil, err
+	}
+	return marshalExtra(bs, state.Extra)
+}
+
+func (state *State) UnmarshalJSON(bs []byte) error {
+	type alias State
+	if err := json.Unmarshal(bs, (*alias)(state)); err != nil {
+		return err
+	}
+	extra, err := unmarshalExtra(bs, state)
+	if err != nil {
+		return err
+	}
+	state.Extra = extra
+	return nil
+}
+
+func (state *State) provideBodySchema() (*hcl.BodySchema, bool) {
+	schema, partial := gohcl.ImpliedBodySchema(state)
+	schema.Attributes = append(schema.Attributes, ([]hcl.AttributeSchema{
+		{
+			Name: "retry",
+		},
+		{
+			Name: "catch",
+		},
+		{
+			Name: "choices",
+		},
+	})...)
+	return schema, partial
 }
 
 func (state *State) unmarshalHCLContent(content *hcl.BodyContent, _ hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
-	var diags hcl.Diagnostics
-	for _, attr := range content.Attributes {
+	attrs, blocks, diags := state.validateHCLNames(content)
+	if state.ranges == nil {
+		state.ranges = make(sourceRanges)
+	}
+	for _, attr := range attrs {
+		state.ranges[attr.Name] = attr.Expr.Range().Ptr()
 		switch attr.Name {
 		case "comment":
 			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Comment)
@@ -306,6 +556,21 @@ func (state *State) unmarshalHCLContent(content *hcl.BodyContent, _ hcl.Body, ct
 		case "resource":
 			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Resource)
 			diags = append(diags, decodeDiags...)
+		case "timeout_seconds":
+			decodeDiags := decodeInteger(attr.Expr, ctx, &state.TimeoutSeconds)
+			diags = append(diags, decodeDiags...)
+		case "timeout_seconds_path":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.TimeoutSecondsPath)
+			diags = append(diags, decodeDiags...)
+		case "heartbeat_seconds":
+			decodeDiags := decodeInteger(attr.Expr, ctx, &state.HeartbeatSeconds)
+			diags = append(diags, decodeDiags...)
+		case "heartbeat_seconds_path":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.HeartbeatSecondsPath)
+			diags = append(diags, decodeDiags...)
+		case "credentials":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Credentials)
+			diags = append(diags, decodeDiags...)
 		case "default":
 			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Default)
 			diags = append(diags, decodeDiags...)
@@ -316,7 +581,28 @@ func (state *State) unmarshalHCLContent(content *hcl.BodyContent, _ hcl.Body, ct
 			decodeDiags := decodeExpression(attr.Expr, ctx, &state.End)
 			diags = append(diags, decodeDiags...)
 		case "max_concurrency":
-			decodeDiags := decodeExpression(attr.Expr, ctx, &state.MaxConcurrency)
+			decodeDiags := decodeInteger(attr.Expr, ctx, &state.MaxConcurrency)
+			diags = append(diags, decodeDiags...)
+		case "max_concurrency_path":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.MaxConcurrencyPath)
+			diags = append(diags, decodeDiags...)
+		case "tolerated_failure_count":
+			decodeDiags := decodeInteger(attr.Expr, ctx, &state.ToleratedFailureCount)
+			diags = append(diags, decodeDiags...)
+		case "tolerated_failure_count_path":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.ToleratedFailureCountPath)
+			diags = append(diags, decodeDiags...)
+		case "tolerated_failure_percentage":
+			decodeDiags := decodeNumber(attr.Expr, ctx, &state.ToleratedFailurePercentage)
+			diags = append(diags, decodeDiags...)
+		case "tolerated_failure_percentage_path":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.ToleratedFailurePercentagePath)
+			diags = append(diags, decodeDiags...)
+		case "label":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Label)
+			diags = append(diags, decodeDiags...)
+		case "item_selector":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.ItemSelector)
 			diags = append(diags, decodeDiags...)
 		case "items_path":
 			decodeDiags := decodeExpression(attr.Expr, ctx, &state.ItemsPath)
@@ -354,16 +640,46 @@ func (state *State) unmarshalHCLContent(content *hcl.BodyContent, _ hcl.Body, ct
 		case "parameters":
 			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Parameters)
 			diags = append(diags, decodeDiags...)
+		case "arguments":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Arguments)
+			diags = append(diags, decodeDiags...)
+		case "output":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Output)
+			diags = append(diags, decodeDiags...)
+		case "assign":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Assign)
+			diags = append(diags, decodeDiags...)
+		case "items":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Items)
+			diags = append(diags, decodeDiags...)
+		case "query_language":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.QueryLanguage)
+			diags = append(diags, decodeDiags...)
 		case "seconds":
-			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Seconds)
+			decodeDiags := decodeInteger(attr.Expr, ctx, &state.Seconds)
+			diags = append(diags, decodeDiags...)
+		case "timestamp":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.Timestamp)
+			diags = append(diags, decodeDiags...)
+		case "seconds_path":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.SecondsPath)
+			diags = append(diags, decodeDiags...)
+		case "timestamp_path":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &state.TimestampPath)
+			diags = append(diags, decodeDiags...)
+		case "extra":
+			decodeDiags := decodeExtra(attr.Expr, ctx, knownJSONNames(state), &state.Extra)
 			diags = append(diags, decodeDiags...)
 		}
 	}
 	var iteratorRange *hcl.Range
-	for _, block := range content.Blocks {
+	for _, block := range blocks {
 		switch block.Type {
 		case "branch":
-			var asl AmazonStatesLanguage
+			asl := AmazonStatesLanguage{
+				ranges: sourceRanges{"": block.DefRange.Ptr()},
+			}
+			diags = append(diags, rejectTopLevelOnlyBlocks(block.Body)...)
 			decodeDiags := asl.DecodeBody(block.Body, ctx.NewChild())
 			diags = append(diags, decodeDiags...)
 			state.Branches = append(state.Branches, &asl)
@@ -377,11 +693,64 @@ func (state *State) unmarshalHCLContent(content *hcl.BodyContent, _ hcl.Body, ct
 				})
 				continue
 			}
-			var asl AmazonStatesLanguage
+			asl := AmazonStatesLanguage{
+				ranges: sourceRanges{"": block.DefRange.Ptr()},
+			}
+			diags = append(diags, rejectTopLevelOnlyBlocks(block.Body)...)
 			decodeDiags := asl.DecodeBody(block.Body, ctx.NewChild())
 			diags = append(diags, decodeDiags...)
 			state.Iterator = &asl
 			iteratorRange = block.DefRange.Ptr()
+		case "item_processor", "item_reader", "item_batcher", "result_writer":
+			if defRange, ok := state.ranges[block.Type]; ok {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf(`Duplicate "%s" block`, block.Type),
+					Detail:   fmt.Sprintf(`Only one "%s" block is allowed. Another was defined at %s`, block.Type, defRange.String()),
+					Subject:  block.DefRange.Ptr(),
+				})
+				continue
+			}
+			state.ranges[block.Type] = block.DefRange.Ptr()
+			switch block.Type {
+			case "item_processor":
+				asl := AmazonStatesLanguage{
+					ranges: sourceRanges{"": block.DefRange.Ptr()},
+				}
+				diags = append(diags, rejectTopLevelOnlyBlocks(block.Body)...)
+				decodeDiags := asl.DecodeBody(block.Body, ctx.NewChild())
+				diags = append(diags, decodeDiags...)
+				state.ItemProcessor = &asl
+			case "item_reader":
+				var reader ItemReader
+				diags = append(diags, decodeBody(block.Body, ctx, &reader)...)
+				state.ItemReader = &reader
+			case "item_batcher":
+				var batcher ItemBatcher
+				diags = append(diags, decodeBody(block.Body, ctx, &batcher)...)
+				state.ItemBatcher = &batcher
+			case "result_writer":
+				var writer ResultWriter
+				diags = append(diags, decodeBody(block.Body, ctx, &writer)...)
+				state.ResultWriter = &writer
+			}
+		case "retry":
+			var retrier Retrier
+			decodeDiags := retrier.decodeBody(block, ctx)
+			diags = append(diags, decodeDiags...)
+			state.Retry = append(state.Retry, &retrier)
+		case "catch":
+			var catcher Catcher
+			decodeDiags := catcher.decodeBody(block, ctx)
+			diags = append(diags, decodeDiags...)
+			state.Catch = append(state.Catch, &catcher)
+		case "choice":
+			rule := ChoiceRule{
+				ranges: sourceRanges{"": block.DefRange.Ptr()},
+			}
+			decodeDiags := unmarshalHCLBody(block.Body, ctx, &rule)
+			diags = append(diags, decodeDiags...)
+			state.Choices = append(state.Choices, &rule)
 		}
 	}
 	return diags
@@ -391,6 +760,21 @@ func (state *State) EncodeAsBlock(states States) (*hclwrite.Block, error) {
 	cloned := *state
 	cloned.Branches = nil
 	cloned.Iterator = nil
+	cloned.Choices = nil
+	cloned.Retry = nil
+	cloned.Catch = nil
+	cloned.ItemProcessor = nil
+	cloned.ItemReader = nil
+	cloned.ItemBatcher = nil
+	cloned.ResultWriter = nil
+	cloned.Extra = nil
+	// the fields that can be JSONata expressions are not encodable by gohcl, they are set below
+	cloned.TimeoutSeconds = nil
+	cloned.HeartbeatSeconds = nil
+	cloned.Seconds = nil
+	cloned.MaxConcurrency = nil
+	cloned.ToleratedFailureCount = nil
+	cloned.ToleratedFailurePercentage = nil
 	block := gohcl.EncodeAsBlock(&cloned, "state")
 	block.SetLabels([]string{strings.ToLower(state.Type), state.Name})
 	body := block.Body()
@@ -408,71 +792,77 @@ func (state *State) EncodeAsBlock(states States) (*hclwrite.Block, error) {
 		}
 		body.SetAttributeTraversal("next", traversal)
 	}
-	if state.Parameters != nil {
-		bs, err := json.Marshal(state.Parameters)
-		if err != nil {
-			return nil, fmt.Errorf("parameter:%w", err)
+	for _, field := range []struct {
+		name  string
+		value *Integer
+	}{
+		{"timeout_seconds", state.TimeoutSeconds},
+		{"heartbeat_seconds", state.HeartbeatSeconds},
+		{"seconds", state.Seconds},
+		{"max_concurrency", state.MaxConcurrency},
+		{"tolerated_failure_count", state.ToleratedFailureCount},
+	} {
+		if field.value != nil {
+			body.SetAttributeValue(field.name, field.value.ctyValue())
 		}
-		body.SetAttributeValue("parameters", cty.StringVal(string(bs)))
-	} else {
-		body.RemoveAttribute("parameters")
 	}
-	if state.Result != nil {
-		bs, err := json.Marshal(state.Result)
-		if err != nil {
-			return nil, fmt.Errorf("result:%w", err)
+	if state.ToleratedFailurePercentage != nil {
+		body.SetAttributeValue("tolerated_failure_percentage", state.ToleratedFailurePercentage.ctyValue())
+	}
+	sortAttributes(body, state)
+	for _, field := range []struct {
+		name  string
+		value RawMessage
+	}{
+		{"parameters", state.Parameters},
+		{"arguments", state.Arguments},
+		{"credentials", state.Credentials},
+		{"result", state.Result},
+		{"result_selector", state.ResultSelector},
+		{"item_selector", state.ItemSelector},
+		{"items", state.Items},
+		{"output", state.Output},
+		{"assign", state.Assign},
+	} {
+		if field.value == nil {
+			body.RemoveAttribute(field.name)
+			continue
+		}
+		if err := field.value.setAttribute(body, field.name); err != nil {
+			return nil, fmt.Errorf("%s:%w", field.name, err)
 		}
-		body.SetAttributeValue("result", cty.StringVal(string(bs)))
-	} else {
-		body.RemoveAttribute("result")
 	}
-	if state.ResultSelector != nil {
-		bs, err := json.Marshal(state.ResultSelector)
+	if len(state.Extra) > 0 {
+		value, err := extraCtyValue(state.Extra)
 		if err != nil {
-			return nil, fmt.Errorf("result_selector:%w", err)
+			return nil, fmt.Errorf("extra:%w", err)
 		}
-		body.SetAttributeValue("result_selector", cty.StringVal(string(bs)))
+		body.SetAttributeValue("extra", value)
 	} else {
-		body.RemoveAttribute("result_selector")
-	}
-	if len(state.Retry) > 0 {
-		values := make([]cty.Value, 0, len(state.Choices))
-		for i, c := range state.Retry {
-			bs, err := json.Marshal(c)
-			if err != nil {
-				return nil, fmt.Errorf("retry[%d]:%w", i, err)
-			}
-			values = append(values, cty.StringVal(string(bs)))
+		body.RemoveAttribute("extra")
+	}
+	for i, retrier := range state.Retry {
+		retryBlock, err := retrier.EncodeAsBlock()
+		if err != nil {
+			return nil, fmt.Errorf("retry[%d]:%w", i, err)
 		}
-		body.SetAttributeValue("retry", cty.ListVal(values))
-	} else {
-		body.RemoveAttribute("retry")
-	}
-	if len(state.Catch) > 0 {
-		values := make([]cty.Value, 0, len(state.Choices))
-		for i, c := range state.Catch {
-			bs, err := json.Marshal(c)
-			if err != nil {
-				return nil, fmt.Errorf("catch[%d]:%w", i, err)
-			}
-			values = append(values, cty.StringVal(string(bs)))
+		body.AppendNewline()
+		body.AppendBlock(retryBlock)
+	}
+	for i, catcher := range state.Catch {
+		catchBlock, err := catcher.EncodeAsBlock(states)
+		if err != nil {
+			return nil, fmt.Errorf("catch[%d]:%w", i, err)
 		}
-		body.SetAttributeValue("catch", cty.ListVal(values))
-	} else {
-		body.RemoveAttribute("catch")
-	}
-	if len(state.Choices) > 0 {
-		values := make([]cty.Value, 0, len(state.Choices))
-		for i, c := range state.Choices {
-			bs, err := json.Marshal(c)
-			if err != nil {
-				return nil, fmt.Errorf("choices[%d]:%w", i, err)
-			}
-			values = append(values, cty.StringVal(string(bs)))
+		body.AppendNewline()
+		body.AppendBlock(catchBlock)
+	}
+	for i, rule := range state.Choices {
+		body.AppendNewline()
+		choiceBlock := body.AppendNewBlock("choice", nil)
+		if err := rule.encodeBody(choiceBlock.Body(), states); err != nil {
+			return nil, fmt.Errorf("choices[%d]:%w", i, err)
 		}
-		body.SetAttributeValue("choices", cty.ListVal(values))
-	} else {
-		body.RemoveAttribute("choices")
 	}
 	if len(state.Branches) > 0 {
 		for _, branch := range state.Branches {
@@ -490,6 +880,37 @@ func (state *State) EncodeAsBlock(states States) (*hclwrite.Block, error) {
 			return nil, err
 		}
 	}
+	if state.ItemReader != nil {
+		readerBlock, err := encodeAsBlock(state.ItemReader, "item_reader")
+		if err != nil {
+			return nil, fmt.Errorf("item_reader:%w", err)
+		}
+		body.AppendNewline()
+		body.AppendBlock(readerBlock)
+	}
+	if state.ItemBatcher != nil {
+		batcherBlock, err := encodeAsBlock(state.ItemBatcher, "item_batcher")
+		if err != nil {
+			return nil, fmt.Errorf("item_batcher:%w", err)
+		}
+		body.AppendNewline()
+		body.AppendBlock(batcherBlock)
+	}
+	if state.ResultWriter != nil {
+		writerBlock, err := encodeAsBlock(state.ResultWriter, "result_writer")
+		if err != nil {
+			return nil, fmt.Errorf("result_writer:%w", err)
+		}
+		body.AppendNewline()
+		body.AppendBlock(writerBlock)
+	}
+	if state.ItemProcessor != nil {
+		body.AppendNewline()
+		processorBlock := body.AppendNewBlock("item_processor", []string{})
+		if err := state.ItemProcessor.EncodeBody(processorBlock.Body()); err != nil {
+			return nil, err
+		}
+	}
 	return block, nil
 }
 
@@ -508,13 +929,92 @@ func (m *RawMessage) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// decodeExpression accepts any HCL value and converts it to JSON.
+// For compatibility, a string that is valid JSON (e.g. the result of jsonencode) is used as it is,
+// and the other strings are converted to JSON strings.
 func (m *RawMessage) decodeExpression(expr hcl.Expression, ctx *hcl.EvalContext) hcl.Diagnostics {
-	var raw string
-	diags := decodeExpression(expr, ctx, &raw)
-	*m = RawMessage(raw)
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return diags
+	}
+	if value.IsNull() {
+		*m = nil
+		return diags
+	}
+	if !value.IsWhollyKnown() {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid JSON value",
+			Detail:   "The value must be known, but it depends on unknown values.",
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	// the string of a JSON object or array, and the result of jsonencode, are the JSON values written in the legacy way
+	if value.Type() == cty.String {
+		raw := strings.TrimSpace(value.AsString())
+		if (isLegacyJSONString(raw) || isJSONEncodeCall(expr)) && json.Valid([]byte(raw)) {
+			*m = RawMessage(raw)
+			return diags
+		}
+	}
+	bs, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid JSON value",
+			Detail:   fmt.Sprintf("The value can not be converted to JSON: %s.", err),
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	*m = RawMessage(bs)
 	return diags
 }
 
+// isLegacyJSONString reports whether the string looks like a JSON object or array, that is decoded as the JSON value it contains.
+func isLegacyJSONString(s string) bool {
+	return strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")
+}
+
+// isJSONEncodeCall reports whether the expression is a call of jsonencode.
+func isJSONEncodeCall(expr hcl.Expression) bool {
+	call, ok := expr.(*hclsyntax.FunctionCallExpr)
+	return ok && call.Name == "jsonencode"
+}
+
+// setAttribute sets the JSON value to the attribute of the body.
+// A JSON string that contains a JSON object or array such as "[1]" is written as jsonencode("[1]"),
+// because the string literal is decoded as the JSON value it contains.
+func (m RawMessage) setAttribute(body *hclwrite.Body, name string) error {
+	value, err := m.ctyValue()
+	if err != nil {
+		return err
+	}
+	if value.Type() != cty.String {
+		body.SetAttributeValue(name, value)
+		return nil
+	}
+	if raw := strings.TrimSpace(value.AsString()); !isLegacyJSONString(raw) || !json.Valid([]byte(raw)) {
+		body.SetAttributeValue(name, value)
+		return nil
+	}
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte("jsonencode")},
+		{Type: hclsyntax.TokenOParen, Bytes: []byte("(")},
+	}
+	tokens = append(tokens, hclwrite.TokensForValue(value)...)
+	tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte(")")})
+	body.SetAttributeRaw(name, tokens)
+	return nil
+}
+
+func (m RawMessage) ctyValue() (cty.Value, error) {
+	ty, err := ctyjson.ImpliedType(m)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(m, ty)
+}
+
 type RawMessages []RawMessage
 
 func (ms *RawMessages) decodeExpression(expr hcl.Expression, ctx *hcl.EvalContext) hcl.Diagnostics {
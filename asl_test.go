@@ -1,7 +1,10 @@
 package aslconv_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"strings"
 	"testing"
@@ -10,6 +13,7 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/mashiike/aslconv"
 	"github.com/sebdah/goldie/v2"
@@ -27,6 +31,7 @@ func requireASLEq(t *testing.T, expected *aslconv.AmazonStatesLanguage, actual *
 	t.Helper()
 	diff := cmp.Diff(
 		expected, actual,
+		cmpopts.IgnoreUnexported(aslconv.AmazonStatesLanguage{}, aslconv.State{}, aslconv.ChoiceRule{}, aslconv.Retrier{}, aslconv.Catcher{}),
 		cmpopts.SortSlices(func(x, y *aslconv.State) bool {
 			return x.Name < y.Name
 		}),
@@ -54,17 +59,19 @@ var sampleASL = &aslconv.AmazonStatesLanguage{
 		{
 			Name: "ChoiceState",
 			Type: "Choice",
-			Choices: []aslconv.RawMessage{
-				aslconv.RawMessage(`{
-					"Variable": "$.foo",
-					"NumericEquals": 1,
-					"Next": "FirstMatchState"
-				}`),
-				aslconv.RawMessage(`{
-					"Variable": "$.foo",
-					"NumericEquals": 2,
-					"Next": "SecondMatchState"
-				  }`),
+			Choices: aslconv.ChoiceRules{
+				{
+					Variable: ptr("$.foo"),
+					Operator: "NumericEquals",
+					Value:    aslconv.RawMessage(`1`),
+					Next:     ptr("FirstMatchState"),
+				},
+				{
+					Variable: ptr("$.foo"),
+					Operator: "NumericEquals",
+					Value:    aslconv.RawMessage(`2`),
+					Next:     ptr("SecondMatchState"),
+				},
 			},
 			Default: ptr("DefaultState"),
 		},
@@ -140,7 +147,7 @@ var othersASL = &aslconv.AmazonStatesLanguage{
 			Type:           "Map",
 			InputPath:      ptr("$.detail"),
 			ItemsPath:      ptr("$.shipped"),
-			MaxConcurrency: ptr(int64(0)),
+			MaxConcurrency: &aslconv.Integer{Value: 0},
 			Iterator: &aslconv.AmazonStatesLanguage{
 				StartAt: "Validate",
 				States: aslconv.States{
@@ -151,30 +158,30 @@ var othersASL = &aslconv.AmazonStatesLanguage{
 						Parameters:     aslconv.RawMessage(`{"input.$": "$"}`),
 						ResultSelector: aslconv.RawMessage(`{"data.$": "$"}`),
 						OutputPath:     ptr("$.items"),
-						Retry: []aslconv.RawMessage{
-							aslconv.RawMessage(`{
-								"ErrorEquals": [ "ErrorA", "ErrorB" ],
-								"IntervalSeconds": 1,
-								"BackoffRate": 2,
-								"MaxAttempts": 2
-							}`),
-							aslconv.RawMessage(`{
-								"ErrorEquals": [ "ErrorC" ],
-								"IntervalSeconds": 5
-							}`),
+						Retry: aslconv.Retriers{
+							{
+								ErrorEquals:     []string{"ErrorA", "ErrorB"},
+								IntervalSeconds: ptr(int64(1)),
+								BackoffRate:     ptr(2.0),
+								MaxAttempts:     ptr(int64(2)),
+							},
+							{
+								ErrorEquals:     []string{"ErrorC"},
+								IntervalSeconds: ptr(int64(5)),
+							},
 						},
-						Catch: []aslconv.RawMessage{
-							aslconv.RawMessage(`{
-								"ErrorEquals": [ "States.ALL" ],
-								"Next": "Wait"
-							}`),
+						Catch: aslconv.Catchers{
+							{
+								ErrorEquals: []string{"States.ALL"},
+								Next:        "Wait",
+							},
 						},
 						Next: ptr("Wait"),
 					},
 					{
 						Name:    "Wait",
 						Type:    "Wait",
-						Seconds: ptr(int64(10)),
+						Seconds: &aslconv.Integer{Value: 10},
 						Next:    ptr("Pass"),
 					},
 					{
@@ -304,6 +311,11 @@ func TestDecodeBody(t *testing.T) {
 			source:   "testdata/others.asl.hcl",
 			expected: othersASL,
 		},
+		{
+			casename: "legacy_json_strings",
+			source:   "testdata/legacy.asl.hcl",
+			expected: othersASL,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.casename, func(t *testing.T) {
@@ -324,6 +336,214 @@ func TestDecodeBody(t *testing.T) {
 	}
 }
 
+func TestDecodeBodyUnsupportedFields(t *testing.T) {
+	cases := []struct {
+		casename string
+		source   string
+		expected []string
+	}{
+		{
+			casename: "seconds_on_task",
+			source: `
+start_at = state.task.Task
+
+state "task" "Task" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+  seconds  = 10
+  end      = true
+}
+`,
+			expected: []string{`An argument named "seconds" is not expected in a Task state.`},
+		},
+		{
+			casename: "typo_on_pass",
+			source: `
+start_at = state.pass.Pass
+
+state "pass" "Pass" {
+  resul = "{}"
+  end   = true
+}
+`,
+			expected: []string{`An argument named "resul" is not expected here. Did you mean "result"?`},
+		},
+		{
+			casename: "branch_on_map",
+			source: `
+start_at = state.map.Map
+
+state "map" "Map" {
+  end = true
+
+  branch {
+    start_at = state.pass.Pass
+
+    state "pass" "Pass" {
+      end = true
+    }
+  }
+}
+`,
+			expected: []string{`A block named "branch" is not expected in a Map state.`},
+		},
+		{
+			casename: "known_field_in_extra",
+			source: `
+start_at = state.pass.Pass
+
+state "pass" "Pass" {
+  end = true
+  extra = {
+    ResultPath = "$.result"
+  }
+}
+`,
+			expected: []string{`The field "ResultPath" is a known field. Use the "result_path" argument or block instead.`},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			parser := hclparse.NewParser()
+			file, diags := parser.ParseHCL([]byte(c.source), c.casename+".asl.hcl")
+			requireNoHasErrors(t, parser.Files(), diags)
+			var actual aslconv.AmazonStatesLanguage
+			diags = actual.DecodeBody(file.Body, &hcl.EvalContext{})
+			details := make([]string, 0, len(diags))
+			for _, diag := range diags {
+				require.NotNil(t, diag.Subject)
+				details = append(details, diag.Detail)
+			}
+			require.ElementsMatch(t, c.expected, details)
+		})
+	}
+}
+
+func TestUnmarshalJSONChoiceRule(t *testing.T) {
+	var rule aslconv.ChoiceRule
+	require.NoError(t, json.Unmarshal([]byte(`{"Variable":"$.x","StringEquals":"a","Next":"B","NewField":1}`), &rule))
+	require.Equal(t, "StringEquals", rule.Operator)
+	require.Equal(t, map[string]aslconv.RawMessage{"NewField": aslconv.RawMessage(`1`)}, rule.Extra)
+	bs, err := json.Marshal(&rule)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Variable":"$.x","StringEquals":"a","Next":"B","NewField":1}`, string(bs))
+
+	for i := 0; i < 10; i++ {
+		err := json.Unmarshal([]byte(`{"Variable":"$.x","StringEquals":"a","NumericEquals":1,"Next":"B"}`), &rule)
+		require.EqualError(t, err, "choice rule has multiple comparison operators `NumericEquals` and `StringEquals`")
+	}
+}
+
+func TestUnmarshalJSONNumberFields(t *testing.T) {
+	var state aslconv.State
+	require.NoError(t, json.Unmarshal([]byte(`{"Type":"Wait","Seconds":"{% $states.input.n %}","End":true}`), &state))
+	require.Equal(t, &aslconv.Integer{Expression: "{% $states.input.n %}"}, state.Seconds)
+	require.True(t, state.Seconds.IsExpression())
+
+	state = aslconv.State{}
+	require.NoError(t, json.Unmarshal([]byte(`{"Type":"Map","ToleratedFailurePercentage":12.5,"End":true}`), &state))
+	require.Equal(t, &aslconv.Number{Value: 12.5}, state.ToleratedFailurePercentage)
+	require.False(t, state.ToleratedFailurePercentage.IsExpression())
+
+	err := json.Unmarshal([]byte(`{"Type":"Wait","Seconds":"10","End":true}`), &aslconv.State{})
+	require.EqualError(t, err, `"10" is neither a number nor a JSONata expression`)
+}
+
+func TestDecodeBodyJSONValues(t *testing.T) {
+	src := `
+start_at = state.pass.Object
+
+state "pass" "Object" {
+  parameters = {
+    "name.$" = "$.name"
+    nested = {
+      list = [1, "two", true]
+    }
+  }
+  next = state.pass.String
+}
+
+state "pass" "String" {
+  result = "hello"
+  next   = state.pass.Legacy
+}
+
+state "pass" "Legacy" {
+  result = jsonencode({ "key" = "value" })
+  next   = state.pass.Number
+}
+
+state "pass" "Number" {
+  result = jsonencode(42)
+  next   = state.pass.Bool
+}
+
+state "pass" "Bool" {
+  result = jsonencode(true)
+  next   = state.pass.EncodedString
+}
+
+state "pass" "EncodedString" {
+  result = jsonencode("x")
+  next   = state.pass.NumberString
+}
+
+state "pass" "NumberString" {
+  result = "42"
+  next   = state.pass.BoolString
+}
+
+state "pass" "BoolString" {
+  result = "true"
+  next   = state.pass.NullString
+}
+
+state "pass" "NullString" {
+  result = "null"
+  end    = true
+}
+`
+	parser := hclparse.NewParser()
+	file, diags := parser.ParseHCL([]byte(src), "json_values.asl.hcl")
+	requireNoHasErrors(t, parser.Files(), diags)
+	var actual aslconv.AmazonStatesLanguage
+	diags = actual.DecodeBody(file.Body, &hcl.EvalContext{
+		Functions: map[string]function.Function{
+			"jsonencode": stdlib.JSONEncodeFunc,
+		},
+	})
+	requireNoHasErrors(t, parser.Files(), diags)
+	require.JSONEq(t, `{"name.$":"$.name","nested":{"list":[1,"two",true]}}`, string(actual.States[0].Parameters))
+	require.JSONEq(t, `"hello"`, string(actual.States[1].Result))
+	require.JSONEq(t, `{"key":"value"}`, string(actual.States[2].Result))
+	require.Equal(t, `42`, string(actual.States[3].Result))
+	require.Equal(t, `true`, string(actual.States[4].Result))
+	require.Equal(t, `"x"`, string(actual.States[5].Result))
+	require.Equal(t, `"42"`, string(actual.States[6].Result))
+	require.Equal(t, `"true"`, string(actual.States[7].Result))
+	require.Equal(t, `"null"`, string(actual.States[8].Result))
+}
+
+func TestEncodeBodyJSONStrings(t *testing.T) {
+	asl := &aslconv.AmazonStatesLanguage{
+		StartAt: "Pass",
+		States: aslconv.States{
+			{Name: "Pass", Type: "Pass", Result: aslconv.RawMessage(`"42"`), Parameters: aslconv.RawMessage(`"[1]"`), End: ptr(true)},
+		},
+	}
+	f := hclwrite.NewEmptyFile()
+	require.NoError(t, asl.EncodeBody(f.Body()))
+	require.Contains(t, string(f.Bytes()), `result     = "42"`)
+	require.Contains(t, string(f.Bytes()), `parameters = jsonencode("[1]")`)
+
+	file, diags := hclsyntax.ParseConfig(f.Bytes(), "encoded.asl.hcl", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+	var actual aslconv.AmazonStatesLanguage
+	diags = actual.DecodeBody(file.Body, &hcl.EvalContext{Functions: aslconv.StandardFunctions()})
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Equal(t, `"42"`, string(actual.States[0].Result))
+	require.Equal(t, `"[1]"`, string(actual.States[0].Parameters))
+}
+
 func TestEncodeBody(t *testing.T) {
 	cases := []struct {
 		casename string
@@ -389,6 +609,10 @@ func TestMarshalDOT(t *testing.T) {
 			casename: "map_and_parallel",
 			source:   loadASL(t, "testdata/map_and_parallel.asl.json"),
 		},
+		{
+			casename: "distributed_map",
+			source:   loadASL(t, "testdata/distributed_map.asl.json"),
+		},
 	}
 
 	g := goldie.New(t, goldie.WithNameSuffix(".asl.gv"))
@@ -401,9 +625,157 @@ func TestMarshalDOT(t *testing.T) {
 	}
 }
 
+func TestMarshalDOTChoiceEdgeAttrs(t *testing.T) {
+	asl := loadASL(t, "testdata/choice.asl.json")
+	var rules []string
+	_, err := asl.MarshalDOT("choice", func(opts *aslconv.MarshalDOTOptions) {
+		opts.ChoiceRuleEdgeAttrs = func(rule *aslconv.ChoiceRule, i int) map[string]string {
+			rules = append(rules, *rule.Next)
+			return map[string]string{"label": fmt.Sprintf(`"%d"`, i)}
+		}
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, rules)
+
+	var conditions []string
+	_, err = asl.MarshalDOT("choice", func(opts *aslconv.MarshalDOTOptions) {
+		opts.ChoiceEdgeAttrs = func(condition map[string]interface{}, i int) map[string]string {
+			conditions = append(conditions, condition["Next"].(string))
+			return map[string]string{"label": fmt.Sprintf(`"%d"`, i)}
+		}
+	})
+	require.NoError(t, err)
+	require.Equal(t, rules, conditions, "the deprecated ChoiceEdgeAttrs must be called for each choice rule")
+}
+
 func loadASL(t *testing.T, path string) *aslconv.AmazonStatesLanguage {
 	t.Helper()
 	asl, err := aslconv.LoadASLWithPath(path)
 	require.NoError(t, err)
 	return asl
 }
+
+func TestRoundTrip(t *testing.T) {
+	cases := []struct {
+		casename string
+		json     string
+		hcl      string
+	}{
+		{
+			casename: "choice",
+			json:     "testdata/choice.asl.json",
+			hcl:      "testdata/choice.asl.hcl",
+		},
+		{
+			casename: "wait",
+			json:     "testdata/wait.asl.json",
+			hcl:      "testdata/wait.asl.hcl",
+		},
+		{
+			casename: "task",
+			json:     "testdata/task.asl.json",
+			hcl:      "testdata/task.asl.hcl",
+		},
+		{
+			casename: "distributed_map",
+			json:     "testdata/distributed_map.asl.json",
+			hcl:      "testdata/distributed_map.asl.hcl",
+		},
+		{
+			casename: "extra",
+			json:     "testdata/extra.asl.json",
+			hcl:      "testdata/extra.asl.hcl",
+		},
+		{
+			casename: "jsonata",
+			json:     "testdata/jsonata.asl.json",
+			hcl:      "testdata/jsonata.asl.hcl",
+		},
+		{
+			casename: "jsonata_expressions",
+			json:     "testdata/jsonata_expressions.asl.json",
+			hcl:      "testdata/jsonata_expressions.asl.hcl",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			fromJSON := loadASL(t, c.json)
+			fromHCL := loadASL(t, c.hcl)
+			requireASLEq(t, fromJSON, fromHCL)
+
+			actual, err := json.MarshalIndent(fromHCL, "", "  ")
+			require.NoError(t, err)
+			bs, err := os.ReadFile(c.json)
+			require.NoError(t, err)
+			require.JSONEq(t, string(bs), string(actual))
+
+			f := hclwrite.NewEmptyFile()
+			require.NoError(t, fromHCL.EncodeBody(f.Body()))
+			bs, err = os.ReadFile(c.hcl)
+			require.NoError(t, err)
+			require.Equal(t, string(bs), string(f.Bytes()))
+
+			f = hclwrite.NewEmptyFile()
+			require.NoError(t, fromJSON.EncodeBody(f.Body()))
+			require.Equal(t, string(bs), string(f.Bytes()), "states must be written in the source order")
+		})
+	}
+}
+
+func TestWriteASLStateOrder(t *testing.T) {
+	cases := []struct {
+		order    aslconv.StateOrder
+		expected []string
+	}{
+		{
+			order:    aslconv.StateOrderSource,
+			expected: []string{"Invoke", "Check", "Done", "Failed"},
+		},
+		{
+			order:    aslconv.StateOrderTraversal,
+			expected: []string{"Invoke", "Check", "Failed", "Done"},
+		},
+		{
+			order:    aslconv.StateOrderAlphabetical,
+			expected: []string{"Check", "Done", "Failed", "Invoke"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.order.String(), func(t *testing.T) {
+			for format, pattern := range map[aslconv.Format]string{
+				aslconv.FormatJSON: `"%s": {`,
+				aslconv.FormatHCL:  `"%s" {`,
+			} {
+				asl := loadASL(t, "testdata/jsonata.asl.json")
+				var buf bytes.Buffer
+				err := format.WriteASL(&buf, asl, func(opts *aslconv.WriteOptions) {
+					opts.StateOrder = c.order
+				})
+				require.NoError(t, err)
+				require.EqualValues(t, loadASL(t, "testdata/jsonata.asl.json"), asl, "the states of the given asl must not be reordered")
+				output := buf.String()
+				t.Log(output)
+				positions := make([]int, 0, len(c.expected))
+				for _, name := range c.expected {
+					pos := strings.Index(output, fmt.Sprintf(pattern, name))
+					require.NotEqual(t, -1, pos, "state %s not found", name)
+					positions = append(positions, pos)
+				}
+				require.IsIncreasing(t, positions, "%s: states must be written in the %s order", format, c.order)
+			}
+		})
+	}
+}
+
+func TestWriteASLKeepsNestedStateOrder(t *testing.T) {
+	for _, order := range []aslconv.StateOrder{aslconv.StateOrderTraversal, aslconv.StateOrderAlphabetical} {
+		t.Run(order.String(), func(t *testing.T) {
+			asl := loadASL(t, "testdata/map_and_parallel.asl.json")
+			err := aslconv.FormatJSON.WriteASL(io.Discard, asl, func(opts *aslconv.WriteOptions) {
+				opts.StateOrder = order
+			})
+			require.NoError(t, err)
+			require.EqualValues(t, loadASL(t, "testdata/map_and_parallel.asl.json"), asl)
+		})
+	}
+}
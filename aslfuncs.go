@@ -0,0 +1,199 @@
+package aslconv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mashiike/aslconv/jsonpath"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// ASLFunctions returns the helper functions to write ARNs, intrinsic functions and paths, that are registered in the default HCLEvalContext.
+//
+//	lambda_arn("process", "us-east-1", "123456789012")   # arn:aws:lambda:us-east-1:123456789012:function:process
+//	sfn_integration("dynamodb:putItem")                   # arn:aws:states:::dynamodb:putItem
+//	sfn_integration("ecs:runTask", "sync")                # arn:aws:states:::ecs:runTask.sync
+//	states_format("Hello, {}", "$.name")                  # States.Format('Hello, {}', $.name)
+//	states_array("$.a", 1, "b")                           # States.Array($.a, 1, 'b')
+//	jsonpath("$.detail", "items", 0, "first name")        # $.detail.items[0]['first name']
+//
+// The string arguments of states_format and states_array, that start with "$" or "States.", are written as paths or intrinsic functions,
+// and the other strings are written as string literals, escaping the characters ' { } and \.
+// The template of states_format is written as is, except the single quotes, to keep the placeholders {}.
+func ASLFunctions() map[string]function.Function {
+	return map[string]function.Function{
+		"lambda_arn":      lambdaARNFunc,
+		"sfn_integration": sfnIntegrationFunc,
+		"states_format":   makeIntrinsicFunc("States.Format", true),
+		"states_array":    makeIntrinsicFunc("States.Array", false),
+		"jsonpath":        jsonPathFunc,
+	}
+}
+
+var lambdaARNFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "name", Type: cty.String},
+		{Name: "region", Type: cty.String},
+		{Name: "account", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		name, region, account := args[0].AsString(), args[1].AsString(), args[2].AsString()
+		if name == "" || region == "" || account == "" {
+			return cty.UnknownVal(cty.String), fmt.Errorf("name, region and account must not be empty")
+		}
+		return cty.StringVal(fmt.Sprintf("arn:aws:lambda:%s:%s:function:%s", region, account, name)), nil
+	},
+})
+
+// sfnIntegrationPatterns are the suffixes of the resource ARN for the service integration patterns.
+var sfnIntegrationPatterns = map[string]string{
+	"":                    "",
+	"request_response":    "",
+	"sync":                ".sync",
+	"sync:2":              ".sync:2",
+	"wait_for_task_token": ".waitForTaskToken",
+	"waitForTaskToken":    ".waitForTaskToken",
+}
+
+var sfnIntegrationFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "service_action", Type: cty.String},
+	},
+	VarParam: &function.Parameter{
+		Name: "pattern",
+		Type: cty.String,
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		serviceAction := args[0].AsString()
+		if service, action, ok := strings.Cut(serviceAction, ":"); !ok || service == "" || action == "" {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(0, `the service action must be "service:action", e.g. "dynamodb:putItem", but "%s" is given`, serviceAction)
+		}
+		if len(args) > 2 {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(2, "too many arguments, only one pattern can be given")
+		}
+		var pattern string
+		if len(args) == 2 {
+			pattern = args[1].AsString()
+		}
+		suffix, ok := sfnIntegrationPatterns[pattern]
+		if !ok {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(1, `the pattern must be "request_response", "sync", "sync:2" or "wait_for_task_token", but "%s" is given`, pattern)
+		}
+		return cty.StringVal("arn:aws:states:::" + serviceAction + suffix), nil
+	},
+})
+
+// makeIntrinsicFunc returns the function that writes the intrinsic function call.
+// If withTemplate is true, the first argument is the template of States.Format, that is quoted without escaping the placeholders {}.
+func makeIntrinsicFunc(name string, withTemplate bool) function.Function {
+	spec := &function.Spec{
+		VarParam: &function.Parameter{
+			Name:      "args",
+			Type:      cty.DynamicPseudoType,
+			AllowNull: true,
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			literals := make([]string, 0, len(args))
+			for i, arg := range args {
+				if withTemplate && i == 0 {
+					literals = append(literals, "'"+strings.ReplaceAll(arg.AsString(), "'", `\'`)+"'")
+					continue
+				}
+				literal, err := intrinsicArgument(arg)
+				if err != nil {
+					return cty.UnknownVal(cty.String), function.NewArgError(i, err)
+				}
+				literals = append(literals, literal)
+			}
+			return cty.StringVal(fmt.Sprintf("%s(%s)", name, strings.Join(literals, ", "))), nil
+		},
+	}
+	if withTemplate {
+		spec.Params = []function.Parameter{
+			{Name: "template", Type: cty.String},
+		}
+	}
+	return function.New(spec)
+}
+
+// intrinsicArgument returns the argument of the intrinsic function.
+func intrinsicArgument(arg cty.Value) (string, error) {
+	if arg.IsNull() {
+		return "null", nil
+	}
+	switch arg.Type() {
+	case cty.String:
+		s := arg.AsString()
+		if strings.HasPrefix(s, "$") || strings.HasPrefix(s, "States.") {
+			return s, nil
+		}
+		return quoteIntrinsicString(s), nil
+	case cty.Number:
+		return arg.AsBigFloat().Text('f', -1), nil
+	case cty.Bool:
+		if arg.True() {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	return "", fmt.Errorf("the argument must be a string, number, bool or null, but %s is given", arg.Type().FriendlyName())
+}
+
+var intrinsicEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `{`, `\{`, `}`, `\}`)
+
+// quoteIntrinsicString returns the string literal of the intrinsic function, the characters ' { } and \ are escaped by backslash.
+func quoteIntrinsicString(s string) string {
+	return "'" + intrinsicEscaper.Replace(s) + "'"
+}
+
+var jsonPathIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var jsonPathFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "path", Type: cty.String},
+	},
+	VarParam: &function.Parameter{
+		Name: "segments",
+		Type: cty.DynamicPseudoType,
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		path := args[0].AsString()
+		if path != "$" && path != "$$" && !strings.HasPrefix(path, "$.") && !strings.HasPrefix(path, "$[") && !strings.HasPrefix(path, "$$.") {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(0, `the path must start with "$" or "$$", but "%s" is given`, path)
+		}
+		var b strings.Builder
+		b.WriteString(path)
+		for i, segment := range args[1:] {
+			switch segment.Type() {
+			case cty.String:
+				s := segment.AsString()
+				if s == "" {
+					return cty.UnknownVal(cty.String), function.NewArgErrorf(i+1, "the segment must not be empty")
+				}
+				if jsonPathIdentifier.MatchString(s) {
+					b.WriteString("." + s)
+				} else {
+					b.WriteString("['" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "']")
+				}
+			case cty.Number:
+				index, accuracy := segment.AsBigFloat().Int64()
+				if accuracy != 0 || index < 0 {
+					return cty.UnknownVal(cty.String), function.NewArgErrorf(i+1, "the index must be a non-negative integer, but %s is given", segment.AsBigFloat().Text('f', -1))
+				}
+				fmt.Fprintf(&b, "[%d]", index)
+			default:
+				return cty.UnknownVal(cty.String), function.NewArgErrorf(i+1, "the segment must be a string or a number, but %s is given", segment.Type().FriendlyName())
+			}
+		}
+		if _, err := jsonpath.Parse(b.String()); err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(0, "the path `%s` is invalid: %s", b.String(), err)
+		}
+		return cty.StringVal(b.String()), nil
+	},
+})
@@ -0,0 +1,130 @@
+package aslconv_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadASLWithASLFunctions(t *testing.T) {
+	cases := []struct {
+		casename string
+		comment  string
+		expected string
+		errorMsg string
+	}{
+		{
+			casename: "lambda_arn",
+			comment:  `lambda_arn("process", "us-east-1", "123456789012")`,
+			expected: "arn:aws:lambda:us-east-1:123456789012:function:process",
+		},
+		{
+			casename: "lambda_arn_empty_region",
+			comment:  `lambda_arn("process", "", "123456789012")`,
+			errorMsg: "Error in function call",
+		},
+		{
+			casename: "sfn_integration",
+			comment:  `sfn_integration("dynamodb:putItem")`,
+			expected: "arn:aws:states:::dynamodb:putItem",
+		},
+		{
+			casename: "sfn_integration_sync",
+			comment:  `sfn_integration("ecs:runTask", "sync")`,
+			expected: "arn:aws:states:::ecs:runTask.sync",
+		},
+		{
+			casename: "sfn_integration_wait_for_task_token",
+			comment:  `sfn_integration("sqs:sendMessage", "wait_for_task_token")`,
+			expected: "arn:aws:states:::sqs:sendMessage.waitForTaskToken",
+		},
+		{
+			casename: "sfn_integration_invalid_pattern",
+			comment:  `sfn_integration("dynamodb:putItem", "async")`,
+			errorMsg: "Invalid function argument",
+		},
+		{
+			casename: "sfn_integration_invalid_service_action",
+			comment:  `sfn_integration("dynamodb")`,
+			errorMsg: "Invalid function argument",
+		},
+		{
+			casename: "states_format",
+			comment:  `states_format("Hello, {}! You're {} years old.", "$.name", 20)`,
+			expected: `States.Format('Hello, {}! You\'re {} years old.', $.name, 20)`,
+		},
+		{
+			casename: "states_array",
+			comment:  `states_array("$.a", "{b}", 1.5, true, null, "$$.Execution.Id")`,
+			expected: `States.Array($.a, '\{b\}', 1.5, true, null, $$.Execution.Id)`,
+		},
+		{
+			casename: "nested",
+			comment:  `states_format("{}", states_array("$.a"))`,
+			expected: `States.Format('{}', States.Array($.a))`,
+		},
+		{
+			casename: "states_array_object",
+			comment:  `states_array({ a = 1 })`,
+			errorMsg: "Invalid function argument",
+		},
+		{
+			casename: "jsonpath",
+			comment:  `jsonpath("$.detail", "items", 0, "first name")`,
+			expected: "$.detail.items[0]['first name']",
+		},
+		{
+			casename: "jsonpath_context",
+			comment:  `jsonpath("$$", "Execution", "Id")`,
+			expected: "$$.Execution.Id",
+		},
+		{
+			casename: "jsonpath_invalid_root",
+			comment:  `jsonpath("detail", "items")`,
+			errorMsg: "Invalid function argument",
+		},
+		{
+			casename: "jsonpath_invalid_path",
+			comment:  `jsonpath("$.a[")`,
+			errorMsg: "Invalid function argument",
+		},
+		{
+			casename: "jsonpath_invalid_path_with_segments",
+			comment:  `jsonpath("$.a..", "b")`,
+			errorMsg: "Invalid function argument",
+		},
+		{
+			casename: "jsonpath_invalid_index",
+			comment:  `jsonpath("$.items", -1)`,
+			errorMsg: "Invalid function argument",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			src := []byte(`start_at = state.pass.Pass
+
+state "pass" "Pass" {
+  comment = ` + c.comment + `
+  end     = true
+}
+`)
+			asl, err := aslconv.FormatHCL.LoadASLWithBytes(src, "testdata/functions/functions.asl.hcl", func(opts *aslconv.LoadOptions) {
+				opts.NoStandardFunctions = true
+				opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+					return nil
+				}
+			})
+			if c.errorMsg != "" {
+				var diags hcl.Diagnostics
+				require.ErrorAs(t, err, &diags)
+				require.Contains(t, diagnosticSummaries(diags), c.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, *asl.States[0].Comment)
+		})
+	}
+}
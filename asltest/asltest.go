@@ -0,0 +1,315 @@
+// Package asltest runs the unit tests of the state machines in-process, with the mocks of the Task states.
+//
+// The tests are written in HCL files, that have `test` blocks:
+//
+//	test "happy path" {
+//	  input = { foo = 1 }
+//
+//	  mock "task.FirstState" {
+//	    return = { result = "ok" }
+//	  }
+//
+//	  expect {
+//	    path   = ["FirstState", "ChoiceState", "FirstMatchState", "NextState"]
+//	    output = { result = "ok" }
+//	  }
+//	}
+//
+// The MockConfigFile of Step Functions Local is also available, see ReadMockConfig.
+package asltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// FileSuffix is the suffix of the test files.
+const FileSuffix = ".asltest.hcl"
+
+// Test is a test case of the state machine.
+type Test struct {
+	Name string
+	// Input is the input of the execution, the default is `{}`.
+	Input json.RawMessage
+	// Mocks are the mocks of the Task states keyed by the state name.
+	Mocks map[string]*Mock
+	// Expect is the expectation of the execution. If it is nil, the execution is expected to succeed.
+	Expect *Expect
+}
+
+// Mock is the mock of a Task state, that returns the responses in order of the invocations.
+// The last response is returned repeatedly, when the invocations are more than the responses.
+type Mock struct {
+	// Type is the type of the mocked state in lower case, e.g. "task". It is empty when not specified.
+	Type      string
+	Responses []*MockResponse
+}
+
+// MockResponse is the response of a mocked invocation, that returns the value or throws the error.
+type MockResponse struct {
+	Return json.RawMessage
+	Error  string
+	Cause  string
+}
+
+// Expect is the expectation of the execution.
+type Expect struct {
+	// Path is the names of the states entered in the execution, in order.
+	Path []string
+	// Output is the output of the execution, compared as JSON values.
+	Output json.RawMessage
+	// Error and Cause are the error of the failed execution. The execution is expected to succeed when Error is empty.
+	Error string
+	Cause *string
+}
+
+type testFileSchema struct {
+	MockConfig *string      `hcl:"mock_config"`
+	Tests      []*testBlock `hcl:"test,block"`
+}
+
+type testBlock struct {
+	Name     string         `hcl:"name,label"`
+	Input    cty.Value      `hcl:"input,optional"`
+	TestCase *string        `hcl:"test_case"`
+	Mocks    []*mockBlock   `hcl:"mock,block"`
+	Expect   []*expectBlock `hcl:"expect,block"`
+	Body     hcl.Body       `hcl:",body"`
+}
+
+type mockBlock struct {
+	State     string           `hcl:"state,label"`
+	Return    cty.Value        `hcl:"return,optional"`
+	Error     *string          `hcl:"error"`
+	Cause     *string          `hcl:"cause"`
+	Responses []*responseBlock `hcl:"response,block"`
+	Body      hcl.Body         `hcl:",body"`
+}
+
+type responseBlock struct {
+	Return cty.Value `hcl:"return,optional"`
+	Error  *string   `hcl:"error"`
+	Cause  *string   `hcl:"cause"`
+}
+
+type expectBlock struct {
+	Path   []string  `hcl:"path,optional"`
+	Output cty.Value `hcl:"output,optional"`
+	Error  *string   `hcl:"error"`
+	Cause  *string   `hcl:"cause"`
+}
+
+// LoadTests loads the tests of the HCL test file.
+// The test file may have `mock_config` attribute, the path of the MockConfigFile of Step Functions Local,
+// and then the `test_case` attribute of the test block imports the mocks of the test case of the state machine.
+// stateMachine is the name of the state machine in the MockConfigFile, that can be empty if it has only one.
+// The diagnostics are returned as hcl.Diagnostics.
+func LoadTests(path string, stateMachine string) ([]*Test, error) {
+	parser := hclparse.NewParser()
+	file, diags := parser.ParseHCLFile(path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	baseDir := filepath.Dir(path)
+	functions := aslconv.StandardFunctions()
+	for name, fn := range aslconv.FileFunctions(baseDir) {
+		functions[name] = fn
+	}
+	ctx := &hcl.EvalContext{Functions: functions}
+	var schema testFileSchema
+	if diags := gohcl.DecodeBody(file.Body, ctx, &schema); diags.HasErrors() {
+		return nil, diags
+	}
+	var mockConfig *MockConfig
+	if schema.MockConfig != nil {
+		mockConfigPath := *schema.MockConfig
+		if !filepath.IsAbs(mockConfigPath) {
+			mockConfigPath = filepath.Join(baseDir, mockConfigPath)
+		}
+		var err error
+		if mockConfig, err = ReadMockConfig(mockConfigPath); err != nil {
+			return nil, err
+		}
+	}
+	tests := make([]*Test, 0, len(schema.Tests))
+	names := make(map[string]*hcl.Range, len(schema.Tests))
+	for _, block := range schema.Tests {
+		if defined, ok := names[block.Name]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate test name",
+				Detail:   fmt.Sprintf(`The test "%s" was already defined at %s.`, block.Name, defined),
+				Subject:  block.Body.MissingItemRange().Ptr(),
+			})
+			continue
+		}
+		names[block.Name] = block.Body.MissingItemRange().Ptr()
+		test, testDiags := block.decode(mockConfig, stateMachine)
+		diags = append(diags, testDiags...)
+		if test != nil {
+			tests = append(tests, test)
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return tests, nil
+}
+
+func (block *testBlock) decode(mockConfig *MockConfig, stateMachine string) (*Test, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	test := &Test{
+		Name:  block.Name,
+		Mocks: make(map[string]*Mock),
+	}
+	input, err := toJSON(block.Input)
+	if err != nil {
+		return nil, append(diags, invalidValue("input", err, block.Body.MissingItemRange()))
+	}
+	test.Input = input
+	if block.TestCase != nil {
+		if mockConfig == nil {
+			return nil, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing mock_config",
+				Detail:   fmt.Sprintf(`The test "%s" has test_case, but the test file has no mock_config.`, block.Name),
+				Subject:  block.Body.MissingItemRange().Ptr(),
+			})
+		}
+		mocks, err := mockConfig.Mocks(stateMachine, *block.TestCase)
+		if err != nil {
+			return nil, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid test_case",
+				Detail:   fmt.Sprintf("The test \"%s\": %s.", block.Name, err),
+				Subject:  block.Body.MissingItemRange().Ptr(),
+			})
+		}
+		for name, mock := range mocks {
+			test.Mocks[name] = mock
+		}
+	}
+	for _, mb := range block.Mocks {
+		// the prefix is the type of the state only when it is a label of the state block, as the state name may have dots
+		typ, name := "", mb.State
+		if prefix, rest, ok := strings.Cut(mb.State, "."); ok {
+			if _, isType := aslconv.StateTypeOfLabel(prefix); isType {
+				typ, name = prefix, rest
+			}
+		}
+		mock := &Mock{Type: typ}
+		responses := mb.Responses
+		if !mb.Return.IsNull() || mb.Error != nil {
+			if len(responses) > 0 {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Conflicting mock responses",
+					Detail:   fmt.Sprintf(`The mock "%s" has both return or error and response blocks. Only one of them can be used.`, mb.State),
+					Subject:  mb.Body.MissingItemRange().Ptr(),
+				})
+				continue
+			}
+			responses = []*responseBlock{{Return: mb.Return, Error: mb.Error, Cause: mb.Cause}}
+		}
+		if len(responses) == 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing mock responses",
+				Detail:   fmt.Sprintf(`The mock "%s" must have return, error or response blocks.`, mb.State),
+				Subject:  mb.Body.MissingItemRange().Ptr(),
+			})
+			continue
+		}
+		for _, rb := range responses {
+			response := &MockResponse{}
+			if rb.Error != nil {
+				response.Error = *rb.Error
+				if rb.Cause != nil {
+					response.Cause = *rb.Cause
+				}
+			} else if response.Return, err = toJSON(rb.Return); err != nil {
+				diags = append(diags, invalidValue("return", err, mb.Body.MissingItemRange()))
+				continue
+			}
+			mock.Responses = append(mock.Responses, response)
+		}
+		test.Mocks[name] = mock
+	}
+	for i, eb := range block.Expect {
+		if i > 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate expect block",
+				Detail:   fmt.Sprintf(`The test "%s" has more than one expect block.`, block.Name),
+				Subject:  block.Body.MissingItemRange().Ptr(),
+			})
+			break
+		}
+		expect := &Expect{Path: eb.Path, Cause: eb.Cause}
+		if eb.Error != nil {
+			expect.Error = *eb.Error
+		}
+		if expect.Output, err = toJSON(eb.Output); err != nil {
+			diags = append(diags, invalidValue("output", err, block.Body.MissingItemRange()))
+		}
+		test.Expect = expect
+	}
+	return test, diags
+}
+
+func invalidValue(name string, err error, subject hcl.Range) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid JSON value",
+		Detail:   fmt.Sprintf("The %s can not be converted to JSON: %s.", name, err),
+		Subject:  subject.Ptr(),
+	}
+}
+
+// toJSON converts the HCL value to JSON, the absent value is nil.
+func toJSON(value cty.Value) (json.RawMessage, error) {
+	if value.IsNull() {
+		return nil, nil
+	}
+	if !value.IsWhollyKnown() {
+		return nil, fmt.Errorf("the value must be known")
+	}
+	return ctyjson.Marshal(value, value.Type())
+}
+
+// LoadTestsWithPath loads the tests of the HCL test file or the MockConfigFile of Step Functions Local, by the extension.
+func LoadTestsWithPath(path string, stateMachine string) ([]*Test, error) {
+	if strings.HasSuffix(path, ".hcl") {
+		return LoadTests(path, stateMachine)
+	}
+	mockConfig, err := ReadMockConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return mockConfig.Tests(stateMachine)
+}
+
+// TestFiles returns the test files in the directory, sorted by the name.
+func TestFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), FileSuffix) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,263 @@
+package asltest_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/mashiike/aslconv"
+	"github.com/mashiike/aslconv/asltest"
+	"github.com/stretchr/testify/require"
+)
+
+func loadASL(t *testing.T) *aslconv.AmazonStatesLanguage {
+	t.Helper()
+	asl, err := aslconv.LoadASLWithPath("../testdata/sample.asl.hcl")
+	require.NoError(t, err)
+	return asl
+}
+
+func TestLoadTests(t *testing.T) {
+	tests, err := asltest.LoadTests("../testdata/asltest/sample.asltest.hcl", "")
+	require.NoError(t, err)
+	require.Len(t, tests, 4)
+
+	first := tests[0]
+	require.Equal(t, "first match", first.Name)
+	require.JSONEq(t, `{"foo": 0}`, string(first.Input))
+	require.Equal(t, "task", first.Mocks["FirstState"].Type)
+	require.JSONEq(t, `{"foo": 1}`, string(first.Mocks["FirstState"].Responses[0].Return))
+	require.Equal(t, []string{"FirstState", "ChoiceState", "FirstMatchState", "NextState"}, first.Expect.Path)
+
+	failed := tests[2]
+	require.Equal(t, "", failed.Mocks["FirstState"].Type)
+	require.Equal(t, &asltest.MockResponse{Error: "States.TaskFailed", Cause: "boom"}, failed.Mocks["FirstState"].Responses[0])
+
+	imported := tests[3]
+	require.Len(t, imported.Mocks, 3)
+	require.JSONEq(t, `{"foo": 2}`, string(imported.Mocks["FirstState"].Responses[0].Return))
+}
+
+func TestLoadTestsMockLabels(t *testing.T) {
+	src := `test "labels" {
+  mock "Call.API" {
+    return = {}
+  }
+  mock "task.Call.Other" {
+    return = {}
+  }
+  mock "Plain" {
+    return = {}
+  }
+}`
+	path := filepath.Join(t.TempDir(), "labels"+asltest.FileSuffix)
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	tests, err := asltest.LoadTests(path, "")
+	require.NoError(t, err)
+	types := make(map[string]string)
+	for name, mock := range tests[0].Mocks {
+		types[name] = mock.Type
+	}
+	require.Equal(t, map[string]string{"Call.API": "", "Call.Other": "task", "Plain": ""}, types)
+}
+
+func TestLoadTestsErrors(t *testing.T) {
+	cases := []struct {
+		casename string
+		src      string
+		expected []string
+	}{
+		{
+			casename: "duplicate",
+			src:      `test "a" {} ` + "\n" + `test "a" {}`,
+			expected: []string{"Duplicate test name"},
+		},
+		{
+			casename: "missing_responses",
+			src: `test "a" {
+  mock "task.A" {}
+}`,
+			expected: []string{"Missing mock responses"},
+		},
+		{
+			casename: "conflicting_responses",
+			src: `test "a" {
+  mock "task.A" {
+    return = {}
+    response {
+      error = "Error"
+    }
+  }
+}`,
+			expected: []string{"Conflicting mock responses"},
+		},
+		{
+			casename: "missing_mock_config",
+			src:      `test "a" { test_case = "HappyPath" }`,
+			expected: []string{"Missing mock_config"},
+		},
+		{
+			casename: "unknown_attribute",
+			src:      `test "a" { unknown = 1 }`,
+			expected: []string{"Unsupported argument"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "invalid"+asltest.FileSuffix)
+			require.NoError(t, os.WriteFile(path, []byte(c.src), 0o644))
+			_, err := asltest.LoadTests(path, "")
+			var diags hcl.Diagnostics
+			require.ErrorAs(t, err, &diags)
+			summaries := make([]string, 0, len(diags))
+			for _, diag := range diags {
+				summaries = append(summaries, diag.Summary)
+			}
+			require.Equal(t, c.expected, summaries)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	asl := loadASL(t)
+	tests, err := asltest.LoadTests("../testdata/asltest/sample.asltest.hcl", "")
+	require.NoError(t, err)
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := test.Run(context.Background(), asl)
+			require.True(t, result.Passed(), result.Failures)
+		})
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	asl := loadASL(t)
+	cases := []struct {
+		casename string
+		test     *asltest.Test
+		expected []string
+	}{
+		{
+			casename: "unknown_state",
+			test: &asltest.Test{
+				Name:  "unknown_state",
+				Mocks: map[string]*asltest.Mock{"Unknown": {Responses: []*asltest.MockResponse{{Return: json.RawMessage(`{}`)}}}},
+			},
+			expected: []string{"the mocked state Unknown is not found"},
+		},
+		{
+			casename: "not_task",
+			test: &asltest.Test{
+				Name:  "not_task",
+				Mocks: map[string]*asltest.Mock{"ChoiceState": {Type: "task", Responses: []*asltest.MockResponse{{Return: json.RawMessage(`{}`)}}}},
+			},
+			expected: []string{"the mocked state ChoiceState is a Choice state, not task"},
+		},
+		{
+			casename: "path_and_output",
+			test: &asltest.Test{
+				Name: "path_and_output",
+				Mocks: map[string]*asltest.Mock{
+					"FirstState":       {Responses: []*asltest.MockResponse{{Return: json.RawMessage(`{"foo": 2}`)}}},
+					"SecondMatchState": {Responses: []*asltest.MockResponse{{Return: json.RawMessage(`{}`)}}},
+					"NextState":        {Responses: []*asltest.MockResponse{{Return: json.RawMessage(`{"a": 1, "b": 2}`)}}},
+				},
+				Expect: &asltest.Expect{
+					Path:   []string{"FirstState", "ChoiceState", "FirstMatchState", "NextState"},
+					Output: json.RawMessage(`{"a": 1, "b": 3}`),
+				},
+			},
+			expected: []string{
+				"the path is different:\n  FirstState\n  ChoiceState\n- FirstMatchState\n+ SecondMatchState\n  NextState",
+				"the output is different:\n  {\n    \"a\": 1,\n-   \"b\": 3\n+   \"b\": 2\n  }",
+			},
+		},
+		{
+			casename: "unexpected_error",
+			test: &asltest.Test{
+				Name: "unexpected_error",
+				Mocks: map[string]*asltest.Mock{
+					"FirstState": {Responses: []*asltest.MockResponse{{Error: "States.Timeout", Cause: "timeout"}}},
+				},
+			},
+			expected: []string{"the execution failed with the error States.Timeout: timeout"},
+		},
+		{
+			casename: "different_error",
+			test: &asltest.Test{
+				Name: "different_error",
+				Mocks: map[string]*asltest.Mock{
+					"FirstState": {Responses: []*asltest.MockResponse{{Error: "States.Timeout", Cause: "timeout"}}},
+				},
+				Expect: &asltest.Expect{Error: "States.TaskFailed"},
+			},
+			expected: []string{"the execution failed with the error States.Timeout, but expected States.TaskFailed"},
+		},
+		{
+			casename: "no_responses",
+			test: &asltest.Test{
+				Name:  "no_responses",
+				Mocks: map[string]*asltest.Mock{"FirstState": {}},
+			},
+			expected: []string{"the execution failed with the error States.Runtime: the mock has no responses"},
+		},
+		{
+			casename: "not_mocked",
+			test: &asltest.Test{
+				Name:   "not_mocked",
+				Expect: &asltest.Expect{Path: []string{"FirstState"}},
+			},
+			expected: []string{"the execution failed with the error States.Runtime: no task handler for the resource arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			result := c.test.Run(context.Background(), asl)
+			require.False(t, result.Passed())
+			require.Equal(t, c.expected, result.Failures)
+		})
+	}
+}
+
+func TestMockConfig(t *testing.T) {
+	asl := loadASL(t)
+	tests, err := asltest.LoadTestsWithPath("../testdata/asltest/MockConfigFile.json", "")
+	require.NoError(t, err)
+	names := make([]string, 0, len(tests))
+	passed := make([]bool, 0, len(tests))
+	for _, test := range tests {
+		names = append(names, test.Name)
+		passed = append(passed, test.Run(context.Background(), asl).Passed())
+	}
+	require.Equal(t, []string{"Failure", "FirstMatch", "SecondMatch"}, names)
+	require.Equal(t, []bool{false, true, true}, passed)
+
+	config, err := asltest.ReadMockConfig("../testdata/asltest/MockConfigFile.json")
+	require.NoError(t, err)
+	mocks, err := config.Mocks("sample", "Failure")
+	require.NoError(t, err)
+	require.Equal(t, []*asltest.MockResponse{
+		{Error: "Lambda.ServiceException", Cause: "unavailable"},
+		{Return: json.RawMessage(`{"foo":1}`)},
+		{Return: json.RawMessage(`{"foo":1}`)},
+	}, compactResponses(mocks["FirstState"].Responses))
+
+	_, err = config.Mocks("unknown", "Failure")
+	require.EqualError(t, err, "the state machine unknown is not found in the mock config")
+	_, err = config.Mocks("", "Unknown")
+	require.EqualError(t, err, "the test case Unknown is not found in the mock config")
+}
+
+// compactResponses compacts the JSON of the responses to compare them.
+func compactResponses(responses []*asltest.MockResponse) []*asltest.MockResponse {
+	for _, response := range responses {
+		if response.Return != nil {
+			bs, _ := json.Marshal(response.Return)
+			response.Return = bs
+		}
+	}
+	return responses
+}
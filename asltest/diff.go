@@ -0,0 +1,42 @@
+package asltest
+
+import "strings"
+
+// diff returns the line diff of the expected and the actual lines,
+// the lines only in the expected are prefixed by "- ", the lines only in the actual by "+ " and the common lines by "  ".
+func diff(expected, actual []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of expected[i:] and actual[j:]
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			switch {
+			case expected[i] == actual[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var b strings.Builder
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && expected[i] == actual[j]:
+			b.WriteString("  " + expected[i] + "\n")
+			i++
+			j++
+		case j == len(actual) || (i < len(expected) && lcs[i+1][j] >= lcs[i][j+1]):
+			b.WriteString("- " + expected[i] + "\n")
+			i++
+		default:
+			b.WriteString("+ " + actual[j] + "\n")
+			j++
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,168 @@
+package asltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/sfn-local-mock-cfg-file.html
+
+// MockConfig is the MockConfigFile of Step Functions Local.
+type MockConfig struct {
+	StateMachines   map[string]*MockConfigStateMachine              `json:"StateMachines"`
+	MockedResponses map[string]map[string]*MockConfigMockedResponse `json:"MockedResponses"`
+}
+
+type MockConfigStateMachine struct {
+	// TestCases are the mocked response names keyed by the state name, for each test case.
+	TestCases map[string]map[string]string `json:"TestCases"`
+}
+
+// MockConfigMockedResponse is the response of the invocations, that has Return or Throw.
+type MockConfigMockedResponse struct {
+	Return json.RawMessage `json:"Return,omitempty"`
+	Throw  *struct {
+		Error string `json:"Error"`
+		Cause string `json:"Cause"`
+	} `json:"Throw,omitempty"`
+}
+
+// ReadMockConfig reads the MockConfigFile.
+func ReadMockConfig(path string) (*MockConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config MockConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// stateMachine returns the state machine of the name. The name can be empty, if the config has only one state machine.
+func (config *MockConfig) stateMachine(name string) (*MockConfigStateMachine, error) {
+	if name != "" {
+		sm, ok := config.StateMachines[name]
+		if !ok {
+			return nil, fmt.Errorf("the state machine %s is not found in the mock config", name)
+		}
+		return sm, nil
+	}
+	if len(config.StateMachines) != 1 {
+		names := make([]string, 0, len(config.StateMachines))
+		for name := range config.StateMachines {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("the mock config has %d state machines [%s], the name of the state machine is required", len(names), strings.Join(names, ", "))
+	}
+	for _, sm := range config.StateMachines {
+		return sm, nil
+	}
+	return nil, nil
+}
+
+// Tests returns the tests of the test cases of the state machine, sorted by the name.
+// The tests run with the empty input and expect the executions to succeed.
+func (config *MockConfig) Tests(stateMachine string) ([]*Test, error) {
+	sm, err := config.stateMachine(stateMachine)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(sm.TestCases))
+	for name := range sm.TestCases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tests := make([]*Test, 0, len(names))
+	for _, name := range names {
+		mocks, err := config.Mocks(stateMachine, name)
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, &Test{Name: name, Mocks: mocks})
+	}
+	return tests, nil
+}
+
+// Mocks returns the mocks of the test case of the state machine, keyed by the state name.
+func (config *MockConfig) Mocks(stateMachine string, testCase string) (map[string]*Mock, error) {
+	sm, err := config.stateMachine(stateMachine)
+	if err != nil {
+		return nil, err
+	}
+	states, ok := sm.TestCases[testCase]
+	if !ok {
+		return nil, fmt.Errorf("the test case %s is not found in the mock config", testCase)
+	}
+	mocks := make(map[string]*Mock, len(states))
+	for state, responseName := range states {
+		mocked, ok := config.MockedResponses[responseName]
+		if !ok {
+			return nil, fmt.Errorf("the mocked response %s of the state %s is not found in the mock config", responseName, state)
+		}
+		responses, err := mockResponses(mocked)
+		if err != nil {
+			return nil, fmt.Errorf("the mocked response %s: %w", responseName, err)
+		}
+		mocks[state] = &Mock{Responses: responses}
+	}
+	return mocks, nil
+}
+
+// mockResponses returns the responses in order of the invocations, the keys are the invocation indexes such as "0" and "1-2".
+func mockResponses(mocked map[string]*MockConfigMockedResponse) ([]*MockResponse, error) {
+	if len(mocked) == 0 {
+		return nil, fmt.Errorf("no responses")
+	}
+	byIndex := make(map[int]*MockConfigMockedResponse)
+	for key, response := range mocked {
+		first, last, err := parseInvocationRange(key)
+		if err != nil {
+			return nil, err
+		}
+		if (response.Return == nil) == (response.Throw == nil) {
+			return nil, fmt.Errorf("the response %s must have either Return or Throw", key)
+		}
+		for i := first; i <= last; i++ {
+			if _, ok := byIndex[i]; ok {
+				return nil, fmt.Errorf("the invocation %d has more than one response", i)
+			}
+			byIndex[i] = response
+		}
+	}
+	responses := make([]*MockResponse, len(byIndex))
+	for i := range responses {
+		response, ok := byIndex[i]
+		if !ok {
+			return nil, fmt.Errorf("the invocation %d has no response", i)
+		}
+		if response.Throw != nil {
+			responses[i] = &MockResponse{Error: response.Throw.Error, Cause: response.Throw.Cause}
+		} else {
+			responses[i] = &MockResponse{Return: response.Return}
+		}
+	}
+	return responses, nil
+}
+
+func parseInvocationRange(key string) (int, int, error) {
+	firstStr, lastStr, isRange := strings.Cut(key, "-")
+	first, err := strconv.Atoi(firstStr)
+	if err != nil || first < 0 {
+		return 0, 0, fmt.Errorf("invalid invocation index %s", key)
+	}
+	if !isRange {
+		return first, first, nil
+	}
+	last, err := strconv.Atoi(lastStr)
+	if err != nil || last < first {
+		return 0, 0, fmt.Errorf("invalid invocation range %s", key)
+	}
+	return first, last, nil
+}
@@ -0,0 +1,67 @@
+package asltest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mashiike/aslconv/asltest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockConfigErrors(t *testing.T) {
+	cases := []struct {
+		casename string
+		src      string
+		expected string
+	}{
+		{
+			casename: "multiple_state_machines",
+			src:      `{"StateMachines": {"a": {"TestCases": {}}, "b": {"TestCases": {}}}}`,
+			expected: "the mock config has 2 state machines [a, b], the name of the state machine is required",
+		},
+		{
+			casename: "unknown_response",
+			src:      `{"StateMachines": {"a": {"TestCases": {"Case": {"State": "Unknown"}}}}}`,
+			expected: "the mocked response Unknown of the state State is not found in the mock config",
+		},
+		{
+			casename: "invalid_range",
+			src: `{"StateMachines": {"a": {"TestCases": {"Case": {"State": "Response"}}}},
+"MockedResponses": {"Response": {"2-1": {"Return": {}}}}}`,
+			expected: "the mocked response Response: invalid invocation range 2-1",
+		},
+		{
+			casename: "overlapped",
+			src: `{"StateMachines": {"a": {"TestCases": {"Case": {"State": "Response"}}}},
+"MockedResponses": {"Response": {"0-1": {"Return": {}}, "1": {"Return": {}}}}}`,
+			expected: "the mocked response Response: the invocation 1 has more than one response",
+		},
+		{
+			casename: "missing_invocation",
+			src: `{"StateMachines": {"a": {"TestCases": {"Case": {"State": "Response"}}}},
+"MockedResponses": {"Response": {"1": {"Return": {}}}}}`,
+			expected: "the mocked response Response: the invocation 0 has no response",
+		},
+		{
+			casename: "empty_response",
+			src: `{"StateMachines": {"a": {"TestCases": {"Case": {"State": "Empty"}}}},
+"MockedResponses": {"Empty": {}}}`,
+			expected: "the mocked response Empty: no responses",
+		},
+		{
+			casename: "return_and_throw",
+			src: `{"StateMachines": {"a": {"TestCases": {"Case": {"State": "Response"}}}},
+"MockedResponses": {"Response": {"0": {"Return": {}, "Throw": {"Error": "Error"}}}}}`,
+			expected: "the mocked response Response: the response 0 must have either Return or Throw",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "MockConfigFile.json")
+			require.NoError(t, os.WriteFile(path, []byte(c.src), 0o644))
+			_, err := asltest.LoadTestsWithPath(path, "")
+			require.EqualError(t, err, c.expected)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package asltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mashiike/aslconv"
+	"github.com/mashiike/aslconv/exec"
+)
+
+// Result is the result of a test.
+type Result struct {
+	Name      string
+	Execution *exec.Execution
+	// Failures are the messages of the unmet expectations, that may have the diffs of multiple lines.
+	Failures []string
+}
+
+// Passed reports whether the test passed.
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Run runs the test against the state machine, with the Task states mocked.
+// The Task states without the mocks are handled by the TaskHandlers of the options, if any.
+// The execution uses exec.FakeClock unless the options set Clock, so the tests do not wait for the Wait states and the retries.
+func (test *Test) Run(ctx context.Context, asl *aslconv.AmazonStatesLanguage, optFns ...func(*exec.Options)) *Result {
+	result := &Result{Name: test.Name}
+	handlers := make(map[string]exec.TaskHandler, len(test.Mocks))
+	for name, mock := range test.Mocks {
+		state := findState(asl, name)
+		switch {
+		case state == nil:
+			result.Failures = append(result.Failures, fmt.Sprintf("the mocked state %s is not found", name))
+			continue
+		case mock.Type != "" && !strings.EqualFold(mock.Type, state.Type):
+			result.Failures = append(result.Failures, fmt.Sprintf("the mocked state %s is a %s state, not %s", name, state.Type, mock.Type))
+			continue
+		case state.Type != "Task":
+			result.Failures = append(result.Failures, fmt.Sprintf("the mocked state %s is not a Task state", name))
+			continue
+		}
+		handlers[name] = mock.handler()
+	}
+	if !result.Passed() {
+		return result
+	}
+	// the fake clock is used by default, so that the Wait states and the retries do not sleep
+	optFns = append([]func(*exec.Options){func(opts *exec.Options) {
+		opts.Clock = exec.NewFakeClock(time.Now())
+	}}, optFns...)
+	optFns = append(optFns, func(opts *exec.Options) {
+		opts.StateTaskHandlers = handlers
+	})
+	execution, err := exec.Execute(ctx, asl, test.Input, optFns...)
+	var stateErr *exec.Error
+	if err != nil && !errors.As(err, &stateErr) {
+		result.Failures = append(result.Failures, fmt.Sprintf("the execution can not be run: %s", err))
+		return result
+	}
+	result.Execution = execution
+	expect := test.Expect
+	if expect == nil {
+		expect = &Expect{}
+	}
+	result.Failures = append(result.Failures, expect.check(execution)...)
+	return result
+}
+
+func (expect *Expect) check(execution *exec.Execution) []string {
+	var failures []string
+	switch {
+	case expect.Error == "" && execution.Status != exec.StatusSucceeded:
+		failures = append(failures, fmt.Sprintf("the execution failed with the error %s: %s", execution.Error, execution.Cause))
+	case expect.Error != "" && execution.Status == exec.StatusSucceeded:
+		failures = append(failures, fmt.Sprintf("the execution succeeded, but expected the error %s", expect.Error))
+	case expect.Error != "" && expect.Error != execution.Error:
+		failures = append(failures, fmt.Sprintf("the execution failed with the error %s, but expected %s", execution.Error, expect.Error))
+	}
+	if expect.Cause != nil && *expect.Cause != execution.Cause {
+		failures = append(failures, fmt.Sprintf("the cause of the error is different:\n%s", diff(
+			[]string{*expect.Cause},
+			[]string{execution.Cause},
+		)))
+	}
+	if expect.Path != nil {
+		if actual := execution.Path(); !reflect.DeepEqual(expect.Path, actual) {
+			failures = append(failures, fmt.Sprintf("the path is different:\n%s", diff(expect.Path, actual)))
+		}
+	}
+	if expect.Output != nil && execution.Status == exec.StatusSucceeded {
+		expected, err := jsonLines(expect.Output)
+		if err != nil {
+			return append(failures, fmt.Sprintf("the expected output is not JSON: %s", err))
+		}
+		actual, err := jsonLines(execution.Output)
+		if err != nil {
+			return append(failures, fmt.Sprintf("the output is not JSON: %s", err))
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			failures = append(failures, fmt.Sprintf("the output is different:\n%s", diff(expected, actual)))
+		}
+	}
+	return failures
+}
+
+// jsonLines returns the lines of the indented JSON, with the keys of the objects sorted.
+func jsonLines(data json.RawMessage) ([]string, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"), nil
+}
+
+// handler returns the TaskHandler that returns the responses in order, and the last one repeatedly.
+func (mock *Mock) handler() exec.TaskHandler {
+	var (
+		mu          sync.Mutex
+		invocations int
+	)
+	return exec.TaskHandlerFunc(func(_ context.Context, _ string, _ json.RawMessage) (json.RawMessage, error) {
+		mu.Lock()
+		i := invocations
+		invocations++
+		mu.Unlock()
+		if len(mock.Responses) == 0 {
+			return nil, &exec.Error{Name: exec.ErrorRuntime, Cause: "the mock has no responses"}
+		}
+		if i >= len(mock.Responses) {
+			i = len(mock.Responses) - 1
+		}
+		response := mock.Responses[i]
+		if response.Error != "" {
+			return nil, &exec.Error{Name: response.Error, Cause: response.Cause}
+		}
+		return response.Return, nil
+	})
+}
+
+// findState returns the state of the name in the state machine, including the states of the branches and the item processors.
+func findState(asl *aslconv.AmazonStatesLanguage, name string) *aslconv.State {
+	if asl == nil {
+		return nil
+	}
+	for _, state := range asl.States {
+		if state.Name == name {
+			return state
+		}
+		for _, branch := range state.Branches {
+			if found := findState(branch, name); found != nil {
+				return found
+			}
+		}
+		if found := findState(state.Iterator, name); found != nil {
+			return found
+		}
+		if found := findState(state.ItemProcessor, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
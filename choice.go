@@ -0,0 +1,612 @@
+package aslconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// https://states-language.net/spec.html#choice-state
+type ChoiceRule struct {
+	Comment *string
+	// Condition is the JSONata expression of the choice rule, e.g. "{% $states.input.foo > 1 %}"
+	Condition *string
+	Variable  *string
+	// Operator is the name of comparison operator, e.g. "NumericEquals" or "IsPresent"
+	Operator string
+	// Value is the operand of the comparison operator
+	Value RawMessage
+	And   []*ChoiceRule
+	Or    []*ChoiceRule
+	Not   *ChoiceRule
+	// Assign and Output are used only in top-level choice rules
+	Assign RawMessage
+	Output RawMessage
+	Next   *string
+	// Extra holds the unknown fields of the choice rule.
+	Extra map[string]RawMessage
+
+	ranges sourceRanges
+}
+
+type ChoiceRules []*ChoiceRule
+
+type choiceOperandKind int
+
+const (
+	choiceOperandString choiceOperandKind = iota
+	choiceOperandNumber
+	choiceOperandBoolean
+	choiceOperandTimestamp
+	choiceOperandPath
+)
+
+func (k choiceOperandKind) String() string {
+	switch k {
+	case choiceOperandString:
+		return "string"
+	case choiceOperandNumber:
+		return "number"
+	case choiceOperandBoolean:
+		return "boolean"
+	case choiceOperandTimestamp:
+		return "timestamp"
+	case choiceOperandPath:
+		return "path"
+	}
+	return ""
+}
+
+func (k choiceOperandKind) ctyType() cty.Type {
+	switch k {
+	case choiceOperandNumber:
+		return cty.Number
+	case choiceOperandBoolean:
+		return cty.Bool
+	}
+	return cty.String
+}
+
+// https://states-language.net/spec.html#choice-state
+var choiceOperators = map[string]choiceOperandKind{
+	"StringEquals":                   choiceOperandString,
+	"StringEqualsPath":               choiceOperandPath,
+	"StringLessThan":                 choiceOperandString,
+	"StringLessThanPath":             choiceOperandPath,
+	"StringGreaterThan":              choiceOperandString,
+	"StringGreaterThanPath":          choiceOperandPath,
+	"StringLessThanEquals":           choiceOperandString,
+	"StringLessThanEqualsPath":       choiceOperandPath,
+	"StringGreaterThanEquals":        choiceOperandString,
+	"StringGreaterThanEqualsPath":    choiceOperandPath,
+	"StringMatches":                  choiceOperandString,
+	"NumericEquals":                  choiceOperandNumber,
+	"NumericEqualsPath":              choiceOperandPath,
+	"NumericLessThan":                choiceOperandNumber,
+	"NumericLessThanPath":            choiceOperandPath,
+	"NumericGreaterThan":             choiceOperandNumber,
+	"NumericGreaterThanPath":         choiceOperandPath,
+	"NumericLessThanEquals":          choiceOperandNumber,
+	"NumericLessThanEqualsPath":      choiceOperandPath,
+	"NumericGreaterThanEquals":       choiceOperandNumber,
+	"NumericGreaterThanEqualsPath":   choiceOperandPath,
+	"BooleanEquals":                  choiceOperandBoolean,
+	"BooleanEqualsPath":              choiceOperandPath,
+	"TimestampEquals":                choiceOperandTimestamp,
+	"TimestampEqualsPath":            choiceOperandPath,
+	"TimestampLessThan":              choiceOperandTimestamp,
+	"TimestampLessThanPath":          choiceOperandPath,
+	"TimestampGreaterThan":           choiceOperandTimestamp,
+	"TimestampGreaterThanPath":       choiceOperandPath,
+	"TimestampLessThanEquals":        choiceOperandTimestamp,
+	"TimestampLessThanEqualsPath":    choiceOperandPath,
+	"TimestampGreaterThanEquals":     choiceOperandTimestamp,
+	"TimestampGreaterThanEqualsPath": choiceOperandPath,
+	"IsNull":                         choiceOperandBoolean,
+	"IsPresent":                      choiceOperandBoolean,
+	"IsNumeric":                      choiceOperandBoolean,
+	"IsString":                       choiceOperandBoolean,
+	"IsBoolean":                      choiceOperandBoolean,
+	"IsTimestamp":                    choiceOperandBoolean,
+}
+
+// choiceRuleJSONNames are the known field names of the choice rule, other than the comparison operators.
+var choiceRuleJSONNames = []string{"Comment", "Condition", "Variable", "Assign", "Output", "And", "Or", "Not", "Next"}
+
+// knownChoiceRuleJSONNames returns the known field names of the choice rule, including the comparison operators.
+func knownChoiceRuleJSONNames() []string {
+	known := make([]string, 0, len(choiceRuleJSONNames)+len(choiceOperators))
+	known = append(known, choiceRuleJSONNames...)
+	for operator := range choiceOperators {
+		known = append(known, operator)
+	}
+	return known
+}
+
+var choiceOperatorsByHCLName = func() map[string]string {
+	names := make(map[string]string, len(choiceOperators))
+	for operator := range choiceOperators {
+		names[toSnakeCase(operator)] = operator
+	}
+	return names
+}()
+
+// toSnakeCase converts the ASL field name to the HCL attribute name, e.g. NumericEquals to numeric_equals
+func toSnakeCase(name string) string {
+	var builder strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				builder.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+func (rule *ChoiceRule) MarshalJSON() ([]byte, error) {
+	data := make(map[string]interface{})
+	if rule.Comment != nil {
+		data["Comment"] = *rule.Comment
+	}
+	if rule.Condition != nil {
+		data["Condition"] = *rule.Condition
+	}
+	if rule.Variable != nil {
+		data["Variable"] = *rule.Variable
+	}
+	if rule.Operator != "" {
+		data[rule.Operator] = rule.Value
+	}
+	if rule.Assign != nil {
+		data["Assign"] = rule.Assign
+	}
+	if rule.Output != nil {
+		data["Output"] = rule.Output
+	}
+	if len(rule.And) > 0 {
+		data["And"] = rule.And
+	}
+	if len(rule.Or) > 0 {
+		data["Or"] = rule.Or
+	}
+	if rule.Not != nil {
+		data["Not"] = rule.Not
+	}
+	if rule.Next != nil {
+		data["Next"] = *rule.Next
+	}
+	for key, value := range rule.Extra {
+		if _, ok := data[key]; !ok {
+			data[key] = value
+		}
+	}
+	return json.Marshal(data)
+}
+
+func (rule *ChoiceRule) UnmarshalJSON(bs []byte) error {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(bs, &data); err != nil {
+		return err
+	}
+	*rule = ChoiceRule{}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := data[key]
+		var err error
+		switch key {
+		case "Comment":
+			err = json.Unmarshal(value, &rule.Comment)
+		case "Condition":
+			err = json.Unmarshal(value, &rule.Condition)
+		case "Variable":
+			err = json.Unmarshal(value, &rule.Variable)
+		case "Assign":
+			rule.Assign = RawMessage(value)
+		case "Output":
+			rule.Output = RawMessage(value)
+		case "And":
+			err = json.Unmarshal(value, &rule.And)
+		case "Or":
+			err = json.Unmarshal(value, &rule.Or)
+		case "Not":
+			err = json.Unmarshal(value, &rule.Not)
+		case "Next":
+			err = json.Unmarshal(value, &rule.Next)
+		default:
+			if _, ok := choiceOperators[key]; !ok {
+				if rule.Extra == nil {
+					rule.Extra = make(map[string]RawMessage)
+				}
+				rule.Extra[key] = RawMessage(value)
+				continue
+			}
+			if rule.Operator != "" {
+				return fmt.Errorf("choice rule has multiple comparison operators `%s` and `%s`", rule.Operator, key)
+			}
+			rule.Operator = key
+			rule.Value = RawMessage(value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%w", key, err)
+		}
+	}
+	return nil
+}
+
+func (rule *ChoiceRule) provideBodySchema() (*hcl.BodySchema, bool) {
+	schema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "comment"},
+			{Name: "condition"},
+			{Name: "variable"},
+			{Name: "assign"},
+			{Name: "output"},
+			{Name: "next"},
+			{Name: "extra"},
+		},
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "and"},
+			{Type: "or"},
+			{Type: "not"},
+		},
+	}
+	names := make([]string, 0, len(choiceOperatorsByHCLName))
+	for name := range choiceOperatorsByHCLName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema.Attributes = append(schema.Attributes, hcl.AttributeSchema{Name: name})
+	}
+	return schema, false
+}
+
+func (rule *ChoiceRule) unmarshalHCLContent(content *hcl.BodyContent, _ hcl.Body, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if rule.ranges == nil {
+		rule.ranges = make(sourceRanges)
+	}
+	var operatorRange *hcl.Range
+	for _, attr := range content.Attributes {
+		rule.ranges[attr.Name] = attr.Expr.Range().Ptr()
+		switch attr.Name {
+		case "comment":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &rule.Comment)
+			diags = append(diags, decodeDiags...)
+		case "condition":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &rule.Condition)
+			diags = append(diags, decodeDiags...)
+		case "variable":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &rule.Variable)
+			diags = append(diags, decodeDiags...)
+		case "assign":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &rule.Assign)
+			diags = append(diags, decodeDiags...)
+		case "output":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &rule.Output)
+			diags = append(diags, decodeDiags...)
+		case "next":
+			decodeDiags := decodeExpression(attr.Expr, ctx, &rule.Next)
+			diags = append(diags, decodeDiags...)
+		case "extra":
+			decodeDiags := decodeExtra(attr.Expr, ctx, knownChoiceRuleJSONNames(), &rule.Extra)
+			diags = append(diags, decodeDiags...)
+		default:
+			operator := choiceOperatorsByHCLName[attr.Name]
+			if operatorRange != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate comparison operator",
+					Detail:   fmt.Sprintf(`Only one comparison operator is allowed in a choice rule. Another was defined at %s`, operatorRange.String()),
+					Subject:  attr.NameRange.Ptr(),
+				})
+				continue
+			}
+			operatorRange = attr.NameRange.Ptr()
+			rule.Operator = operator
+			decodeDiags := rule.Value.decodeOperand(attr.Expr, ctx, choiceOperators[operator])
+			diags = append(diags, decodeDiags...)
+		}
+	}
+	for _, block := range content.Blocks {
+		var nested ChoiceRule
+		nested.ranges = sourceRanges{"": block.DefRange.Ptr()}
+		diags = append(diags, unmarshalHCLBody(block.Body, ctx, &nested)...)
+		switch block.Type {
+		case "and":
+			rule.And = append(rule.And, &nested)
+		case "or":
+			rule.Or = append(rule.Or, &nested)
+		case "not":
+			if rule.Not != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  `Duplicate "not" block`,
+					Detail:   fmt.Sprintf(`Only one "not" block is allowed. Another was defined at %s`, rule.Not.ranges.get("").String()),
+					Subject:  block.DefRange.Ptr(),
+				})
+				continue
+			}
+			rule.Not = &nested
+		}
+	}
+	return diags
+}
+
+func (m *RawMessage) decodeOperand(expr hcl.Expression, ctx *hcl.EvalContext, kind choiceOperandKind) hcl.Diagnostics {
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return diags
+	}
+	converted, err := convert.Convert(value, kind.ctyType())
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid comparison operand",
+			Detail:   fmt.Sprintf(`The operand must be a %s: %s.`, kind, err),
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	bs, err := ctyjson.Marshal(converted, kind.ctyType())
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid comparison operand",
+			Detail:   err.Error(),
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	*m = RawMessage(bs)
+	return diags
+}
+
+func (rules *ChoiceRules) decodeExpression(expr hcl.Expression, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var raws RawMessages
+	diags := decodeExpression(expr, ctx, &raws)
+	if diags.HasErrors() {
+		return diags
+	}
+	for i, raw := range raws {
+		var rule ChoiceRule
+		if err := json.Unmarshal(raw, &rule); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid choice rule",
+				Detail:   fmt.Sprintf(`choices[%d]:%s`, i, err),
+				Subject:  expr.Range().Ptr(),
+			})
+			continue
+		}
+		rule.ranges = sourceRanges{"": expr.Range().Ptr()}
+		*rules = append(*rules, &rule)
+	}
+	return diags
+}
+
+func (rule *ChoiceRule) encodeBody(body *hclwrite.Body, states States) error {
+	if rule.Comment != nil {
+		body.SetAttributeValue("comment", cty.StringVal(*rule.Comment))
+	}
+	if rule.Condition != nil {
+		body.SetAttributeValue("condition", cty.StringVal(*rule.Condition))
+	}
+	if rule.Variable != nil {
+		body.SetAttributeValue("variable", cty.StringVal(*rule.Variable))
+	}
+	if rule.Operator != "" {
+		value, err := rule.Value.ctyValue()
+		if err != nil {
+			return fmt.Errorf("%s:%w", rule.Operator, err)
+		}
+		body.SetAttributeValue(toSnakeCase(rule.Operator), value)
+	}
+	if rule.Assign != nil {
+		value, err := rule.Assign.ctyValue()
+		if err != nil {
+			return fmt.Errorf("assign:%w", err)
+		}
+		body.SetAttributeValue("assign", value)
+	}
+	if rule.Output != nil {
+		value, err := rule.Output.ctyValue()
+		if err != nil {
+			return fmt.Errorf("output:%w", err)
+		}
+		body.SetAttributeValue("output", value)
+	}
+	if rule.Next != nil {
+		traversal, err := states.getTraversal(*rule.Next)
+		if err != nil {
+			return err
+		}
+		body.SetAttributeTraversal("next", traversal)
+	}
+	if len(rule.Extra) > 0 {
+		value, err := extraCtyValue(rule.Extra)
+		if err != nil {
+			return fmt.Errorf("extra:%w", err)
+		}
+		body.SetAttributeValue("extra", value)
+	}
+	type nestedRule struct {
+		blockType string
+		rule      *ChoiceRule
+	}
+	nestedBlocks := make([]nestedRule, 0, len(rule.And)+len(rule.Or)+1)
+	for _, nested := range rule.And {
+		nestedBlocks = append(nestedBlocks, nestedRule{blockType: "and", rule: nested})
+	}
+	for _, nested := range rule.Or {
+		nestedBlocks = append(nestedBlocks, nestedRule{blockType: "or", rule: nested})
+	}
+	if rule.Not != nil {
+		nestedBlocks = append(nestedBlocks, nestedRule{blockType: "not", rule: rule.Not})
+	}
+	for i, nested := range nestedBlocks {
+		if i > 0 || len(body.Attributes()) > 0 {
+			body.AppendNewline()
+		}
+		block := body.AppendNewBlock(nested.blockType, nil)
+		if err := nested.rule.encodeBody(block.Body(), states); err != nil {
+			return fmt.Errorf("%s:%w", nested.blockType, err)
+		}
+	}
+	return nil
+}
+
+func (rule *ChoiceRule) validate(path string, isTopLevel bool) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if isTopLevel && rule.Next == nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing transition",
+			Detail:   fmt.Sprintf(`%s has no Next. A top-level choice rule must have Next.`, path),
+			Subject:  rule.ranges.get(""),
+		})
+	}
+	if !isTopLevel && rule.Next != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unexpected transition",
+			Detail:   fmt.Sprintf(`%s has Next. A nested choice rule can not have Next.`, path),
+			Subject:  rule.ranges.get("next"),
+		})
+	}
+	if !isTopLevel && rule.Condition != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid choice rule",
+			Detail:   fmt.Sprintf(`%s has Condition. A nested choice rule can not have Condition.`, path),
+			Subject:  rule.ranges.get("condition"),
+		})
+	}
+	if !isTopLevel && (rule.Assign != nil || rule.Output != nil) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid choice rule",
+			Detail:   fmt.Sprintf(`%s has Assign or Output. A nested choice rule can not have them.`, path),
+			Subject:  rule.ranges.get(""),
+		})
+	}
+	var expressions int
+	if rule.Condition != nil {
+		expressions++
+	}
+	if rule.Operator != "" {
+		expressions++
+	}
+	if len(rule.And) > 0 {
+		expressions++
+	}
+	if len(rule.Or) > 0 {
+		expressions++
+	}
+	if rule.Not != nil {
+		expressions++
+	}
+	if expressions != 1 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid choice rule",
+			Detail:   fmt.Sprintf(`%s must have exactly one of Condition, a comparison operator, And, Or or Not.`, path),
+			Subject:  rule.ranges.get(""),
+		})
+	}
+	if rule.Operator != "" {
+		diags = append(diags, rule.validateOperand(path)...)
+	}
+	if rule.Operator == "" && rule.Variable != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid choice rule",
+			Detail:   fmt.Sprintf(`%s has Variable without a comparison operator.`, path),
+			Subject:  rule.ranges.get("variable"),
+		})
+	}
+	for i, nested := range rule.And {
+		diags = append(diags, nested.validate(fmt.Sprintf("%s.And[%d]", path, i), false)...)
+	}
+	for i, nested := range rule.Or {
+		diags = append(diags, nested.validate(fmt.Sprintf("%s.Or[%d]", path, i), false)...)
+	}
+	if rule.Not != nil {
+		diags = append(diags, rule.Not.validate(path+".Not", false)...)
+	}
+	return diags
+}
+
+func (rule *ChoiceRule) validateOperand(path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	attr := toSnakeCase(rule.Operator)
+	kind, ok := choiceOperators[rule.Operator]
+	if !ok {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid comparison operator",
+			Detail:   fmt.Sprintf(`%s has unknown comparison operator "%s".`, path, rule.Operator),
+			Subject:  rule.ranges.get(""),
+		})
+	}
+	if rule.Variable == nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing Variable",
+			Detail:   fmt.Sprintf(`%s.%s requires Variable.`, path, rule.Operator),
+			Subject:  rule.ranges.get(attr),
+		})
+	}
+	var value interface{}
+	if err := json.Unmarshal(rule.Value, &value); err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid comparison operand",
+			Detail:   fmt.Sprintf(`%s.%s is not a valid JSON: %s`, path, rule.Operator, err),
+			Subject:  rule.ranges.get(attr),
+		})
+	}
+	valid := false
+	switch kind {
+	case choiceOperandNumber:
+		_, valid = value.(float64)
+	case choiceOperandBoolean:
+		_, valid = value.(bool)
+	case choiceOperandString:
+		_, valid = value.(string)
+	case choiceOperandPath:
+		var str string
+		str, valid = value.(string)
+		valid = valid && strings.HasPrefix(str, "$")
+	case choiceOperandTimestamp:
+		var str string
+		str, valid = value.(string)
+		if valid {
+			_, err := time.Parse(time.RFC3339, str)
+			valid = err == nil
+		}
+	}
+	if !valid {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid comparison operand",
+			Detail:   fmt.Sprintf(`%s.%s must be a %s, but got %s.`, path, rule.Operator, kind, string(rule.Value)),
+			Subject:  rule.ranges.get(attr),
+		})
+	}
+	return diags
+}
@@ -1,29 +1,70 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/mashiike/aslconv"
+	"github.com/mashiike/aslconv/asltest"
+	"github.com/zclconf/go-cty/cty"
 )
 
 const usage = `aslconv is Amazon State Language(ASL) Format Converter
 
   usages:
     aslconv -l
-    aslconv [options] asl_file
+    aslconv [options] asl_file or project_directory
     cat asl_file | aslconv -f json -t hcl
+    aslconv validate [options] asl_file or project_directory
+    aslconv fmt [options] [asl_file or directory ...]
+    aslconv test [options] asl_file test_file or directory ...
 
   options:
     -f, --from-formant  original format
     -t, --to-formant    converted format
 	-l, --list          displays a list of formats
 	-o, --output        output destination. If unspecified, output to stdout
+                        If a directory such as dir/, writes a file for each state_machine block
+    -s, --state-order   order of the states in output, source(default), traversal or alphabetical
+    -var name=value     sets the input variable, can be specified multiple times
+    -var-file path      sets the input variables from the file, can be specified multiple times
     -h, --help          prints help information
+
+  validate options:
+    -f, --from-formant  original format, when load from stdin
+    -var name=value     sets the input variable, can be specified multiple times
+    -var-file path      sets the input variables from the file, can be specified multiple times
+
+  project_directory is the directory of *.asl.hcl and *.asl.hcl.json files, that are merged as one state machine.
+
+  -var and -var-file are applied in the order of the command line, the latter overrides the former.
+  input variables are also set by the environment variables ASLCONV_VAR_<name>,
+  that are overridden by -var and -var-file, and ignored if the variables are not declared.
+
+  fmt options:
+    -check              lists the files that are not formatted, and exits with non-zero status if any
+    -write              overwrites the files with the formatted source, instead of printing to stdout
+                        -check and -write can not be used together, and -write requires the files
+    -s, --state-order   order of the states, source(default), traversal or alphabetical
+
+  test options:
+    -state-machine name the state machine name in the MockConfigFile, required when it has more than one
+    -run regexp         runs only the tests whose names match the regular expression
+    -var name=value     sets the input variable, can be specified multiple times
+    -var-file path      sets the input variables from the file, can be specified multiple times
+
+  test_file is a *.asltest.hcl file or a MockConfigFile of Step Functions Local,
+  and the directory is expanded into the *.asltest.hcl files in it.
 `
 
 func main() {
@@ -34,11 +75,23 @@ func main() {
 }
 
 func _main() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			return _validate(os.Args[2:])
+		case "fmt":
+			return _fmt(os.Args[2:])
+		case "test":
+			return _test(os.Args[2:])
+		}
+	}
 	var (
 		from     string
 		to       string
 		showList bool
 		output   string
+		order    string
+		vars     variableFlags
 	)
 	flag.StringVar(&from, "from-formant", "", "")
 	flag.StringVar(&from, "f", "", "")
@@ -48,11 +101,16 @@ func _main() error {
 	flag.BoolVar(&showList, "l", false, "")
 	flag.StringVar(&output, "output", "", "")
 	flag.StringVar(&output, "o", "", "")
+	flag.StringVar(&order, "state-order", "", "")
+	flag.StringVar(&order, "s", "", "")
+	vars.register(flag.CommandLine)
 	flag.Usage = func() { fmt.Print(usage) }
 	flag.Parse()
 
+	// the output ending with a slash or an existing directory writes a file for each state machine
+	outputDir := output != "" && (strings.HasSuffix(output, "/") || isDir(output))
 	var out io.Writer = os.Stdout
-	if output != "" {
+	if output != "" && !outputDir {
 		fp, err := os.Create(output)
 		if err != nil {
 			return err
@@ -71,7 +129,21 @@ func _main() error {
 	if !ok {
 		return fmt.Errorf("-to-format option: %s is unknown format", to)
 	}
+	stateOrder, ok := aslconv.GetStateOrder(order)
+	if !ok {
+		return fmt.Errorf("--state-order option: %s is unknown order", order)
+	}
+	variablesOptFn, err := vars.loadOptions()
+	if err != nil {
+		return err
+	}
 	log.Printf("convert to %s", toFormat)
+	if outputDir {
+		if flag.NArg() == 0 {
+			return errors.New("asl_file or project_directory is required, when output to a directory")
+		}
+		return writeProject(flag.Arg(0), output, toFormat, stateOrder, variablesOptFn)
+	}
 	var asl *aslconv.AmazonStatesLanguage
 	if flag.NArg() == 0 {
 		if from == "" {
@@ -79,7 +151,7 @@ func _main() error {
 		}
 		log.Println("load from stdin")
 		var err error
-		asl, err = aslconv.LoadASLWithReader(os.Stdin, from)
+		asl, err = aslconv.LoadASLWithReader(os.Stdin, from, variablesOptFn)
 		if err != nil {
 			return err
 		}
@@ -87,13 +159,350 @@ func _main() error {
 		path := flag.Arg(0)
 		log.Printf("load from %s", path)
 		var err error
-		asl, err = aslconv.LoadASLWithPath(path)
+		asl, err = aslconv.LoadASLWithPath(path, variablesOptFn)
 		if err != nil {
 			return err
 		}
 	}
-	if err := toFormat.WriteASL(out, asl); err != nil {
+	if err := toFormat.WriteASL(out, asl, func(opts *aslconv.WriteOptions) {
+		opts.StateOrder = stateOrder
+	}); err != nil {
 		return err
 	}
 	return nil
 }
+
+// writeProject writes the state machines of the project into the directory, as <name>.<ext> for each state machine.
+func writeProject(path string, dir string, toFormat aslconv.Format, stateOrder aslconv.StateOrder, optFn func(*aslconv.LoadOptions)) error {
+	log.Printf("load project from %s", path)
+	machines, err := aslconv.LoadProject(path, optFn)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(machines))
+	for name := range machines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ext := strings.TrimPrefix(toFormat.Exts()[0], "*")
+	for _, name := range names {
+		filename := filepath.Join(dir, name+ext)
+		log.Printf("write %s", filename)
+		fp, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		err = toFormat.WriteASL(fp, machines[name], func(opts *aslconv.WriteOptions) {
+			opts.StateOrder = stateOrder
+		})
+		if closeErr := fp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isDir(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.IsDir()
+}
+
+// variableFlags holds -var and -var-file options in the order of the command line.
+type variableFlags []variableFlag
+
+type variableFlag struct {
+	name  string
+	value string
+}
+
+func (f *variableFlags) register(fs *flag.FlagSet) {
+	for _, name := range []string{"var", "var-file"} {
+		name := name
+		fs.Func(name, "", func(s string) error {
+			*f = append(*f, variableFlag{name: name, value: s})
+			return nil
+		})
+	}
+}
+
+// loadOptions returns the LoadOptions function that sets the input variables.
+// The values of -var and -var-file are applied in the order of the command line, the latter overrides the former.
+// ASLCONV_VAR_* environment variables are overridden by them, and ignored if the variables are not declared.
+func (f *variableFlags) loadOptions() (func(*aslconv.LoadOptions), error) {
+	values := make(map[string]cty.Value)
+	for _, v := range *f {
+		switch v.name {
+		case "var":
+			name, value, err := aslconv.ParseVariableFlag(v.value)
+			if err != nil {
+				return nil, fmt.Errorf("-var option: %w", err)
+			}
+			values[name] = value
+		case "var-file":
+			fileValues, err := aslconv.LoadVariablesFile(v.value)
+			if err != nil {
+				return nil, fmt.Errorf("-var-file %s: %w", v.value, err)
+			}
+			for name, value := range fileValues {
+				values[name] = value
+			}
+		}
+	}
+	environ := aslconv.VariablesFromEnviron(os.Environ())
+	return func(opts *aslconv.LoadOptions) {
+		opts.Variables = values
+		opts.EnvironVariables = environ
+	}, nil
+}
+
+func _validate(args []string) error {
+	var (
+		from string
+		vars variableFlags
+	)
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.StringVar(&from, "from-formant", "", "")
+	fs.StringVar(&from, "f", "", "")
+	vars.register(fs)
+	fs.Usage = func() { fmt.Print(usage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	variablesOptFn, err := vars.loadOptions()
+	if err != nil {
+		return err
+	}
+	optFn := func(opts *aslconv.LoadOptions) {
+		opts.Validate = true
+		variablesOptFn(opts)
+	}
+	if fs.NArg() == 0 {
+		if from == "" {
+			return errors.New("--from-format or -f option is required, when load from stdin")
+		}
+		log.Println("validate stdin")
+		if _, err := aslconv.LoadASLWithReader(os.Stdin, from, optFn); err != nil {
+			return err
+		}
+		log.Println("stdin is valid")
+		return nil
+	}
+	path := fs.Arg(0)
+	log.Printf("validate %s", path)
+	if _, err := aslconv.LoadASLWithPath(path, optFn); err != nil {
+		return err
+	}
+	log.Printf("%s is valid", path)
+	return nil
+}
+
+func _fmt(args []string) error {
+	var (
+		check bool
+		write bool
+		order string
+	)
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	fs.BoolVar(&check, "check", false, "")
+	fs.BoolVar(&write, "write", false, "")
+	fs.StringVar(&order, "state-order", "source", "")
+	fs.StringVar(&order, "s", "source", "")
+	fs.Usage = func() { fmt.Print(usage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	stateOrder, ok := aslconv.GetStateOrder(order)
+	if !ok {
+		return fmt.Errorf("--state-order option: %s is unknown order", order)
+	}
+	if check && write {
+		return errors.New("-check and -write options can not be used together")
+	}
+	optFn := func(opts *aslconv.FormatOptions) {
+		opts.StateOrder = stateOrder
+	}
+	if fs.NArg() == 0 {
+		if write {
+			return errors.New("-write option requires asl_file or directory, stdin can not be overwritten")
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		formatted, diags := aslconv.FormatHCLSource(src, "<stdin>", optFn)
+		if diags.HasErrors() {
+			return diags
+		}
+		if check {
+			if !bytes.Equal(src, formatted) {
+				return errors.New("stdin is not formatted")
+			}
+			return nil
+		}
+		_, err = os.Stdout.Write(formatted)
+		return err
+	}
+	paths, err := fmtTargets(fs.Args())
+	if err != nil {
+		return err
+	}
+	var unformatted []string
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		formatted, diags := aslconv.FormatHCLSource(src, path, optFn)
+		if diags.HasErrors() {
+			return diags
+		}
+		switch {
+		case check:
+			if !bytes.Equal(src, formatted) {
+				fmt.Println(path)
+				unformatted = append(unformatted, path)
+			}
+		case write:
+			if bytes.Equal(src, formatted) {
+				continue
+			}
+			stat, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, formatted, stat.Mode()); err != nil {
+				return err
+			}
+			fmt.Println(path)
+		default:
+			if _, err := os.Stdout.Write(formatted); err != nil {
+				return err
+			}
+		}
+	}
+	if len(unformatted) > 0 {
+		return fmt.Errorf("%d file(s) are not formatted", len(unformatted))
+	}
+	return nil
+}
+
+// fmtTargets expands the directories into the *.asl.hcl files in them.
+func fmtTargets(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		stat, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !isFormattableFile(entry.Name()) {
+				continue
+			}
+			paths = append(paths, filepath.Join(arg, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// isFormattableFile reports whether the file is a project file in the native HCL syntax.
+// The files in the JSON syntax such as *.asl.hcl.json are not formatted.
+func isFormattableFile(name string) bool {
+	for _, suffix := range aslconv.ProjectFileSuffixes {
+		if strings.HasSuffix(suffix, ".json") {
+			continue
+		}
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func _test(args []string) error {
+	var (
+		stateMachine string
+		run          string
+		vars         variableFlags
+	)
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	fs.StringVar(&stateMachine, "state-machine", "", "")
+	fs.StringVar(&run, "run", "", "")
+	vars.register(fs)
+	fs.Usage = func() { fmt.Print(usage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return errors.New("asl_file and test_file or directory are required")
+	}
+	var runPattern *regexp.Regexp
+	if run != "" {
+		var err error
+		if runPattern, err = regexp.Compile(run); err != nil {
+			return fmt.Errorf("-run option: %w", err)
+		}
+	}
+	variablesOptFn, err := vars.loadOptions()
+	if err != nil {
+		return err
+	}
+	asl, err := aslconv.LoadASLWithPath(fs.Arg(0), variablesOptFn)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for _, arg := range fs.Args()[1:] {
+		if !isDir(arg) {
+			paths = append(paths, arg)
+			continue
+		}
+		files, err := asltest.TestFiles(arg)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, files...)
+	}
+	var passed, failed int
+	for _, path := range paths {
+		tests, err := asltest.LoadTestsWithPath(path, stateMachine)
+		if err != nil {
+			return err
+		}
+		for _, test := range tests {
+			if runPattern != nil && !runPattern.MatchString(test.Name) {
+				continue
+			}
+			result := test.Run(context.Background(), asl)
+			if result.Passed() {
+				fmt.Printf("--- PASS: %s\n", result.Name)
+				passed++
+				continue
+			}
+			fmt.Printf("--- FAIL: %s\n", result.Name)
+			for _, failure := range result.Failures {
+				fmt.Println("    " + strings.ReplaceAll(failure, "\n", "\n    "))
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("FAIL: %d passed, %d failed\n", passed, failed)
+		return fmt.Errorf("%d test(s) failed", failed)
+	}
+	fmt.Printf("PASS: %d passed\n", passed)
+	return nil
+}
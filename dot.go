@@ -10,13 +10,20 @@ import (
 )
 
 type MarshalDOTOptions struct {
-	PrepareGraph          func(*gographviz.Graph) error
-	TerminalNodeAttrs     func() map[string]string
-	StateNodeAttrs        func(*State) map[string]string
-	EdgeAttrs             func(label string) map[string]string
-	ChoiceEdgeAttrs       func(condition map[string]interface{}, i int) map[string]string
+	PrepareGraph      func(*gographviz.Graph) error
+	TerminalNodeAttrs func() map[string]string
+	StateNodeAttrs    func(*State) map[string]string
+	EdgeAttrs         func(label string) map[string]string
+	// ChoiceEdgeAttrs is called with the choice rule decoded as a JSON object, and takes precedence over ChoiceRuleEdgeAttrs if set.
+	//
+	// Deprecated: Use ChoiceRuleEdgeAttrs instead.
+	ChoiceEdgeAttrs func(condition map[string]interface{}, i int) map[string]string
+	// ChoiceRuleEdgeAttrs is used for the edge of the i-th choice rule.
+	ChoiceRuleEdgeAttrs   func(rule *ChoiceRule, i int) map[string]string
 	BranchesSubGraphAttrs func(*State) map[string]string
 	IteratorSubGraphAttrs func(*State) map[string]string
+	// ItemProcessorSubGraphAttrs is used for the subgraph of ItemProcessor of Map state.
+	ItemProcessorSubGraphAttrs func(*State) map[string]string
 }
 
 func (top *AmazonStatesLanguage) MarshalDOT(graphName string, optFns ...func(*MarshalDOTOptions)) (string, error) {
@@ -48,7 +55,7 @@ func (top *AmazonStatesLanguage) MarshalDOT(graphName string, optFns ...func(*Ma
 			}
 			return attrs
 		},
-		ChoiceEdgeAttrs: func(_ map[string]interface{}, i int) map[string]string {
+		ChoiceRuleEdgeAttrs: func(_ *ChoiceRule, i int) map[string]string {
 			attrs := map[string]string{
 				"arrowhead": "vee",
 				"label":     fmt.Sprintf(`"Rule #%d"`, i+1),
@@ -73,6 +80,15 @@ func (top *AmazonStatesLanguage) MarshalDOT(graphName string, optFns ...func(*Ma
 				"labeljust": `"l"`,
 			}
 		},
+		ItemProcessorSubGraphAttrs: func(s *State) map[string]string {
+			return map[string]string{
+				"shape":     `"box"`,
+				"style":     `"dashed"`,
+				"fillcolor": `"#00000080"`,
+				"label":     fmt.Sprintf(`"%s(item processor)"`, s.Name),
+				"labeljust": `"l"`,
+			}
+		},
 	}
 	for _, optFn := range optFns {
 		optFn(opts)
@@ -160,13 +176,17 @@ func (state *State) marshalDOT(g *gographviz.Graph, graphName string, startName
 		}
 		return nil
 	}
-	if state.Iterator != nil {
-		subGraphAttrs := opts.IteratorSubGraphAttrs(state)
+	iterator, subGraphAttrsFn := state.Iterator, opts.IteratorSubGraphAttrs
+	if state.ItemProcessor != nil {
+		iterator, subGraphAttrsFn = state.ItemProcessor, opts.ItemProcessorSubGraphAttrs
+	}
+	if iterator != nil {
+		subGraphAttrs := subGraphAttrsFn(state)
 		subGraphName := "cluster_" + state.Name
 		if err := g.AddSubGraph(quoteForNode(graphName), quoteForNode(subGraphName), subGraphAttrs); err != nil {
 			return err
 		}
-		err := state.Iterator.marshalDOT(g, subGraphName, state.Name, subGraphName+"_end", opts)
+		err := iterator.marshalDOT(g, subGraphName, state.Name, subGraphName+"_end", opts)
 		if err != nil {
 			return err
 		}
@@ -193,15 +213,23 @@ func (state *State) marshalDOT(g *gographviz.Graph, graphName string, startName
 	if state.Default != nil && *state.Default != "" {
 		nextStates[*state.Default] = opts.EdgeAttrs("default")
 	}
-	for i, rawMessage := range state.Choices {
-		var choice map[string]interface{}
-		if err := json.Unmarshal([]byte(rawMessage), &choice); err != nil {
+	for i, rule := range state.Choices {
+		if rule.Next == nil {
+			continue
+		}
+		if opts.ChoiceEdgeAttrs == nil {
+			nextStates[*rule.Next] = opts.ChoiceRuleEdgeAttrs(rule, i)
+			continue
+		}
+		bs, err := json.Marshal(rule)
+		if err != nil {
 			return err
 		}
-		if next, ok := choice["Next"].(string); ok {
-			nextStates[next] = opts.ChoiceEdgeAttrs(choice, i)
-
+		var choice map[string]interface{}
+		if err := json.Unmarshal(bs, &choice); err != nil {
+			return err
 		}
+		nextStates[*rule.Next] = opts.ChoiceEdgeAttrs(choice, i)
 	}
 	for next, edgeAttrs := range nextStates {
 		if err := g.AddEdge(quoteForNode(state.Name), quoteForNode(next), true, edgeAttrs); err != nil {
@@ -0,0 +1,282 @@
+package aslconv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Editor updates an existing HCL file in place.
+// Unlike EncodeBody, that regenerates the whole file, comments, locals and expressions such as `local.function_arn` are kept as they are.
+// States are looked up by name in the top-level states first, and then in the states of branch, iterator and item_processor blocks.
+// The states of each state_machine block are top-level states, use StateMachine to edit only one of the state machines.
+type Editor struct {
+	file *hclwrite.File
+	// body is the body of the file, or the body of the state_machine block being edited.
+	body *hclwrite.Body
+}
+
+// NewEditor parses the HCL source for editing.
+func NewEditor(src []byte, filename string) (*Editor, hcl.Diagnostics) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return &Editor{file: file, body: file.Body()}, diags
+}
+
+// StateMachine returns the Editor of the state_machine block named name, that edits the same file.
+func (e *Editor) StateMachine(name string) (*Editor, error) {
+	for _, block := range e.file.Body().Blocks() {
+		if block.Type() == "state_machine" && len(block.Labels()) == 1 && block.Labels()[0] == name {
+			return &Editor{file: e.file, body: block.Body()}, nil
+		}
+	}
+	return nil, fmt.Errorf("state machine `%s` not found", name)
+}
+
+// stateMachineBodies returns the bodies of the state_machine blocks in the body.
+func stateMachineBodies(body *hclwrite.Body) []*hclwrite.Body {
+	var bodies []*hclwrite.Body
+	for _, block := range body.Blocks() {
+		if block.Type() == "state_machine" {
+			bodies = append(bodies, block.Body())
+		}
+	}
+	return bodies
+}
+
+// File returns the underlying hclwrite.File.
+func (e *Editor) File() *hclwrite.File {
+	return e.file
+}
+
+// Bytes returns the edited HCL source.
+func (e *Editor) Bytes() []byte {
+	return e.file.Bytes()
+}
+
+// stateScope is a body that declares states, i.e. the top-level body or the body of branch, iterator or item_processor block.
+type stateScope struct {
+	body   *hclwrite.Body
+	blocks []*hclwrite.Block
+}
+
+var stateScopeBlockTypes = map[string]bool{
+	"branch":         true,
+	"iterator":       true,
+	"item_processor": true,
+}
+
+func newStateScope(body *hclwrite.Body) *stateScope {
+	scope := &stateScope{body: body}
+	for _, block := range body.Blocks() {
+		if block.Type() == "state" && len(block.Labels()) == 2 {
+			scope.blocks = append(scope.blocks, block)
+		}
+	}
+	return scope
+}
+
+func (scope *stateScope) find(name string) *hclwrite.Block {
+	for _, block := range scope.blocks {
+		if block.Labels()[1] == name {
+			return block
+		}
+	}
+	return nil
+}
+
+// states returns the states declared in the scope, that has only Type and Name for traversals.
+func (scope *stateScope) states() States {
+	states := make(States, 0, len(scope.blocks))
+	for _, block := range scope.blocks {
+		states = append(states, &State{Type: block.Labels()[0], Name: block.Labels()[1]})
+	}
+	return states
+}
+
+// scopes returns all state scopes in the file, the top-level scope comes first.
+func (e *Editor) scopes() []*stateScope {
+	var scopes []*stateScope
+	var walk func(body *hclwrite.Body)
+	walk = func(body *hclwrite.Body) {
+		scope := newStateScope(body)
+		scopes = append(scopes, scope)
+		for _, state := range scope.blocks {
+			for _, block := range state.Body().Blocks() {
+				if stateScopeBlockTypes[block.Type()] {
+					walk(block.Body())
+				}
+			}
+		}
+	}
+	walk(e.body)
+	for _, body := range stateMachineBodies(e.body) {
+		walk(body)
+	}
+	return scopes
+}
+
+func (e *Editor) findState(name string) (*stateScope, *hclwrite.Block, error) {
+	for _, scope := range e.scopes() {
+		if block := scope.find(name); block != nil {
+			return scope, block, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("state `%s` not found", name)
+}
+
+// AddState appends the state to the top-level states.
+// Next, Default and the other transitions of the state must refer to the top-level states or the state itself.
+// If the file has state_machine blocks, AddState must be called on the Editor returned by StateMachine.
+func (e *Editor) AddState(state *State) error {
+	if len(stateMachineBodies(e.body)) > 0 {
+		return errors.New("the file has state_machine blocks, choose one of them by StateMachine")
+	}
+	scope := newStateScope(e.body)
+	if scope.find(state.Name) != nil {
+		return fmt.Errorf("state `%s` already exists", state.Name)
+	}
+	block, err := state.EncodeAsBlock(append(scope.states(), state))
+	if err != nil {
+		return fmt.Errorf("%s:%w", state.Name, err)
+	}
+	e.body.AppendNewline()
+	e.body.AppendBlock(block)
+	return nil
+}
+
+// RemoveState removes the state block and the blank line after it. Transitions to the removed state are not changed.
+func (e *Editor) RemoveState(name string) error {
+	scope, block, err := e.findState(name)
+	if err != nil {
+		return err
+	}
+	removeBodyItem(scope.body, block.BuildTokens(nil), func() {
+		scope.body.RemoveBlock(block)
+	})
+	return nil
+}
+
+// removeBodyItem removes the item of the body by remove, and the blank line that separated the item from the others.
+// The item is located by its tokens, that are shared with the body, so the items rendered identically are not confused.
+func removeBodyItem(body *hclwrite.Body, item hclwrite.Tokens, remove func()) {
+	tokens := body.BuildTokens(nil)
+	start := -1
+	for i, token := range tokens {
+		if len(item) > 0 && token == item[0] {
+			start = i
+			break
+		}
+	}
+	remove()
+	if start < 0 {
+		return
+	}
+	end := start + len(item)
+	atStart := true
+	for _, token := range tokens[:start] {
+		if token.Type != hclsyntax.TokenNewline {
+			atStart = false
+			break
+		}
+	}
+	atEnd := end >= len(tokens) || tokens[end].Type == hclsyntax.TokenEOF
+	blankAfter := !atEnd && tokens[end].Type == hclsyntax.TokenNewline
+	blankBefore := start >= 2 && tokens[start-1].Type == hclsyntax.TokenNewline && isLineEnd(tokens[start-2])
+	switch {
+	case atStart && blankAfter:
+		tokens[end].Bytes = nil
+	case blankBefore && (blankAfter || atEnd):
+		tokens[start-1].Bytes = nil
+	}
+}
+
+// isLineEnd reports whether the token ends a line, the comment token includes the newline.
+func isLineEnd(token *hclwrite.Token) bool {
+	return token.Type == hclsyntax.TokenNewline || (token.Type == hclsyntax.TokenComment && bytes.HasSuffix(token.Bytes, []byte("\n")))
+}
+
+// RenameState renames the state, and rewrites the references such as `state.task.OldName` in the same scope.
+func (e *Editor) RenameState(oldName, newName string) error {
+	scope, block, err := e.findState(oldName)
+	if err != nil {
+		return err
+	}
+	if scope.find(newName) != nil {
+		return fmt.Errorf("state `%s` already exists", newName)
+	}
+	stateType := block.Labels()[0]
+	block.SetLabels([]string{stateType, newName})
+	renameVariablePrefix(
+		scope.body,
+		[]string{"state", stateType, oldName},
+		[]string{"state", stateType, newName},
+	)
+	return nil
+}
+
+// renameVariablePrefix rewrites the references in the body, except in the nested state scopes.
+func renameVariablePrefix(body *hclwrite.Body, search, replacement []string) {
+	for _, attr := range body.Attributes() {
+		attr.Expr().RenameVariablePrefix(search, replacement)
+	}
+	for _, block := range body.Blocks() {
+		if stateScopeBlockTypes[block.Type()] {
+			continue
+		}
+		renameVariablePrefix(block.Body(), search, replacement)
+	}
+}
+
+// SetAttribute sets the attribute of the state to the value.
+func (e *Editor) SetAttribute(stateName, name string, value cty.Value) error {
+	_, block, err := e.findState(stateName)
+	if err != nil {
+		return err
+	}
+	block.Body().SetAttributeValue(name, value)
+	return nil
+}
+
+// SetTransition sets the attribute of the state, e.g. "next" or "default", to the reference to the next state.
+func (e *Editor) SetTransition(stateName, name, next string) error {
+	scope, block, err := e.findState(stateName)
+	if err != nil {
+		return err
+	}
+	nextBlock := scope.find(next)
+	if nextBlock == nil {
+		return fmt.Errorf("state `%s` not found in the scope of `%s`", next, stateName)
+	}
+	block.Body().SetAttributeTraversal(name, hcl.Traversal{
+		hcl.TraverseRoot{Name: "state"},
+		hcl.TraverseAttr{Name: strings.ToLower(nextBlock.Labels()[0])},
+		hcl.TraverseAttr{Name: next},
+	})
+	return nil
+}
+
+// RemoveAttribute removes the attribute of the state, and the blank line that separated it from the others.
+func (e *Editor) RemoveAttribute(stateName, name string) error {
+	_, block, err := e.findState(stateName)
+	if err != nil {
+		return err
+	}
+	body := block.Body()
+	attr := body.GetAttribute(name)
+	if attr == nil {
+		return fmt.Errorf("state `%s` has no attribute `%s`", stateName, name)
+	}
+	removeBodyItem(body, attr.BuildTokens(nil), func() {
+		body.RemoveAttribute(name)
+	})
+	return nil
+}
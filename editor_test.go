@@ -0,0 +1,463 @@
+package aslconv_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const editorSource = `# The example of editing
+locals {
+  function_arn = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+}
+
+start_at = state.task.Hello
+
+// Hello calls the function
+state "task" "Hello" {
+  resource = local.function_arn # keep this reference
+  next     = state.pass.World
+}
+
+state "pass" "World" {
+  end = true
+}
+
+state "parallel" "Parallel" {
+  end = true
+
+  branch {
+    start_at = state.pass.World
+
+    state "pass" "World" {
+      end = true
+    }
+  }
+}
+`
+
+func TestEditor(t *testing.T) {
+	editor, diags := aslconv.NewEditor([]byte(editorSource), "editor.asl.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	require.NoError(t, editor.RenameState("Hello", "Greeting"))
+	require.NoError(t, editor.SetAttribute("Greeting", "timeout_seconds", cty.NumberIntVal(30)))
+	require.NoError(t, editor.AddState(&aslconv.State{
+		Type:   "Succeed",
+		Name:   "Done",
+		Output: aslconv.RawMessage(`{"message":"done"}`),
+	}))
+	require.NoError(t, editor.SetTransition("Greeting", "next", "Done"))
+	require.NoError(t, editor.RemoveState("World"))
+	require.NoError(t, editor.RemoveAttribute("Parallel", "end"))
+
+	require.EqualError(t, editor.RenameState("Missing", "Other"), "state `Missing` not found")
+	require.EqualError(t, editor.AddState(&aslconv.State{Type: "Pass", Name: "Done", End: ptr(true)}), "state `Done` already exists")
+
+	expected := `# The example of editing
+locals {
+  function_arn = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+}
+
+start_at = state.task.Greeting
+
+// Hello calls the function
+state "task" "Greeting" {
+  resource        = local.function_arn # keep this reference
+  next            = state.succeed.Done
+  timeout_seconds = 30
+}
+
+state "parallel" "Parallel" {
+  branch {
+    start_at = state.pass.World
+
+    state "pass" "World" {
+      end = true
+    }
+  }
+}
+
+state "succeed" "Done" {
+  output = {
+    message = "done"
+  }
+}
+`
+	require.Equal(t, expected, string(editor.Bytes()))
+}
+
+const editorOperationSource = `start_at = state.task.Hello
+
+state "task" "Hello" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+  next     = state.pass.World
+}
+
+state "pass" "World" {
+  end = true
+}
+`
+
+const editorStateMachinesSource = `state_machine "orders" {
+  start_at = state.pass.Order
+
+  state "pass" "Order" {
+    end = true
+  }
+}
+
+state_machine "refunds" {
+  start_at = state.pass.Refund
+
+  state "pass" "Refund" {
+    end = true
+  }
+}
+`
+
+func TestEditorOperations(t *testing.T) {
+	cases := []struct {
+		casename string
+		src      string
+		edit     func(editor *aslconv.Editor) error
+		expected string
+		err      string
+	}{
+		{
+			casename: "add_state",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.AddState(&aslconv.State{Type: "Succeed", Name: "Done"})
+			},
+			expected: editorOperationSource + `
+state "succeed" "Done" {
+}
+`,
+		},
+		{
+			casename: "add_state_exists",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.AddState(&aslconv.State{Type: "Pass", Name: "World", End: ptr(true)})
+			},
+			err: "state `World` already exists",
+		},
+		{
+			casename: "remove_state",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RemoveState("Hello")
+			},
+			expected: `start_at = state.task.Hello
+
+state "pass" "World" {
+  end = true
+}
+`,
+		},
+		{
+			casename: "remove_last_state",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RemoveState("World")
+			},
+			expected: `start_at = state.task.Hello
+
+state "task" "Hello" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+  next     = state.pass.World
+}
+`,
+		},
+		{
+			casename: "remove_state_rendered_identically",
+			src: `state "parallel" "Parallel" {
+branch {
+start_at = state.pass.World
+state "pass" "World" {
+end = true
+}
+}
+}
+
+state "pass" "World" {
+end = true
+}
+`,
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RemoveState("World")
+			},
+			expected: `state "parallel" "Parallel" {
+  branch {
+    start_at = state.pass.World
+    state "pass" "World" {
+      end = true
+    }
+  }
+}
+`,
+		},
+		{
+			casename: "remove_state_missing",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RemoveState("Missing")
+			},
+			err: "state `Missing` not found",
+		},
+		{
+			casename: "rename_state",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RenameState("World", "Earth")
+			},
+			expected: `start_at = state.task.Hello
+
+state "task" "Hello" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+  next     = state.pass.Earth
+}
+
+state "pass" "Earth" {
+  end = true
+}
+`,
+		},
+		{
+			casename: "rename_state_exists",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RenameState("World", "Hello")
+			},
+			err: "state `Hello` already exists",
+		},
+		{
+			casename: "rename_state_missing",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RenameState("Missing", "Other")
+			},
+			err: "state `Missing` not found",
+		},
+		{
+			casename: "set_attribute",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.SetAttribute("World", "result", cty.StringVal("ok"))
+			},
+			expected: `start_at = state.task.Hello
+
+state "task" "Hello" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+  next     = state.pass.World
+}
+
+state "pass" "World" {
+  end    = true
+  result = "ok"
+}
+`,
+		},
+		{
+			casename: "set_attribute_missing_state",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.SetAttribute("Missing", "result", cty.StringVal("ok"))
+			},
+			err: "state `Missing` not found",
+		},
+		{
+			casename: "set_transition",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.SetTransition("Hello", "next", "Hello")
+			},
+			expected: `start_at = state.task.Hello
+
+state "task" "Hello" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+  next     = state.task.Hello
+}
+
+state "pass" "World" {
+  end = true
+}
+`,
+		},
+		{
+			casename: "set_transition_missing_state",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.SetTransition("Missing", "next", "World")
+			},
+			err: "state `Missing` not found",
+		},
+		{
+			casename: "set_transition_missing_next",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.SetTransition("Hello", "next", "Missing")
+			},
+			err: "state `Missing` not found in the scope of `Hello`",
+		},
+		{
+			casename: "remove_attribute",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RemoveAttribute("Hello", "next")
+			},
+			expected: `start_at = state.task.Hello
+
+state "task" "Hello" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+}
+
+state "pass" "World" {
+  end = true
+}
+`,
+		},
+		{
+			casename: "remove_attribute_missing_state",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RemoveAttribute("Missing", "next")
+			},
+			err: "state `Missing` not found",
+		},
+		{
+			casename: "rename_state_in_state_machine",
+			src:      editorStateMachinesSource,
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RenameState("Refund", "Return")
+			},
+			expected: `state_machine "orders" {
+  start_at = state.pass.Order
+
+  state "pass" "Order" {
+    end = true
+  }
+}
+
+state_machine "refunds" {
+  start_at = state.pass.Return
+
+  state "pass" "Return" {
+    end = true
+  }
+}
+`,
+		},
+		{
+			casename: "set_attribute_in_state_machine",
+			src:      editorStateMachinesSource,
+			edit: func(editor *aslconv.Editor) error {
+				return editor.SetAttribute("Order", "result", cty.StringVal("ok"))
+			},
+			expected: `state_machine "orders" {
+  start_at = state.pass.Order
+
+  state "pass" "Order" {
+    end    = true
+    result = "ok"
+  }
+}
+
+state_machine "refunds" {
+  start_at = state.pass.Refund
+
+  state "pass" "Refund" {
+    end = true
+  }
+}
+`,
+		},
+		{
+			casename: "add_state_to_state_machine",
+			src:      editorStateMachinesSource,
+			edit: func(editor *aslconv.Editor) error {
+				orders, err := editor.StateMachine("orders")
+				if err != nil {
+					return err
+				}
+				return orders.AddState(&aslconv.State{Type: "Succeed", Name: "Done"})
+			},
+			expected: `state_machine "orders" {
+  start_at = state.pass.Order
+
+  state "pass" "Order" {
+    end = true
+  }
+
+  state "succeed" "Done" {
+  }
+}
+
+state_machine "refunds" {
+  start_at = state.pass.Refund
+
+  state "pass" "Refund" {
+    end = true
+  }
+}
+`,
+		},
+		{
+			casename: "add_state_with_state_machines",
+			src:      editorStateMachinesSource,
+			edit: func(editor *aslconv.Editor) error {
+				return editor.AddState(&aslconv.State{Type: "Succeed", Name: "Done"})
+			},
+			err: "the file has state_machine blocks, choose one of them by StateMachine",
+		},
+		{
+			casename: "remove_state_in_state_machine",
+			src:      editorStateMachinesSource,
+			edit: func(editor *aslconv.Editor) error {
+				refunds, err := editor.StateMachine("refunds")
+				if err != nil {
+					return err
+				}
+				if err := refunds.RemoveState("Order"); err == nil {
+					return errors.New("the state of the other state machine must not be found")
+				}
+				return editor.RemoveState("Order")
+			},
+			expected: `state_machine "orders" {
+  start_at = state.pass.Order
+}
+
+state_machine "refunds" {
+  start_at = state.pass.Refund
+
+  state "pass" "Refund" {
+    end = true
+  }
+}
+`,
+		},
+		{
+			casename: "state_machine_missing",
+			src:      editorStateMachinesSource,
+			edit: func(editor *aslconv.Editor) error {
+				_, err := editor.StateMachine("missing")
+				return err
+			},
+			err: "state machine `missing` not found",
+		},
+		{
+			casename: "remove_attribute_missing",
+			edit: func(editor *aslconv.Editor) error {
+				return editor.RemoveAttribute("World", "next")
+			},
+			err: "state `World` has no attribute `next`",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			src := c.src
+			if src == "" {
+				src = editorOperationSource
+			}
+			editor, diags := aslconv.NewEditor([]byte(src), "editor.asl.hcl")
+			require.False(t, diags.HasErrors(), diags.Error())
+			err := c.edit(editor)
+			if c.err != "" {
+				require.EqualError(t, err, c.err)
+				require.Equal(t, src, string(editor.Bytes()))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, string(editor.Bytes()))
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mashiike/aslconv"
+	"github.com/mashiike/aslconv/jsonpath"
+)
+
+// evaluateChoiceRule reports whether the choice rule matches the input.
+// The comparison of the values of different types does not match, e.g. StringEquals to a number.
+func evaluateChoiceRule(rule *aslconv.ChoiceRule, input, contextObject interface{}) (bool, error) {
+	switch {
+	case len(rule.And) > 0:
+		for _, nested := range rule.And {
+			matched, err := evaluateChoiceRule(nested, input, contextObject)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	case len(rule.Or) > 0:
+		for _, nested := range rule.Or {
+			matched, err := evaluateChoiceRule(nested, input, contextObject)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+	case rule.Not != nil:
+		matched, err := evaluateChoiceRule(rule.Not, input, contextObject)
+		return !matched, err
+	case rule.Condition != nil:
+		return false, &Error{Name: ErrorRuntime, Cause: "the JSONata condition of the choice rule is not supported"}
+	case rule.Variable == nil || rule.Operator == "":
+		return false, &Error{Name: ErrorRuntime, Cause: "the choice rule has neither a comparison nor And, Or and Not"}
+	}
+	variablePath, err := jsonpath.ParseReference(*rule.Variable)
+	if err != nil {
+		return false, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("Variable: %s", err)}
+	}
+	variable, found := variablePath.Lookup(input, contextObject)
+	operand, err := decodeJSON(rule.Value)
+	if err != nil {
+		return false, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("%s: %s", rule.Operator, err)}
+	}
+	if rule.Operator == "IsPresent" {
+		return found == (operand == true), nil
+	}
+	if !found {
+		return false, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("Invalid path %s: the choice rule references an invalid value", *rule.Variable)}
+	}
+	operator := rule.Operator
+	if strings.HasSuffix(operator, "Path") {
+		operator = strings.TrimSuffix(operator, "Path")
+		s, ok := operand.(string)
+		if !ok {
+			return false, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("%s: the operand must be a path", rule.Operator)}
+		}
+		operandPath, err := jsonpath.ParseReference(s)
+		if err != nil {
+			return false, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("%s: %s", rule.Operator, err)}
+		}
+		if operand, found = operandPath.Lookup(input, contextObject); !found {
+			return false, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("Invalid path %s: the choice rule references an invalid value", s)}
+		}
+	}
+	switch operator {
+	case "IsNull":
+		return (variable == nil) == (operand == true), nil
+	case "IsNumeric":
+		_, ok := variable.(json.Number)
+		return ok == (operand == true), nil
+	case "IsString":
+		_, ok := variable.(string)
+		return ok == (operand == true), nil
+	case "IsBoolean":
+		_, ok := variable.(bool)
+		return ok == (operand == true), nil
+	case "IsTimestamp":
+		_, ok := timestampOf(variable)
+		return ok == (operand == true), nil
+	case "BooleanEquals":
+		b, ok := variable.(bool)
+		return ok && b == operand, nil
+	case "StringMatches":
+		s, ok := variable.(string)
+		pattern, isString := operand.(string)
+		return ok && isString && stringMatches(s, pattern), nil
+	}
+	var compare func(a, b interface{}) (int, bool)
+	var prefix string
+	switch {
+	case strings.HasPrefix(operator, "String"):
+		compare, prefix = compareStrings, "String"
+	case strings.HasPrefix(operator, "Numeric"):
+		compare, prefix = compareNumbers, "Numeric"
+	case strings.HasPrefix(operator, "Timestamp"):
+		compare, prefix = compareTimestamps, "Timestamp"
+	}
+	if compare != nil {
+		c, ok := compare(variable, operand)
+		if !ok {
+			return false, nil
+		}
+		switch strings.TrimPrefix(operator, prefix) {
+		case "Equals":
+			return c == 0, nil
+		case "LessThan":
+			return c < 0, nil
+		case "GreaterThan":
+			return c > 0, nil
+		case "LessThanEquals":
+			return c <= 0, nil
+		case "GreaterThanEquals":
+			return c >= 0, nil
+		}
+	}
+	return false, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("the comparison operator %s is not supported", rule.Operator)}
+}
+
+func compareStrings(a, b interface{}) (int, bool) {
+	x, ok := a.(string)
+	y, ok2 := b.(string)
+	if !ok || !ok2 {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+func compareNumbers(a, b interface{}) (int, bool) {
+	x, ok := a.(json.Number)
+	y, ok2 := b.(json.Number)
+	if !ok || !ok2 {
+		return 0, false
+	}
+	fx, err := x.Float64()
+	if err != nil {
+		return 0, false
+	}
+	fy, err := y.Float64()
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case fx < fy:
+		return -1, true
+	case fx > fy:
+		return 1, true
+	}
+	return 0, true
+}
+
+func compareTimestamps(a, b interface{}) (int, bool) {
+	x, ok := timestampOf(a)
+	y, ok2 := timestampOf(b)
+	if !ok || !ok2 {
+		return 0, false
+	}
+	switch {
+	case x.Before(y):
+		return -1, true
+	case x.After(y):
+		return 1, true
+	}
+	return 0, true
+}
+
+func timestampOf(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+// stringMatches reports whether s matches the pattern, that has the wildcards `*`. `\*` and `\\` are the escaped characters.
+// It is the greedy matching that backtracks only to the last wildcard, so it runs in O(len(s) * len(pattern)) time.
+func stringMatches(s, pattern string) bool {
+	// wildcards[i] reports whether literals[i] is the wildcard, after the escapes are resolved
+	var literals []byte
+	var wildcards []bool
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '*':
+			literals, wildcards = append(literals, '*'), append(wildcards, true)
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			literals, wildcards = append(literals, pattern[i]), append(wildcards, false)
+		default:
+			literals, wildcards = append(literals, pattern[i]), append(wildcards, false)
+		}
+	}
+	si, pi := 0, 0
+	// star is the position of the last wildcard in the pattern and next is the position in s that it is retried from
+	star, next := -1, 0
+	for si < len(s) {
+		switch {
+		case pi < len(literals) && wildcards[pi]:
+			star, next = pi, si
+			pi++
+		case pi < len(literals) && literals[pi] == s[si]:
+			si++
+			pi++
+		case star >= 0:
+			next++
+			si, pi = next, star+1
+		default:
+			return false
+		}
+	}
+	for pi < len(literals) && wildcards[pi] {
+		pi++
+	}
+	return pi == len(literals)
+}
@@ -0,0 +1,76 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mashiike/aslconv/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteChoiceRules(t *testing.T) {
+	input := `{
+  "string": "hello world",
+  "number": 10,
+  "bool": true,
+  "null": null,
+  "timestamp": "2024-01-01T00:00:00Z",
+  "other": 10,
+  "long": "` + strings.Repeat("a", 100) + `"
+}`
+	cases := []struct {
+		rule    string
+		matched bool
+	}{
+		{`{"Variable": "$.string", "StringEquals": "hello world"}`, true},
+		{`{"Variable": "$.string", "StringLessThan": "world"}`, true},
+		{`{"Variable": "$.string", "StringMatches": "hello *"}`, true},
+		{`{"Variable": "$.string", "StringMatches": "hello\\*"}`, false},
+		{`{"Variable": "$.string", "StringMatches": "*o*o*d"}`, true},
+		{`{"Variable": "$.string", "StringMatches": "*world*"}`, true},
+		{`{"Variable": "$.string", "StringMatches": "*o*x*"}`, false},
+		{`{"Variable": "$.string", "StringMatches": "hello world**"}`, true},
+		{`{"Variable": "$.long", "StringMatches": "` + strings.Repeat("*a", 20) + `*b"}`, false},
+		{`{"Variable": "$.long", "StringMatches": "` + strings.Repeat("*a", 20) + `"}`, true},
+		{`{"Variable": "$.number", "StringEquals": "10"}`, false},
+		{`{"Variable": "$.number", "NumericEquals": 10}`, true},
+		{`{"Variable": "$.number", "NumericGreaterThanEquals": 10.5}`, false},
+		{`{"Variable": "$.number", "NumericEqualsPath": "$.other"}`, true},
+		{`{"Variable": "$.bool", "BooleanEquals": true}`, true},
+		{`{"Variable": "$.timestamp", "TimestampLessThan": "2024-01-02T00:00:00Z"}`, true},
+		{`{"Variable": "$.null", "IsNull": true}`, true},
+		{`{"Variable": "$.missing", "IsPresent": false}`, true},
+		{`{"Variable": "$.number", "IsNumeric": true}`, true},
+		{`{"Variable": "$.string", "IsTimestamp": true}`, false},
+		{`{"And": [{"Variable": "$.bool", "BooleanEquals": true}, {"Variable": "$.number", "NumericLessThan": 5}]}`, false},
+		{`{"Or": [{"Variable": "$.bool", "BooleanEquals": false}, {"Variable": "$.number", "NumericLessThan": 20}]}`, true},
+		{`{"Not": {"Variable": "$.string", "StringEquals": "hello"}}`, true},
+	}
+	for _, c := range cases {
+		t.Run(c.rule, func(t *testing.T) {
+			var rule map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(c.rule), &rule))
+			rule["Next"] = "Matched"
+			bs, err := json.Marshal(rule)
+			require.NoError(t, err)
+			asl := parseASL(t, fmt.Sprintf(`{
+  "StartAt": "Choice",
+  "States": {
+    "Choice": {"Type": "Choice", "Choices": [%s], "Default": "Unmatched"},
+    "Matched": {"Type": "Succeed"},
+    "Unmatched": {"Type": "Succeed"}
+  }
+}`, bs))
+			execution, err := exec.Execute(context.Background(), asl, json.RawMessage(input))
+			require.NoError(t, err)
+			expected := "Unmatched"
+			if c.matched {
+				expected = "Matched"
+			}
+			require.Equal(t, []string{"Choice", expected}, execution.Path())
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package exec
+
+import (
+	"context"
+	"errors"
+)
+
+// The predefined error names of the Amazon States Language.
+// https://states-language.net/spec.html#appendix-a
+const (
+	ErrorAll                    = "States.ALL"
+	ErrorHeartbeatTimeout       = "States.HeartbeatTimeout"
+	ErrorTimeout                = "States.Timeout"
+	ErrorTaskFailed             = "States.TaskFailed"
+	ErrorPermissions            = "States.Permissions"
+	ErrorResultPathMatchFailure = "States.ResultPathMatchFailure"
+	ErrorParameterPathFailure   = "States.ParameterPathFailure"
+	ErrorBranchFailed           = "States.BranchFailed"
+	ErrorNoChoiceMatched        = "States.NoChoiceMatched"
+	ErrorIntrinsicFailure       = "States.IntrinsicFailure"
+	ErrorRuntime                = "States.Runtime"
+)
+
+// Error is the error of a state, that is matched by ErrorEquals of Retry and Catch.
+type Error struct {
+	// Name is the error name, e.g. "States.TaskFailed".
+	Name  string
+	Cause string
+}
+
+func (e *Error) Error() string {
+	if e.Cause == "" {
+		return e.Name
+	}
+	return e.Name + ": " + e.Cause
+}
+
+// taskError converts the error of TaskHandler into *Error.
+func taskError(err error) error {
+	var stateErr *Error
+	if errors.As(err, &stateErr) {
+		return stateErr
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Name: ErrorTimeout, Cause: err.Error()}
+	}
+	return &Error{Name: ErrorTaskFailed, Cause: err.Error()}
+}
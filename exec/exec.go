@@ -0,0 +1,300 @@
+// Package exec runs the state machines of aslconv in-process, without deploying them to AWS.
+//
+// The states are executed by the semantics of the Amazon States Language with JSONPath,
+// and the Task states call the TaskHandler registered for the Resource.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mashiike/aslconv"
+)
+
+// TaskHandler handles the Task states of the Resource.
+// The returned error is reported as States.TaskFailed, unless it is *Error that has the error name.
+type TaskHandler interface {
+	Invoke(ctx context.Context, resource string, input json.RawMessage) (json.RawMessage, error)
+}
+
+// TaskHandlerFunc is the function that implements TaskHandler.
+type TaskHandlerFunc func(ctx context.Context, resource string, input json.RawMessage) (json.RawMessage, error)
+
+func (f TaskHandlerFunc) Invoke(ctx context.Context, resource string, input json.RawMessage) (json.RawMessage, error) {
+	return f(ctx, resource, input)
+}
+
+type Options struct {
+	// TaskHandlers are the handlers of the Task states, keyed by Resource.
+	TaskHandlers map[string]TaskHandler
+	// StateTaskHandlers are the handlers of the Task states keyed by the state name, that take precedence over TaskHandlers.
+	// They are used to mock the specific states, e.g. by the unit tests of the state machine.
+	StateTaskHandlers map[string]TaskHandler
+	// Clock is used by the Wait states, the delays of the retries and the timestamps of the history, the default is SystemClock.
+	Clock Clock
+	// Random returns a pseudo-random number in [0.0, 1.0) for the full jitter of the retries,
+	// States.MathRandom without the seed and States.UUID, the default is rand.Float64.
+	Random func() float64
+	// Name is the name of the execution, that is referred as $$.Execution.Name.
+	Name string
+	// StateMachineName is the name of the state machine, that is referred as $$.StateMachine.Name.
+	StateMachineName string
+}
+
+// Execution is the result of Execute.
+type Execution struct {
+	// Status is "SUCCEEDED" or "FAILED".
+	Status string
+	Output json.RawMessage
+	// Error and Cause are set when the execution failed.
+	Error   string
+	Cause   string
+	History []*Event
+}
+
+const (
+	StatusSucceeded = "SUCCEEDED"
+	StatusFailed    = "FAILED"
+)
+
+// Path returns the names of the top-level states entered in the execution, in order.
+// The states of the branches of Parallel and the iterations of Map are not included.
+func (e *Execution) Path() []string {
+	var path []string
+	for _, event := range e.History {
+		if event.Type == EventStateEntered && event.Parent == "" {
+			path = append(path, event.State)
+		}
+	}
+	return path
+}
+
+// EventType is the type of the history event.
+type EventType string
+
+const (
+	EventStateEntered EventType = "StateEntered"
+	EventStateExited  EventType = "StateExited"
+	EventStateFailed  EventType = "StateFailed"
+	// EventStateRetried is recorded for each failed attempt that is retried, with the delay before the next attempt.
+	EventStateRetried EventType = "StateRetried"
+	// EventStateCaught is recorded when the error of the last attempt is caught, followed by EventStateExited.
+	EventStateCaught EventType = "StateCaught"
+)
+
+// Event is the history event of the execution.
+// The events of the branches of Parallel and the iterations of Map are recorded with Parent and Index,
+// and the events of the concurrent branches and iterations may be interleaved.
+type Event struct {
+	Type  EventType
+	State string
+	// Parent is the name of the Parallel or Map state that runs the branch or the iteration of the state, empty for the top-level states.
+	Parent string `json:",omitempty"`
+	// Index is the index of the branch of Parallel or the iteration of Map, that is meaningful only when Parent is set.
+	Index     int `json:",omitempty"`
+	Timestamp time.Time
+	Input     json.RawMessage `json:",omitempty"`
+	Output    json.RawMessage `json:",omitempty"`
+	Error     string          `json:",omitempty"`
+	Cause     string          `json:",omitempty"`
+	// Attempt is the number of the attempt of the state from 1, that is set for EventStateRetried and EventStateCaught.
+	Attempt int           `json:",omitempty"`
+	Delay   time.Duration `json:",omitempty"`
+}
+
+// Execute runs the state machine with the input, from StartAt to a terminal state.
+// When the execution fails, Execute returns the Execution with the status FAILED and the *Error.
+// The other errors are returned when the execution can not be run, e.g. the input is not JSON or ctx is canceled.
+func Execute(ctx context.Context, asl *aslconv.AmazonStatesLanguage, input json.RawMessage, optFns ...func(*Options)) (*Execution, error) {
+	opts := &Options{
+		Clock:            SystemClock,
+		Random:           rand.Float64,
+		Name:             "local",
+		StateMachineName: "local",
+	}
+	for _, optFn := range optFns {
+		optFn(opts)
+	}
+	if len(bytes.TrimSpace(input)) == 0 {
+		input = json.RawMessage(`{}`)
+	}
+	value, err := decodeJSON(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	e := &executor{
+		opts: opts,
+		contextObject: map[string]interface{}{
+			"Execution": map[string]interface{}{
+				"Id":        fmt.Sprintf("arn:aws:states:local:000000000000:execution:%s:%s", opts.StateMachineName, opts.Name),
+				"Input":     value,
+				"Name":      opts.Name,
+				"StartTime": formatTime(opts.Clock.Now()),
+			},
+			"StateMachine": map[string]interface{}{
+				"Id":   fmt.Sprintf("arn:aws:states:local:000000000000:stateMachine:%s", opts.StateMachineName),
+				"Name": opts.StateMachineName,
+			},
+		},
+		history: &history{},
+	}
+	output, err := e.run(ctx, asl, value)
+	execution := &Execution{
+		History: e.history.events,
+	}
+	var stateErr *Error
+	switch {
+	case err == nil:
+		execution.Status = StatusSucceeded
+		execution.Output = encodeJSON(output)
+		return execution, nil
+	case errors.As(err, &stateErr):
+		execution.Status = StatusFailed
+		execution.Error = stateErr.Name
+		execution.Cause = stateErr.Cause
+		return execution, stateErr
+	}
+	return nil, err
+}
+
+// executor runs the states of a state machine, a branch of Parallel or an iteration of Map.
+type executor struct {
+	opts *Options
+	// contextObject is the context object, that is referred as $$.
+	contextObject map[string]interface{}
+	history       *history
+	// parent and index are set to the events of the branch or the iteration.
+	parent string
+	index  int
+}
+
+type history struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (h *history) add(event *Event) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+}
+
+// child returns the executor of the i-th branch or iteration of the Parallel or Map state named parent,
+// that records the events into the same history.
+func (e *executor) child(contextObject map[string]interface{}, parent string, i int) *executor {
+	return &executor{
+		opts:          e.opts,
+		contextObject: contextObject,
+		history:       e.history,
+		parent:        parent,
+		index:         i,
+	}
+}
+
+// addEvent records the event of the state run by the executor.
+func (e *executor) addEvent(event *Event) {
+	event.Parent, event.Index = e.parent, e.index
+	e.history.add(event)
+}
+
+func (e *executor) run(ctx context.Context, asl *aslconv.AmazonStatesLanguage, input interface{}) (interface{}, error) {
+	states := make(map[string]*aslconv.State, len(asl.States))
+	for _, state := range asl.States {
+		states[state.Name] = state
+	}
+	queryLanguage := "JSONPath"
+	if asl.QueryLanguage != nil {
+		queryLanguage = *asl.QueryLanguage
+	}
+	name := asl.StartAt
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		state, ok := states[name]
+		if !ok {
+			return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("the state %s is not found", name)}
+		}
+		e.addEvent(&Event{
+			Type:      EventStateEntered,
+			State:     name,
+			Timestamp: e.opts.Clock.Now(),
+			Input:     encodeJSON(input),
+		})
+		output, next, err := e.runState(ctx, state, queryLanguage, input)
+		if err != nil {
+			var stateErr *Error
+			if errors.As(err, &stateErr) {
+				e.addEvent(&Event{
+					Type:      EventStateFailed,
+					State:     name,
+					Timestamp: e.opts.Clock.Now(),
+					Error:     stateErr.Name,
+					Cause:     stateErr.Cause,
+				})
+			}
+			return nil, err
+		}
+		e.addEvent(&Event{
+			Type:      EventStateExited,
+			State:     name,
+			Timestamp: e.opts.Clock.Now(),
+			Output:    encodeJSON(output),
+		})
+		if next == "" {
+			return output, nil
+		}
+		name, input = next, output
+	}
+}
+
+// stateContext returns the context object in the state.
+func (e *executor) stateContext(state *aslconv.State, enteredTime time.Time, retryCount int) map[string]interface{} {
+	contextObject := make(map[string]interface{}, len(e.contextObject)+1)
+	for key, value := range e.contextObject {
+		contextObject[key] = value
+	}
+	contextObject["State"] = map[string]interface{}{
+		"Name":        state.Name,
+		"EnteredTime": formatTime(enteredTime),
+		"RetryCount":  json.Number(strconv.Itoa(retryCount)),
+	}
+	return contextObject
+}
+
+// decodeJSON decodes the JSON with json.Number, to keep the numbers as they are.
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+func encodeJSON(value interface{}) json.RawMessage {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		// the values are decoded from JSON, so they are always encodable
+		panic(err)
+	}
+	return bs
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
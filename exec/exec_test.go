@@ -0,0 +1,309 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashiike/aslconv"
+	"github.com/mashiike/aslconv/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func loadASL(t *testing.T, path string) *aslconv.AmazonStatesLanguage {
+	t.Helper()
+	asl, err := aslconv.LoadASLWithPath(path)
+	require.NoError(t, err)
+	return asl
+}
+
+func parseASL(t *testing.T, definition string) *aslconv.AmazonStatesLanguage {
+	t.Helper()
+	var asl aslconv.AmazonStatesLanguage
+	require.NoError(t, json.Unmarshal([]byte(definition), &asl))
+	return &asl
+}
+
+// echoHandler returns the input of the task with the resource name.
+func echoHandler(name string) exec.TaskHandler {
+	return exec.TaskHandlerFunc(func(_ context.Context, _ string, input json.RawMessage) (json.RawMessage, error) {
+		return json.Marshal(map[string]interface{}{
+			"handler": name,
+			"input":   input,
+		})
+	})
+}
+
+func TestExecuteChoice(t *testing.T) {
+	asl := loadASL(t, "../testdata/sample.asl.hcl")
+	handlers := map[string]exec.TaskHandler{
+		"arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME": exec.TaskHandlerFunc(func(_ context.Context, _ string, input json.RawMessage) (json.RawMessage, error) {
+			return input, nil
+		}),
+		"arn:aws:lambda:us-east-1:123456789012:function:OnFirstMatch":  echoHandler("first"),
+		"arn:aws:lambda:us-east-1:123456789012:function:OnSecondMatch": echoHandler("second"),
+	}
+	optFn := func(opts *exec.Options) {
+		opts.TaskHandlers = handlers
+	}
+	cases := []struct {
+		casename string
+		input    string
+		path     []string
+		output   string
+		errName  string
+	}{
+		{
+			casename: "first",
+			input:    `{"foo": 1}`,
+			path:     []string{"FirstState", "ChoiceState", "FirstMatchState", "NextState"},
+			output:   `{"handler": "first", "input": {"foo": 1}}`,
+		},
+		{
+			casename: "second",
+			input:    `{"foo": 2}`,
+			path:     []string{"FirstState", "ChoiceState", "SecondMatchState", "NextState"},
+			output:   `{"handler": "second", "input": {"foo": 2}}`,
+		},
+		{
+			casename: "default",
+			input:    `{"foo": 3}`,
+			path:     []string{"FirstState", "ChoiceState", "DefaultState"},
+			errName:  "DefaultStateError",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			execution, err := exec.Execute(context.Background(), asl, json.RawMessage(c.input), optFn)
+			require.Equal(t, c.path, execution.Path())
+			if c.errName != "" {
+				var stateErr *exec.Error
+				require.ErrorAs(t, err, &stateErr)
+				require.Equal(t, c.errName, stateErr.Name)
+				require.Equal(t, exec.StatusFailed, execution.Status)
+				require.Equal(t, "No Matches!", execution.Cause)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, exec.StatusSucceeded, execution.Status)
+			require.JSONEq(t, c.output, string(execution.Output))
+		})
+	}
+}
+
+func TestExecuteInputOutputProcessing(t *testing.T) {
+	asl := parseASL(t, `{
+  "StartAt": "Task",
+  "States": {
+    "Task": {
+      "Type": "Task",
+      "Resource": "arn:aws:states:::lambda:invoke",
+      "InputPath": "$.order",
+      "Parameters": {
+        "id.$": "$.id",
+        "execution.$": "$$.Execution.Name",
+        "static": {"nested.$": "$.items[1]"}
+      },
+      "ResultSelector": {
+        "total.$": "$.total"
+      },
+      "ResultPath": "$.result.payment",
+      "OutputPath": "$.result",
+      "Next": "Pass"
+    },
+    "Pass": {
+      "Type": "Pass",
+      "Result": {"status": "done"},
+      "ResultPath": "$.pass",
+      "End": true
+    }
+  }
+}`)
+	var invoked json.RawMessage
+	execution, err := exec.Execute(context.Background(), asl, json.RawMessage(`{"order": {"id": "o-1", "items": ["a", "b"]}}`), func(opts *exec.Options) {
+		opts.Name = "test"
+		opts.TaskHandlers = map[string]exec.TaskHandler{
+			"arn:aws:states:::lambda:invoke": exec.TaskHandlerFunc(func(_ context.Context, _ string, input json.RawMessage) (json.RawMessage, error) {
+				invoked = input
+				return json.RawMessage(`{"total": 100, "ignored": true}`), nil
+			}),
+		}
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id": "o-1", "execution": "test", "static": {"nested": "b"}}`, string(invoked))
+	require.JSONEq(t, `{"payment": {"total": 100}, "pass": {"status": "done"}}`, string(execution.Output))
+}
+
+func TestExecuteIntrinsicFunctions(t *testing.T) {
+	asl := parseASL(t, `{
+  "StartAt": "Pass",
+  "States": {
+    "Pass": {
+      "Type": "Pass",
+      "Parameters": {
+        "greeting.$": "States.Format('Hello, {}!', $.name)",
+        "count.$": "States.MathAdd(States.ArrayLength($.items), 1)",
+        "unique.$": "States.ArrayUnique($.items)",
+        "execution.$": "States.Format('{}/{}', $$.StateMachine.Name, $$.Execution.Name)",
+        "random.$": "States.MathRandom(0, 10)",
+        "uuid.$": "States.UUID()"
+      },
+      "End": true
+    }
+  }
+}`)
+	execution, err := exec.Execute(context.Background(), asl, json.RawMessage(`{"name": "Alice", "items": [1, 2, 2, 3]}`), func(opts *exec.Options) {
+		opts.Name = "test"
+		opts.Random = func() float64 { return 0.25 }
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+  "greeting": "Hello, Alice!",
+  "count": 5,
+  "unique": [1, 2, 3],
+  "execution": "local/test",
+  "random": 2,
+  "uuid": "40404040-4040-4040-8040-404040404040"
+}`, string(execution.Output))
+}
+
+func TestExecuteWait(t *testing.T) {
+	asl := parseASL(t, `{
+  "StartAt": "WaitSeconds",
+  "States": {
+    "WaitSeconds": {"Type": "Wait", "Seconds": 10, "Next": "WaitSecondsPath"},
+    "WaitSecondsPath": {"Type": "Wait", "SecondsPath": "$.seconds", "Next": "WaitTimestamp"},
+    "WaitTimestamp": {"Type": "Wait", "TimestampPath": "$.until", "Next": "Done"},
+    "Done": {"Type": "Succeed"}
+  }
+}`)
+	clock := exec.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	execution, err := exec.Execute(context.Background(), asl, json.RawMessage(`{"seconds": 20, "until": "2024-01-01T00:01:00Z"}`), func(opts *exec.Options) {
+		opts.Clock = clock
+	})
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second}, clock.Sleeps())
+	require.Equal(t, time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), clock.Now())
+	require.Equal(t, time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), execution.History[len(execution.History)-1].Timestamp)
+}
+
+func TestExecuteParallelAndMap(t *testing.T) {
+	asl := parseASL(t, `{
+  "StartAt": "Parallel",
+  "States": {
+    "Parallel": {
+      "Type": "Parallel",
+      "Branches": [
+        {"StartAt": "A", "States": {"A": {"Type": "Pass", "Result": "a", "End": true}}},
+        {"StartAt": "B", "States": {"B": {"Type": "Task", "Resource": "double", "InputPath": "$.items[0]", "End": true}}}
+      ],
+      "ResultPath": "$.parallel",
+      "Next": "Map"
+    },
+    "Map": {
+      "Type": "Map",
+      "ItemsPath": "$.items",
+      "MaxConcurrency": 1,
+      "ItemSelector": {
+        "index.$": "$$.Map.Item.Index",
+        "value.$": "$$.Map.Item.Value"
+      },
+      "ItemProcessor": {
+        "StartAt": "Double",
+        "States": {"Double": {"Type": "Task", "Resource": "double", "InputPath": "$.value", "End": true}}
+      },
+      "ResultPath": "$.doubled",
+      "End": true
+    }
+  }
+}`)
+	execution, err := exec.Execute(context.Background(), asl, json.RawMessage(`{"items": [1, 2, 3]}`), func(opts *exec.Options) {
+		opts.TaskHandlers = map[string]exec.TaskHandler{
+			"double": exec.TaskHandlerFunc(func(_ context.Context, _ string, input json.RawMessage) (json.RawMessage, error) {
+				var n int
+				if err := json.Unmarshal(input, &n); err != nil {
+					return nil, err
+				}
+				return json.Marshal(n * 2)
+			}),
+		}
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"items": [1, 2, 3], "parallel": ["a", 2], "doubled": [2, 4, 6]}`, string(execution.Output))
+	require.Equal(t, []string{"Parallel", "Map"}, execution.Path())
+}
+
+func TestExecuteErrors(t *testing.T) {
+	cases := []struct {
+		casename   string
+		definition string
+		handler    exec.TaskHandler
+		errName    string
+	}{
+		{
+			casename:   "task_failed",
+			definition: `{"StartAt": "Task", "States": {"Task": {"Type": "Task", "Resource": "task", "End": true}}}`,
+			handler: exec.TaskHandlerFunc(func(_ context.Context, _ string, _ json.RawMessage) (json.RawMessage, error) {
+				return nil, errors.New("boom")
+			}),
+			errName: exec.ErrorTaskFailed,
+		},
+		{
+			casename:   "custom_error",
+			definition: `{"StartAt": "Task", "States": {"Task": {"Type": "Task", "Resource": "task", "End": true}}}`,
+			handler: exec.TaskHandlerFunc(func(_ context.Context, _ string, _ json.RawMessage) (json.RawMessage, error) {
+				return nil, &exec.Error{Name: "CustomError", Cause: "boom"}
+			}),
+			errName: "CustomError",
+		},
+		{
+			casename:   "no_handler",
+			definition: `{"StartAt": "Task", "States": {"Task": {"Type": "Task", "Resource": "unknown", "End": true}}}`,
+			errName:    exec.ErrorRuntime,
+		},
+		{
+			casename:   "no_choice_matched",
+			definition: `{"StartAt": "Choice", "States": {"Choice": {"Type": "Choice", "Choices": [{"Variable": "$.foo", "StringEquals": "bar", "Next": "Done"}]}, "Done": {"Type": "Succeed"}}}`,
+			errName:    exec.ErrorNoChoiceMatched,
+		},
+		{
+			casename:   "parameter_path_failure",
+			definition: `{"StartAt": "Pass", "States": {"Pass": {"Type": "Pass", "Parameters": {"missing.$": "$.missing"}, "End": true}}}`,
+			errName:    exec.ErrorParameterPathFailure,
+		},
+		{
+			casename:   "intrinsic_failure",
+			definition: `{"StartAt": "Pass", "States": {"Pass": {"Type": "Pass", "Parameters": {"sum.$": "States.MathAdd($.foo, 1)"}, "End": true}}}`,
+			errName:    exec.ErrorIntrinsicFailure,
+		},
+		{
+			casename:   "result_path_match_failure",
+			definition: `{"StartAt": "Pass", "States": {"Pass": {"Type": "Pass", "ResultPath": "$.foo.bar", "End": true}}}`,
+			errName:    exec.ErrorResultPathMatchFailure,
+		},
+		{
+			casename:   "branch_failed",
+			definition: `{"StartAt": "Parallel", "States": {"Parallel": {"Type": "Parallel", "End": true, "Branches": [{"StartAt": "Fail", "States": {"Fail": {"Type": "Fail", "Error": "BranchError"}}}]}}}`,
+			errName:    "BranchError",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			asl := parseASL(t, c.definition)
+			execution, err := exec.Execute(context.Background(), asl, json.RawMessage(`{"foo": "baz"}`), func(opts *exec.Options) {
+				if c.handler != nil {
+					opts.TaskHandlers = map[string]exec.TaskHandler{"task": c.handler}
+				}
+			})
+			var stateErr *exec.Error
+			require.ErrorAs(t, err, &stateErr)
+			require.Equal(t, c.errName, stateErr.Name)
+			require.Equal(t, exec.StatusFailed, execution.Status)
+			require.Equal(t, c.errName, execution.Error)
+			last := execution.History[len(execution.History)-1]
+			require.Equal(t, exec.EventStateFailed, last.Type)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mashiike/aslconv/intrinsic"
+	"github.com/mashiike/aslconv/jsonpath"
+)
+
+// selectPath returns the value of the path, that is InputPath, OutputPath, ItemsPath or the other paths. The path nil means `$`.
+func selectPath(field string, p *string, input, contextObject interface{}) (interface{}, error) {
+	if p == nil {
+		return input, nil
+	}
+	parsed, err := jsonpath.Parse(*p)
+	if err != nil {
+		return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("%s: %s", field, err)}
+	}
+	value, err := parsed.Get(input, contextObject)
+	if err != nil {
+		return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("%s: %s", field, err)}
+	}
+	return value, nil
+}
+
+// applyResultPath returns the state input that has the result at ResultPath. The path nil means `$`.
+func applyResultPath(resultPath *string, input, result interface{}) (interface{}, error) {
+	if resultPath == nil {
+		return result, nil
+	}
+	parsed, err := jsonpath.ParseReference(*resultPath)
+	if err != nil {
+		return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("ResultPath: %s", err)}
+	}
+	output, err := parsed.Set(input, result)
+	if err != nil {
+		return nil, &Error{Name: ErrorResultPathMatchFailure, Cause: err.Error()}
+	}
+	return output, nil
+}
+
+// resolvePayload returns the payload template, e.g. Parameters, that has the values of the fields ending with `.$` resolved.
+// The intrinsic functions such as States.MathRandom and States.UUID use Random of the options.
+func (e *executor) resolvePayload(field string, template, input, contextObject interface{}) (interface{}, error) {
+	evaluate := intrinsic.Options{Random: e.opts.Random}.Evaluate
+	resolved, err := jsonpath.ResolvePayload(template, input, contextObject, evaluate)
+	if err != nil {
+		var payloadErr *jsonpath.PayloadError
+		if errors.As(err, &payloadErr) && jsonpath.IsIntrinsic(payloadErr.Value) {
+			return nil, &Error{Name: ErrorIntrinsicFailure, Cause: fmt.Sprintf("%s: %s", field, err)}
+		}
+		return nil, &Error{Name: ErrorParameterPathFailure, Cause: fmt.Sprintf("%s: %s", field, err)}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,92 @@
+package exec
+
+import (
+	"math"
+	"time"
+
+	"github.com/mashiike/aslconv"
+)
+
+// The defaults of the retrier fields.
+// https://states-language.net/spec.html#retrying-after-error
+const (
+	defaultIntervalSeconds = 1
+	defaultMaxAttempts     = 3
+	defaultBackoffRate     = 2.0
+)
+
+// matches reports whether the error matches ErrorEquals.
+// States.ALL matches any error except States.Runtime, and States.TaskFailed matches any error except States.Timeout,
+// as the errors of the runtime are not retriable nor catchable.
+func (err *Error) matches(errorEquals []string) bool {
+	if err.Name == ErrorRuntime {
+		return false
+	}
+	for _, name := range errorEquals {
+		switch name {
+		case err.Name, ErrorAll:
+			return true
+		case ErrorTaskFailed:
+			if err.Name != ErrorTimeout {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryDelay returns the delay before the next attempt, by the first retrier that matches the error.
+// retryCounts are the numbers of the retries by each retrier, that is incremented when retried.
+// ok is false when no retrier matches or the attempts of the matched retrier are exhausted.
+func (e *executor) retryDelay(retriers aslconv.Retriers, retryCounts []int, err *Error) (delay time.Duration, ok bool) {
+	for i, retrier := range retriers {
+		if !err.matches(retrier.ErrorEquals) {
+			continue
+		}
+		maxAttempts := int64(defaultMaxAttempts)
+		if retrier.MaxAttempts != nil {
+			maxAttempts = *retrier.MaxAttempts
+		}
+		if int64(retryCounts[i]) >= maxAttempts {
+			return 0, false
+		}
+		interval := float64(defaultIntervalSeconds)
+		if retrier.IntervalSeconds != nil {
+			interval = float64(*retrier.IntervalSeconds)
+		}
+		backoffRate := defaultBackoffRate
+		if retrier.BackoffRate != nil {
+			backoffRate = *retrier.BackoffRate
+		}
+		seconds := interval * math.Pow(backoffRate, float64(retryCounts[i]))
+		if retrier.MaxDelaySeconds != nil && seconds > float64(*retrier.MaxDelaySeconds) {
+			seconds = float64(*retrier.MaxDelaySeconds)
+		}
+		// the full jitter randomizes the delay between 0 and the computed delay
+		if retrier.JitterStrategy != nil && *retrier.JitterStrategy == "FULL" {
+			seconds *= e.opts.Random()
+		}
+		retryCounts[i]++
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// catch returns the output and the next state of the first catcher that matches the error.
+// The error output, that has Error and Cause, is placed into the raw input of the state by ResultPath of the catcher.
+func (e *executor) catch(state *aslconv.State, input interface{}, err *Error) (interface{}, string, bool, error) {
+	for _, catcher := range state.Catch {
+		if !err.matches(catcher.ErrorEquals) {
+			continue
+		}
+		output, resultErr := applyResultPath(catcher.ResultPath, input, map[string]interface{}{
+			"Error": err.Name,
+			"Cause": err.Cause,
+		})
+		if resultErr != nil {
+			return nil, "", false, resultErr
+		}
+		return output, catcher.Next, true, nil
+	}
+	return nil, "", false, nil
+}
@@ -0,0 +1,292 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mashiike/aslconv/exec"
+	"github.com/stretchr/testify/require"
+)
+
+// failingHandler returns the errors in order, and then the input of the task.
+// The inputs of all invocations are appended to inputs.
+func failingHandler(inputs *[]string, errs ...error) exec.TaskHandler {
+	return exec.TaskHandlerFunc(func(_ context.Context, _ string, input json.RawMessage) (json.RawMessage, error) {
+		*inputs = append(*inputs, string(input))
+		if len(*inputs) <= len(errs) {
+			return nil, errs[len(*inputs)-1]
+		}
+		return input, nil
+	})
+}
+
+func retryASL(retry, catch string) string {
+	return fmt.Sprintf(`{
+  "StartAt": "Task",
+  "States": {
+    "Task": {
+      "Type": "Task",
+      "Resource": "arn:aws:lambda:us-east-1:123456789012:function:Task",
+      "Parameters": {
+        "retry_count.$": "$$.State.RetryCount"
+      },
+      "Retry": %s,
+      "Catch": %s,
+      "End": true
+    },
+    "Handled": {
+      "Type": "Pass",
+      "End": true
+    }
+  }
+}`, retry, catch)
+}
+
+func TestExecuteRetry(t *testing.T) {
+	asl := parseASL(t, retryASL(`[
+  {"ErrorEquals": ["Custom.Error"], "IntervalSeconds": 2, "BackoffRate": 3, "MaxAttempts": 3, "MaxDelaySeconds": 10}
+]`, `[]`))
+	var inputs []string
+	clock := exec.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	errCustom := &exec.Error{Name: "Custom.Error", Cause: "retry me"}
+	execution, err := exec.Execute(context.Background(), asl, json.RawMessage(`{}`), func(opts *exec.Options) {
+		opts.Clock = clock
+		opts.TaskHandlers = map[string]exec.TaskHandler{
+			"arn:aws:lambda:us-east-1:123456789012:function:Task": failingHandler(&inputs, errCustom, errCustom, errCustom),
+		}
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"retry_count": 3}`, string(execution.Output))
+	require.Equal(t, []time.Duration{2 * time.Second, 6 * time.Second, 10 * time.Second}, clock.Sleeps())
+	require.Equal(t, []string{`{"retry_count":0}`, `{"retry_count":1}`, `{"retry_count":2}`, `{"retry_count":3}`}, inputs)
+
+	type attempt struct {
+		Type    exec.EventType
+		Attempt int
+		Delay   time.Duration
+	}
+	var attempts []attempt
+	for _, event := range execution.History {
+		attempts = append(attempts, attempt{Type: event.Type, Attempt: event.Attempt, Delay: event.Delay})
+	}
+	require.Equal(t, []attempt{
+		{Type: exec.EventStateEntered},
+		{Type: exec.EventStateRetried, Attempt: 1, Delay: 2 * time.Second},
+		{Type: exec.EventStateRetried, Attempt: 2, Delay: 6 * time.Second},
+		{Type: exec.EventStateRetried, Attempt: 3, Delay: 10 * time.Second},
+		{Type: exec.EventStateExited},
+	}, attempts)
+	require.Equal(t, "Custom.Error", execution.History[1].Error)
+	require.Equal(t, "retry me", execution.History[1].Cause)
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 18, 0, time.UTC), execution.History[4].Timestamp)
+}
+
+func TestExecuteRetryAndCatch(t *testing.T) {
+	errCustom := &exec.Error{Name: "Custom.Error", Cause: "custom"}
+	errTimeout := &exec.Error{Name: exec.ErrorTimeout, Cause: "timeout"}
+	errRuntime := &exec.Error{Name: exec.ErrorRuntime, Cause: "runtime"}
+	cases := []struct {
+		casename string
+		retry    string
+		catch    string
+		errs     []error
+		random   func() float64
+		sleeps   []time.Duration
+		path     []string
+		output   string
+		errName  string
+	}{
+		{
+			casename: "default_retrier",
+			retry:    `[{"ErrorEquals": ["States.ALL"]}]`,
+			catch:    `[]`,
+			errs:     []error{errCustom, errCustom, errCustom, errCustom},
+			sleeps:   []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second},
+			path:     []string{"Task"},
+			errName:  "Custom.Error",
+		},
+		{
+			casename: "full_jitter",
+			retry:    `[{"ErrorEquals": ["States.ALL"], "IntervalSeconds": 4, "MaxAttempts": 2, "JitterStrategy": "FULL"}]`,
+			catch:    `[]`,
+			errs:     []error{errCustom, errCustom},
+			random:   func() float64 { return 0.25 },
+			sleeps:   []time.Duration{1 * time.Second, 2 * time.Second},
+			path:     []string{"Task"},
+			output:   `{"retry_count": 2}`,
+		},
+		{
+			casename: "zero_max_attempts",
+			retry:    `[{"ErrorEquals": ["Custom.Error"], "MaxAttempts": 0}, {"ErrorEquals": ["States.ALL"]}]`,
+			catch:    `[]`,
+			errs:     []error{errCustom},
+			path:     []string{"Task"},
+			errName:  "Custom.Error",
+		},
+		{
+			casename: "task_failed_does_not_match_timeout",
+			retry:    `[{"ErrorEquals": ["States.TaskFailed"]}]`,
+			catch:    `[]`,
+			errs:     []error{errTimeout},
+			path:     []string{"Task"},
+			errName:  exec.ErrorTimeout,
+		},
+		{
+			casename: "task_failed_matches_handler_error",
+			retry:    `[{"ErrorEquals": ["States.TaskFailed"]}]`,
+			catch:    `[]`,
+			errs:     []error{errors.New("unexpected")},
+			sleeps:   []time.Duration{1 * time.Second},
+			path:     []string{"Task"},
+			output:   `{"retry_count": 1}`,
+		},
+		{
+			casename: "catch_after_retries",
+			retry:    `[{"ErrorEquals": ["Custom.Error"], "MaxAttempts": 1}]`,
+			catch:    `[{"ErrorEquals": ["Other.Error"], "Next": "Unknown"}, {"ErrorEquals": ["States.ALL"], "Next": "Handled", "ResultPath": "$.error"}]`,
+			errs:     []error{errCustom, errCustom},
+			sleeps:   []time.Duration{1 * time.Second},
+			path:     []string{"Task", "Handled"},
+			output:   `{"input": true, "error": {"Error": "Custom.Error", "Cause": "custom"}}`,
+		},
+		{
+			casename: "catch_default_result_path",
+			retry:    `[]`,
+			catch:    `[{"ErrorEquals": ["Custom.Error"], "Next": "Handled"}]`,
+			errs:     []error{errCustom},
+			path:     []string{"Task", "Handled"},
+			output:   `{"Error": "Custom.Error", "Cause": "custom"}`,
+		},
+		{
+			casename: "runtime_is_not_retried_nor_caught",
+			retry:    `[{"ErrorEquals": ["States.ALL"]}]`,
+			catch:    `[{"ErrorEquals": ["States.ALL"], "Next": "Handled"}]`,
+			errs:     []error{errRuntime},
+			path:     []string{"Task"},
+			errName:  exec.ErrorRuntime,
+		},
+		{
+			casename: "catch_result_path_failure",
+			retry:    `[]`,
+			catch:    `[{"ErrorEquals": ["States.ALL"], "Next": "Handled", "ResultPath": "$.input.error"}]`,
+			errs:     []error{errCustom},
+			path:     []string{"Task"},
+			errName:  exec.ErrorResultPathMatchFailure,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			asl := parseASL(t, retryASL(c.retry, c.catch))
+			var inputs []string
+			clock := exec.NewFakeClock(time.Now())
+			execution, err := exec.Execute(context.Background(), asl, json.RawMessage(`{"input": true}`), func(opts *exec.Options) {
+				opts.Clock = clock
+				if c.random != nil {
+					opts.Random = c.random
+				}
+				opts.TaskHandlers = map[string]exec.TaskHandler{
+					"arn:aws:lambda:us-east-1:123456789012:function:Task": failingHandler(&inputs, c.errs...),
+				}
+			})
+			require.Equal(t, c.path, execution.Path())
+			require.Equal(t, c.sleeps, clock.Sleeps())
+			if c.errName != "" {
+				var stateErr *exec.Error
+				require.ErrorAs(t, err, &stateErr)
+				require.Equal(t, c.errName, stateErr.Name)
+				require.Equal(t, exec.StatusFailed, execution.Status)
+				return
+			}
+			require.NoError(t, err)
+			require.JSONEq(t, c.output, string(execution.Output))
+		})
+	}
+}
+
+func TestExecuteRetryInBranches(t *testing.T) {
+	asl := parseASL(t, `{
+  "StartAt": "Parallel",
+  "States": {
+    "Parallel": {
+      "Type": "Parallel",
+      "Branches": [
+        {
+          "StartAt": "Task",
+          "States": {
+            "Task": {
+              "Type": "Task",
+              "Resource": "arn:aws:lambda:us-east-1:123456789012:function:Task",
+              "Retry": [{"ErrorEquals": ["Custom.Error"]}],
+              "End": true
+            }
+          }
+        }
+      ],
+      "ResultPath": "$.parallel",
+      "Next": "Map"
+    },
+    "Map": {
+      "Type": "Map",
+      "ItemsPath": "$.items",
+      "ItemProcessor": {
+        "StartAt": "Item",
+        "States": {
+          "Item": {
+            "Type": "Task",
+            "Resource": "arn:aws:lambda:us-east-1:123456789012:function:Item",
+            "Catch": [{"ErrorEquals": ["States.ALL"], "Next": "Handled"}],
+            "End": true
+          },
+          "Handled": {
+            "Type": "Pass",
+            "End": true
+          }
+        }
+      },
+      "End": true
+    }
+  }
+}`)
+	var inputs []string
+	errCustom := &exec.Error{Name: "Custom.Error", Cause: "custom"}
+	execution, err := exec.Execute(context.Background(), asl, json.RawMessage(`{"items": [1]}`), func(opts *exec.Options) {
+		opts.Clock = exec.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		opts.TaskHandlers = map[string]exec.TaskHandler{
+			"arn:aws:lambda:us-east-1:123456789012:function:Task": failingHandler(&inputs, errCustom),
+			"arn:aws:lambda:us-east-1:123456789012:function:Item": exec.TaskHandlerFunc(func(_ context.Context, _ string, _ json.RawMessage) (json.RawMessage, error) {
+				return nil, errCustom
+			}),
+		}
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Parallel", "Map"}, execution.Path())
+
+	type event struct {
+		Type   exec.EventType
+		State  string
+		Parent string
+		Index  int
+	}
+	var events []event
+	for _, e := range execution.History {
+		events = append(events, event{Type: e.Type, State: e.State, Parent: e.Parent, Index: e.Index})
+	}
+	require.Equal(t, []event{
+		{Type: exec.EventStateEntered, State: "Parallel"},
+		{Type: exec.EventStateEntered, State: "Task", Parent: "Parallel"},
+		{Type: exec.EventStateRetried, State: "Task", Parent: "Parallel"},
+		{Type: exec.EventStateExited, State: "Task", Parent: "Parallel"},
+		{Type: exec.EventStateExited, State: "Parallel"},
+		{Type: exec.EventStateEntered, State: "Map"},
+		{Type: exec.EventStateEntered, State: "Item", Parent: "Map"},
+		{Type: exec.EventStateCaught, State: "Item", Parent: "Map"},
+		{Type: exec.EventStateExited, State: "Item", Parent: "Map"},
+		{Type: exec.EventStateEntered, State: "Handled", Parent: "Map"},
+		{Type: exec.EventStateExited, State: "Handled", Parent: "Map"},
+		{Type: exec.EventStateExited, State: "Map"},
+	}, events)
+}
@@ -0,0 +1,369 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mashiike/aslconv"
+)
+
+// runState runs the state, and returns the output and the name of the next state, that is empty at the end.
+// The failed attempts are retried by Retry of the state, and then the error is routed to the next state by Catch.
+func (e *executor) runState(ctx context.Context, state *aslconv.State, queryLanguage string, input interface{}) (interface{}, string, error) {
+	if state.QueryLanguage != nil {
+		queryLanguage = *state.QueryLanguage
+	}
+	if queryLanguage != "JSONPath" {
+		return nil, "", &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("the query language %s is not supported", queryLanguage)}
+	}
+	enteredTime := e.opts.Clock.Now()
+	retryCounts := make([]int, len(state.Retry))
+	for attempt := 1; ; attempt++ {
+		output, next, err := e.attemptState(ctx, state, input, e.stateContext(state, enteredTime, attempt-1))
+		var stateErr *Error
+		if err == nil || !errors.As(err, &stateErr) {
+			return output, next, err
+		}
+		delay, ok := e.retryDelay(state.Retry, retryCounts, stateErr)
+		if !ok {
+			output, next, caught, catchErr := e.catch(state, input, stateErr)
+			if catchErr != nil {
+				return nil, "", catchErr
+			}
+			if !caught {
+				return nil, "", err
+			}
+			e.addEvent(&Event{
+				Type:      EventStateCaught,
+				State:     state.Name,
+				Timestamp: e.opts.Clock.Now(),
+				Error:     stateErr.Name,
+				Cause:     stateErr.Cause,
+				Attempt:   attempt,
+			})
+			return output, next, nil
+		}
+		e.addEvent(&Event{
+			Type:      EventStateRetried,
+			State:     state.Name,
+			Timestamp: e.opts.Clock.Now(),
+			Error:     stateErr.Name,
+			Cause:     stateErr.Cause,
+			Attempt:   attempt,
+			Delay:     delay,
+		})
+		if err := e.opts.Clock.Sleep(ctx, delay); err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// attemptState runs the state once, and returns the output and the name of the next state.
+func (e *executor) attemptState(ctx context.Context, state *aslconv.State, input interface{}, contextObject map[string]interface{}) (interface{}, string, error) {
+	next := ""
+	if state.Next != nil {
+		next = *state.Next
+	}
+	effective, err := selectPath("InputPath", state.InputPath, input, contextObject)
+	if err != nil {
+		return nil, "", err
+	}
+	var output interface{}
+	switch state.Type {
+	case "Pass":
+		output, err = e.runPass(state, input, effective, contextObject)
+	case "Task":
+		output, err = e.runTask(ctx, state, input, effective, contextObject)
+	case "Choice":
+		next, err = e.runChoice(state, effective, contextObject)
+		output = effective
+	case "Wait":
+		err = e.runWait(ctx, state, effective, contextObject)
+		output = effective
+	case "Succeed":
+		output, next = effective, ""
+	case "Fail":
+		failure := &Error{}
+		if state.Error != nil {
+			failure.Name = *state.Error
+		}
+		if state.Cause != nil {
+			failure.Cause = *state.Cause
+		}
+		return nil, "", failure
+	case "Parallel":
+		output, err = e.runParallel(ctx, state, input, effective, contextObject)
+	case "Map":
+		output, err = e.runMap(ctx, state, input, effective, contextObject)
+	default:
+		return nil, "", &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("the state type %s is not supported", state.Type)}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	output, err = selectPath("OutputPath", state.OutputPath, output, contextObject)
+	if err != nil {
+		return nil, "", err
+	}
+	return output, next, nil
+}
+
+// payload returns the field, such as Parameters, with the paths resolved. ok is false if the field is not set.
+func (e *executor) payload(field string, raw aslconv.RawMessage, input, contextObject interface{}) (value interface{}, ok bool, err error) {
+	if len(raw) == 0 {
+		return nil, false, nil
+	}
+	template, err := decodeJSON(raw)
+	if err != nil {
+		return nil, false, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("%s: %s", field, err)}
+	}
+	value, err = e.resolvePayload(field, template, input, contextObject)
+	return value, true, err
+}
+
+// parametersOf returns the Parameters of the state resolved, or the effective input if Parameters is not set.
+func (e *executor) parametersOf(state *aslconv.State, effective, contextObject interface{}) (interface{}, error) {
+	parameters, ok, err := e.payload("Parameters", state.Parameters, effective, contextObject)
+	if err != nil || !ok {
+		return effective, err
+	}
+	return parameters, nil
+}
+
+// processResult applies ResultSelector and ResultPath to the result of the state.
+func (e *executor) processResult(state *aslconv.State, input, result, contextObject interface{}) (interface{}, error) {
+	selected, ok, err := e.payload("ResultSelector", state.ResultSelector, result, contextObject)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		result = selected
+	}
+	return applyResultPath(state.ResultPath, input, result)
+}
+
+func (e *executor) runPass(state *aslconv.State, input, effective, contextObject interface{}) (interface{}, error) {
+	result, err := e.parametersOf(state, effective, contextObject)
+	if err != nil {
+		return nil, err
+	}
+	if len(state.Result) > 0 {
+		if result, err = decodeJSON(state.Result); err != nil {
+			return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("Result: %s", err)}
+		}
+	}
+	return applyResultPath(state.ResultPath, input, result)
+}
+
+func (e *executor) runTask(ctx context.Context, state *aslconv.State, input, effective, contextObject interface{}) (interface{}, error) {
+	parameters, err := e.parametersOf(state, effective, contextObject)
+	if err != nil {
+		return nil, err
+	}
+	if state.Resource == nil {
+		return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("the task state %s has no Resource", state.Name)}
+	}
+	handler, ok := e.opts.StateTaskHandlers[state.Name]
+	if !ok {
+		handler, ok = e.opts.TaskHandlers[*state.Resource]
+	}
+	if !ok {
+		return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("no task handler for the resource %s", *state.Resource)}
+	}
+	output, err := handler.Invoke(ctx, *state.Resource, encodeJSON(parameters))
+	if err != nil {
+		return nil, taskError(err)
+	}
+	if len(output) == 0 {
+		output = json.RawMessage(`null`)
+	}
+	result, err := decodeJSON(output)
+	if err != nil {
+		return nil, &Error{Name: ErrorTaskFailed, Cause: fmt.Sprintf("the output of the task is not JSON: %s", err)}
+	}
+	return e.processResult(state, input, result, contextObject)
+}
+
+func (e *executor) runChoice(state *aslconv.State, effective, contextObject interface{}) (string, error) {
+	for _, rule := range state.Choices {
+		matched, err := evaluateChoiceRule(rule, effective, contextObject)
+		if err != nil {
+			return "", err
+		}
+		if matched && rule.Next != nil {
+			return *rule.Next, nil
+		}
+	}
+	if state.Default != nil {
+		return *state.Default, nil
+	}
+	return "", &Error{Name: ErrorNoChoiceMatched, Cause: fmt.Sprintf("no choice rule matched in the state %s", state.Name)}
+}
+
+func (e *executor) runWait(ctx context.Context, state *aslconv.State, effective, contextObject interface{}) error {
+	var d time.Duration
+	switch {
+	case state.Seconds != nil:
+		if state.Seconds.IsExpression() {
+			return &Error{Name: ErrorRuntime, Cause: "Seconds: JSONata expressions are not supported"}
+		}
+		d = time.Duration(state.Seconds.Value) * time.Second
+	case state.SecondsPath != nil:
+		value, err := selectPath("SecondsPath", state.SecondsPath, effective, contextObject)
+		if err != nil {
+			return err
+		}
+		seconds, ok := value.(json.Number)
+		if !ok {
+			return &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("SecondsPath: the value of %s is not a number", *state.SecondsPath)}
+		}
+		n, err := seconds.Int64()
+		if err != nil || n < 0 {
+			return &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("SecondsPath: the value of %s is not a non-negative integer", *state.SecondsPath)}
+		}
+		d = time.Duration(n) * time.Second
+	case state.Timestamp != nil || state.TimestampPath != nil:
+		field, timestamp := "Timestamp", ""
+		if state.Timestamp != nil {
+			timestamp = *state.Timestamp
+		} else {
+			field = "TimestampPath"
+			value, err := selectPath(field, state.TimestampPath, effective, contextObject)
+			if err != nil {
+				return err
+			}
+			s, ok := value.(string)
+			if !ok {
+				return &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("TimestampPath: the value of %s is not a string", *state.TimestampPath)}
+			}
+			timestamp = s
+		}
+		t, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("%s: %s", field, err)}
+		}
+		d = t.Sub(e.opts.Clock.Now())
+	}
+	return e.opts.Clock.Sleep(ctx, d)
+}
+
+// runBranches runs the state machines concurrently, at most maxConcurrency at a time if it is positive.
+// The error of the first branch in order is returned, if any branch fails.
+func (e *executor) runBranches(ctx context.Context, n int, maxConcurrency int, run func(ctx context.Context, i int) (interface{}, error)) ([]interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]interface{}, n)
+	errs := make([]error, n)
+	if maxConcurrency <= 0 || maxConcurrency > n {
+		maxConcurrency = n
+	}
+	semaphore := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			results[i], errs[i] = run(ctx, i)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+	var canceled error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var stateErr *Error
+		if errors.As(err, &stateErr) {
+			return nil, err
+		}
+		if canceled == nil {
+			canceled = err
+		}
+	}
+	if canceled != nil {
+		return nil, canceled
+	}
+	return results, nil
+}
+
+func (e *executor) runParallel(ctx context.Context, state *aslconv.State, input, effective, contextObject interface{}) (interface{}, error) {
+	parameters, err := e.parametersOf(state, effective, contextObject)
+	if err != nil {
+		return nil, err
+	}
+	results, err := e.runBranches(ctx, len(state.Branches), 0, func(ctx context.Context, i int) (interface{}, error) {
+		return e.child(e.contextObject, state.Name, i).run(ctx, state.Branches[i], parameters)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e.processResult(state, input, results, contextObject)
+}
+
+func (e *executor) runMap(ctx context.Context, state *aslconv.State, input, effective interface{}, contextObject map[string]interface{}) (interface{}, error) {
+	processor := state.ItemProcessor
+	if processor == nil {
+		processor = state.Iterator
+	}
+	if processor == nil {
+		return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("the map state %s has no ItemProcessor", state.Name)}
+	}
+	if state.ItemReader != nil || state.ItemBatcher != nil || state.ResultWriter != nil {
+		return nil, &Error{Name: ErrorRuntime, Cause: "ItemReader, ItemBatcher and ResultWriter of Distributed Map are not supported"}
+	}
+	value, err := selectPath("ItemsPath", state.ItemsPath, effective, contextObject)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, &Error{Name: ErrorRuntime, Cause: fmt.Sprintf("the items of the map state %s is not an array", state.Name)}
+	}
+	// ItemSelector is the new name of Parameters in Map state
+	selector, selectorField := state.ItemSelector, "ItemSelector"
+	if len(selector) == 0 {
+		selector, selectorField = state.Parameters, "Parameters"
+	}
+	maxConcurrency := 0
+	if state.MaxConcurrency.IsExpression() {
+		return nil, &Error{Name: ErrorRuntime, Cause: "MaxConcurrency: JSONata expressions are not supported"}
+	}
+	if state.MaxConcurrency != nil {
+		maxConcurrency = int(state.MaxConcurrency.Value)
+	}
+	results, err := e.runBranches(ctx, len(items), maxConcurrency, func(ctx context.Context, i int) (interface{}, error) {
+		itemContext := make(map[string]interface{}, len(contextObject)+1)
+		for key, value := range contextObject {
+			itemContext[key] = value
+		}
+		itemContext["Map"] = map[string]interface{}{
+			"Item": map[string]interface{}{
+				"Index": json.Number(fmt.Sprint(i)),
+				"Value": items[i],
+			},
+		}
+		itemInput, ok, err := e.payload(selectorField, selector, effective, itemContext)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			itemInput = items[i]
+		}
+		return e.child(e.contextObject, state.Name, i).run(ctx, processor, itemInput)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e.processResult(state, input, results, contextObject)
+}
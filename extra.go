@@ -0,0 +1,148 @@
+package aslconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Extra fields are the fields that aslconv does not know, e.g. fields added by a newer spec.
+// They are kept as they are, so that the conversion never loses data.
+// In HCL, they are written as the `extra` attribute:
+//
+//	extra = {
+//	  Annotations = {
+//	    owner = "team-a"
+//	  }
+//	}
+
+// knownJSONNames returns the JSON field names of the struct type of v.
+func knownJSONNames(v interface{}) []string {
+	rt := reflect.TypeOf(v)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	names := make([]string, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func isKnownJSONName(known []string, key string) bool {
+	for _, name := range known {
+		// encoding/json matches the field names case-insensitively.
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalExtra returns the fields of the JSON object that are not the fields of the struct type of v.
+func unmarshalExtra(bs []byte, v interface{}) (map[string]RawMessage, error) {
+	var data map[string]RawMessage
+	if err := json.Unmarshal(bs, &data); err != nil {
+		return nil, err
+	}
+	known := knownJSONNames(v)
+	var extra map[string]RawMessage
+	for key, value := range data {
+		if isKnownJSONName(known, key) {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]RawMessage)
+		}
+		extra[key] = value
+	}
+	return extra, nil
+}
+
+// marshalExtra adds the extra fields to the JSON object, the known fields take precedence.
+func marshalExtra(bs []byte, extra map[string]RawMessage) ([]byte, error) {
+	if len(extra) == 0 {
+		return bs, nil
+	}
+	var data map[string]RawMessage
+	if err := json.Unmarshal(bs, &data); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		if _, ok := data[key]; ok {
+			continue
+		}
+		data[key] = value
+	}
+	return json.Marshal(data)
+}
+
+// decodeExtra decodes the `extra` attribute, that must be an object.
+func decodeExtra(expr hcl.Expression, ctx *hcl.EvalContext, known []string, extra *map[string]RawMessage) hcl.Diagnostics {
+	var raw RawMessage
+	diags := decodeExpression(expr, ctx, &raw)
+	if diags.HasErrors() || raw == nil {
+		return diags
+	}
+	var data map[string]RawMessage
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid extra fields",
+			Detail:   "The extra fields must be an object.",
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if isKnownJSONName(known, key) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid extra fields",
+				Detail:   fmt.Sprintf(`The field "%s" is a known field. Use the "%s" argument or block instead.`, key, toSnakeCase(key)),
+				Subject:  expr.Range().Ptr(),
+			})
+			delete(data, key)
+		}
+	}
+	if len(data) > 0 {
+		*extra = data
+	}
+	return diags
+}
+
+// extraCtyValue converts the extra fields to the value of the `extra` attribute.
+func extraCtyValue(extra map[string]RawMessage) (cty.Value, error) {
+	values := make(map[string]cty.Value, len(extra))
+	for key, raw := range extra {
+		value, err := raw.ctyValue()
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("%s:%w", key, err)
+		}
+		values[key] = value
+	}
+	return cty.ObjectVal(values), nil
+}
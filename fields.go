@@ -0,0 +1,229 @@
+package aslconv
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/agext/levenshtein"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// stateField describes a State field by the JSON field name and the HCL attribute or block name.
+type stateField struct {
+	json  string
+	hcl   string
+	isSet func(*State) bool
+	// aliases are other HCL names of the field, e.g. the block name of the field that is also written as an attribute
+	aliases []string
+}
+
+var stateFields = []stateField{
+	{json: "Comment", hcl: "comment", isSet: func(s *State) bool { return s.Comment != nil }},
+	{json: "QueryLanguage", hcl: "query_language", isSet: func(s *State) bool { return s.QueryLanguage != nil }},
+	{json: "Resource", hcl: "resource", isSet: func(s *State) bool { return s.Resource != nil }},
+	{json: "TimeoutSeconds", hcl: "timeout_seconds", isSet: func(s *State) bool { return s.TimeoutSeconds != nil }},
+	{json: "TimeoutSecondsPath", hcl: "timeout_seconds_path", isSet: func(s *State) bool { return s.TimeoutSecondsPath != nil }},
+	{json: "HeartbeatSeconds", hcl: "heartbeat_seconds", isSet: func(s *State) bool { return s.HeartbeatSeconds != nil }},
+	{json: "HeartbeatSecondsPath", hcl: "heartbeat_seconds_path", isSet: func(s *State) bool { return s.HeartbeatSecondsPath != nil }},
+	{json: "Credentials", hcl: "credentials", isSet: func(s *State) bool { return s.Credentials != nil }},
+	{json: "Default", hcl: "default", isSet: func(s *State) bool { return s.Default != nil }},
+	{json: "Seconds", hcl: "seconds", isSet: func(s *State) bool { return s.Seconds != nil }},
+	{json: "Timestamp", hcl: "timestamp", isSet: func(s *State) bool { return s.Timestamp != nil }},
+	{json: "SecondsPath", hcl: "seconds_path", isSet: func(s *State) bool { return s.SecondsPath != nil }},
+	{json: "TimestampPath", hcl: "timestamp_path", isSet: func(s *State) bool { return s.TimestampPath != nil }},
+	{json: "MaxConcurrency", hcl: "max_concurrency", isSet: func(s *State) bool { return s.MaxConcurrency != nil }},
+	{json: "MaxConcurrencyPath", hcl: "max_concurrency_path", isSet: func(s *State) bool { return s.MaxConcurrencyPath != nil }},
+	{json: "ToleratedFailureCount", hcl: "tolerated_failure_count", isSet: func(s *State) bool { return s.ToleratedFailureCount != nil }},
+	{json: "ToleratedFailureCountPath", hcl: "tolerated_failure_count_path", isSet: func(s *State) bool { return s.ToleratedFailureCountPath != nil }},
+	{json: "ToleratedFailurePercentage", hcl: "tolerated_failure_percentage", isSet: func(s *State) bool { return s.ToleratedFailurePercentage != nil }},
+	{json: "ToleratedFailurePercentagePath", hcl: "tolerated_failure_percentage_path", isSet: func(s *State) bool { return s.ToleratedFailurePercentagePath != nil }},
+	{json: "Label", hcl: "label", isSet: func(s *State) bool { return s.Label != nil }},
+	{json: "Next", hcl: "next", isSet: func(s *State) bool { return s.Next != nil }},
+	{json: "ItemsPath", hcl: "items_path", isSet: func(s *State) bool { return s.ItemsPath != nil }},
+	{json: "ItemSelector", hcl: "item_selector", isSet: func(s *State) bool { return s.ItemSelector != nil }},
+	{json: "Items", hcl: "items", isSet: func(s *State) bool { return s.Items != nil }},
+	{json: "InputPath", hcl: "input_path", isSet: func(s *State) bool { return s.InputPath != nil }},
+	{json: "OutputPath", hcl: "output_path", isSet: func(s *State) bool { return s.OutputPath != nil }},
+	{json: "ResultPath", hcl: "result_path", isSet: func(s *State) bool { return s.ResultPath != nil }},
+	{json: "End", hcl: "end", isSet: func(s *State) bool { return s.End != nil }},
+	{json: "Error", hcl: "error", isSet: func(s *State) bool { return s.Error != nil }},
+	{json: "Cause", hcl: "cause", isSet: func(s *State) bool { return s.Cause != nil }},
+	{json: "Retry", hcl: "retry", isSet: func(s *State) bool { return len(s.Retry) > 0 }},
+	{json: "Catch", hcl: "catch", isSet: func(s *State) bool { return len(s.Catch) > 0 }},
+	{json: "Parameters", hcl: "parameters", isSet: func(s *State) bool { return s.Parameters != nil }},
+	{json: "Arguments", hcl: "arguments", isSet: func(s *State) bool { return s.Arguments != nil }},
+	{json: "Result", hcl: "result", isSet: func(s *State) bool { return s.Result != nil }},
+	{json: "ResultSelector", hcl: "result_selector", isSet: func(s *State) bool { return s.ResultSelector != nil }},
+	{json: "Output", hcl: "output", isSet: func(s *State) bool { return s.Output != nil }},
+	{json: "Assign", hcl: "assign", isSet: func(s *State) bool { return s.Assign != nil }},
+	{json: "Choices", hcl: "choice", isSet: func(s *State) bool { return len(s.Choices) > 0 }, aliases: []string{"choices"}},
+	{json: "Branches", hcl: "branch", isSet: func(s *State) bool { return len(s.Branches) > 0 }},
+	{json: "Iterator", hcl: "iterator", isSet: func(s *State) bool { return s.Iterator != nil }},
+	{json: "ItemProcessor", hcl: "item_processor", isSet: func(s *State) bool { return s.ItemProcessor != nil }},
+	{json: "ItemReader", hcl: "item_reader", isSet: func(s *State) bool { return s.ItemReader != nil }},
+	{json: "ItemBatcher", hcl: "item_batcher", isSet: func(s *State) bool { return s.ItemBatcher != nil }},
+	{json: "ResultWriter", hcl: "result_writer", isSet: func(s *State) bool { return s.ResultWriter != nil }},
+}
+
+// https://states-language.net/spec.html#state-type-table
+var stateTypeFields = map[string][]string{
+	"Task": {
+		"Comment", "InputPath", "OutputPath", "Next", "End",
+		"Parameters", "ResultSelector", "ResultPath", "Retry", "Catch",
+		"Resource", "TimeoutSeconds", "TimeoutSecondsPath", "HeartbeatSeconds", "HeartbeatSecondsPath", "Credentials",
+		"QueryLanguage", "Arguments", "Output", "Assign",
+	},
+	"Pass": {
+		"Comment", "InputPath", "OutputPath", "Next", "End",
+		"Parameters", "ResultPath",
+		"Result",
+		"QueryLanguage", "Output", "Assign",
+	},
+	"Choice": {
+		"Comment", "InputPath", "OutputPath",
+		"Choices", "Default",
+		"QueryLanguage", "Output", "Assign",
+	},
+	"Wait": {
+		"Comment", "InputPath", "OutputPath", "Next", "End",
+		"Seconds", "Timestamp", "SecondsPath", "TimestampPath",
+		"QueryLanguage", "Output", "Assign",
+	},
+	"Succeed": {
+		"Comment", "InputPath", "OutputPath",
+		"QueryLanguage", "Output",
+	},
+	"Fail": {
+		"Comment",
+		"Error", "Cause",
+		"QueryLanguage",
+	},
+	"Parallel": {
+		"Comment", "InputPath", "OutputPath", "Next", "End",
+		"Parameters", "ResultSelector", "ResultPath", "Retry", "Catch",
+		"Branches",
+		"QueryLanguage", "Arguments", "Output", "Assign",
+	},
+	"Map": {
+		"Comment", "InputPath", "OutputPath", "Next", "End",
+		"Parameters", "ResultSelector", "ResultPath", "Retry", "Catch",
+		"Iterator", "ItemsPath", "MaxConcurrency",
+		"ItemProcessor", "ItemReader", "ItemSelector", "ItemBatcher", "ResultWriter",
+		"MaxConcurrencyPath", "ToleratedFailureCount", "ToleratedFailureCountPath",
+		"ToleratedFailurePercentage", "ToleratedFailurePercentagePath", "Label",
+		"QueryLanguage", "Items", "Output", "Assign",
+	},
+}
+
+func isAllowedField(stateType string, jsonName string) bool {
+	for _, name := range stateTypeFields[stateType] {
+		if name == jsonName {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHCLNames returns the HCL attribute and block names, that can be used for the state type.
+func allowedHCLNames(stateType string) []string {
+	names := make([]string, 0, len(stateTypeFields[stateType]))
+	for _, field := range stateFields {
+		if isAllowedField(stateType, field.json) {
+			names = append(names, field.hcl)
+			names = append(names, field.aliases...)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isAllowedHCLName(stateType string, hclName string) bool {
+	for _, field := range stateFields {
+		if field.hcl == hclName {
+			return isAllowedField(stateType, field.json)
+		}
+		for _, alias := range field.aliases {
+			if alias == hclName {
+				return isAllowedField(stateType, field.json)
+			}
+		}
+	}
+	return true
+}
+
+// nameSuggestion returns the most similar candidate to the given name, if it exists.
+func nameSuggestion(given string, candidates []string) (string, bool) {
+	var suggestion string
+	minDist := 3
+	for _, candidate := range candidates {
+		if dist := levenshtein.Distance(given, candidate, nil); dist < minDist {
+			suggestion = candidate
+			minDist = dist
+		}
+	}
+	return suggestion, suggestion != ""
+}
+
+// validateHCLNames checks the attributes and blocks of the state body against the state type.
+func (state *State) validateHCLNames(content *hcl.BodyContent) (hcl.Attributes, hcl.Blocks, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	allowed := allowedHCLNames(state.Type)
+	detail := func(kind string, name string) string {
+		d := fmt.Sprintf(`%s named "%s" is not expected in a %s state.`, kind, name, state.Type)
+		if suggestion, ok := nameSuggestion(name, allowed); ok {
+			return d + fmt.Sprintf(` Did you mean "%s"?`, suggestion)
+		}
+		return d
+	}
+	attrs := make(hcl.Attributes, len(content.Attributes))
+	for name, attr := range content.Attributes {
+		if !isAllowedHCLName(state.Type, name) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported argument",
+				Detail:   detail("An argument", name),
+				Subject:  attr.NameRange.Ptr(),
+			})
+			continue
+		}
+		attrs[name] = attr
+	}
+	blocks := make(hcl.Blocks, 0, len(content.Blocks))
+	for _, block := range content.Blocks {
+		if !isAllowedHCLName(state.Type, block.Type) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported block type",
+				Detail:   detail("A block", block.Type),
+				Subject:  block.TypeRange.Ptr(),
+			})
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return attrs, blocks, diags
+}
+
+// validateFields checks the fields of the state against the state type.
+func (state *State) validateFields(path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if _, ok := stateTypeFields[state.Type]; !ok {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid state type",
+			Detail:   fmt.Sprintf(`%s.Type "%s" is invalid.`, path, state.Type),
+			Subject:  state.ranges.get(""),
+		})
+	}
+	for _, field := range stateFields {
+		if field.isSet(state) && !isAllowedField(state.Type, field.json) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported field",
+				Detail:   fmt.Sprintf(`%s.%s is not allowed in a %s state.`, path, field.json, state.Type),
+				Subject:  state.ranges.get(field.hcl),
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,126 @@
+package aslconv
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/dynblock"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// stateInstance is a state generated from a state block.
+type stateInstance struct {
+	name string
+	ctx  *hcl.EvalContext
+	body hcl.Body
+}
+
+var forEachSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "for_each"},
+	},
+}
+
+// expandStateBlock returns the states generated from the state block.
+// Without for_each, the block itself is the only state. With for_each, a state is generated for each element,
+// with `each.key` and `each.value` in scope, and the name label is evaluated as a template, e.g. "Process_${each.key}".
+// In the native syntax, the label is written as "Process_$${each.key}", because HCL does not allow template sequences in labels.
+// The body of the states expands `dynamic` blocks, such as `dynamic "branch"`.
+// known is false when for_each can not be evaluated yet, e.g. it refers to locals not evaluated.
+func expandStateBlock(block *hcl.Block, ctx *hcl.EvalContext) (instances []stateInstance, known bool, diags hcl.Diagnostics) {
+	content, body, diags := block.Body.PartialContent(forEachSchema)
+	attr, ok := content.Attributes["for_each"]
+	if !ok {
+		return []stateInstance{{name: block.Labels[1], ctx: ctx, body: dynblock.Expand(body, ctx)}}, true, diags
+	}
+	forEach, valueDiags := attr.Expr.Value(ctx)
+	diags = append(diags, valueDiags...)
+	if valueDiags.HasErrors() {
+		return nil, false, diags
+	}
+	if !forEach.IsWhollyKnown() {
+		return nil, false, append(diags, &hcl.Diagnostic{
+			Severity:    hcl.DiagError,
+			Summary:     "Invalid for_each argument",
+			Detail:      "The for_each value depends on values that can not be determined.",
+			Subject:     attr.Expr.Range().Ptr(),
+			Expression:  attr.Expr,
+			EvalContext: ctx,
+		})
+	}
+	elements, elementDiags := forEachElements(forEach, attr.Expr.Range())
+	diags = append(diags, elementDiags...)
+	if elementDiags.HasErrors() {
+		return nil, true, diags
+	}
+	nameRange := block.LabelRanges[1]
+	template, templateDiags := hclsyntax.ParseTemplate([]byte(block.Labels[1]), nameRange.Filename, nameRange.Start)
+	diags = append(diags, templateDiags...)
+	if templateDiags.HasErrors() {
+		return nil, true, diags
+	}
+	for _, element := range elements {
+		child := ctx.NewChild()
+		child.Variables = map[string]cty.Value{
+			"each": cty.ObjectVal(map[string]cty.Value{
+				"key":   element.key,
+				"value": element.value,
+			}),
+		}
+		var name string
+		decodeDiags := decodeExpression(template, child, &name)
+		diags = append(diags, decodeDiags...)
+		if decodeDiags.HasErrors() {
+			continue
+		}
+		instances = append(instances, stateInstance{name: name, ctx: child, body: dynblock.Expand(body, child)})
+	}
+	return instances, true, diags
+}
+
+type eachElement struct {
+	key   cty.Value
+	value cty.Value
+}
+
+// forEachElements returns the elements of for_each, that is a map or an object keyed by strings, or a set, list or tuple of strings.
+// The key of a string element is the string itself.
+func forEachElements(forEach cty.Value, subject hcl.Range) ([]eachElement, hcl.Diagnostics) {
+	invalid := func(detail string) hcl.Diagnostics {
+		return hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid for_each argument",
+			Detail:   detail,
+			Subject:  subject.Ptr(),
+		}}
+	}
+	if forEach.IsNull() {
+		return nil, invalid("The for_each value must not be null.")
+	}
+	ty := forEach.Type()
+	var elements []eachElement
+	switch {
+	case ty.IsMapType() || ty.IsObjectType():
+		for it := forEach.ElementIterator(); it.Next(); {
+			key, value := it.Element()
+			elements = append(elements, eachElement{key: key, value: value})
+		}
+	case ty.IsSetType() || ty.IsListType() || ty.IsTupleType():
+		keys := make(map[string]bool)
+		for it := forEach.ElementIterator(); it.Next(); {
+			_, value := it.Element()
+			if value.IsNull() || value.Type() != cty.String {
+				return nil, invalid(fmt.Sprintf("The for_each elements must be strings, but %s is given.", value.Type().FriendlyName()))
+			}
+			if keys[value.AsString()] {
+				return nil, invalid(fmt.Sprintf(`The for_each elements must be unique, but "%s" is given more than once.`, value.AsString()))
+			}
+			keys[value.AsString()] = true
+			elements = append(elements, eachElement{key: value, value: value})
+		}
+	default:
+		return nil, invalid(fmt.Sprintf("The for_each value must be a map, or a set or list of strings, but %s is given.", ty.FriendlyName()))
+	}
+	return elements, nil
+}
@@ -0,0 +1,88 @@
+package aslconv_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadASLWithForEach(t *testing.T) {
+	expected := loadASL(t, "testdata/for_each.asl.json")
+	actual, err := aslconv.LoadASLWithPath("testdata/for_each.asl.hcl", func(opts *aslconv.LoadOptions) {
+		opts.Validate = true
+	})
+	require.NoError(t, err)
+	requireASLEq(t, expected, actual)
+}
+
+func TestLoadASLWithForEachErrors(t *testing.T) {
+	cases := []struct {
+		casename string
+		source   string
+		expected string
+	}{
+		{
+			casename: "not_collection",
+			source: `
+start_at = state.pass.Pass
+
+state "pass" "Pass" {
+  for_each = 3
+  end      = true
+}
+`,
+			expected: "Invalid for_each argument",
+		},
+		{
+			casename: "duplicate_elements",
+			source: `
+start_at = state.pass.Pass_a
+
+state "pass" "Pass_$${each.key}" {
+  for_each = ["a", "a"]
+  end      = true
+}
+`,
+			expected: "Invalid for_each argument",
+		},
+		{
+			casename: "label_without_template",
+			source: `
+start_at = state.pass.Pass
+
+state "pass" "Pass" {
+  for_each = ["a", "b"]
+  end      = true
+}
+`,
+			expected: `Duplicate "state" name`,
+		},
+		{
+			casename: "each_outside_for_each",
+			source: `
+start_at = state.pass.Pass
+
+state "pass" "Pass" {
+  comment = each.key
+  end     = true
+}
+`,
+			expected: "Unknown variable",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			_, err := aslconv.FormatHCL.LoadASLWithBytes([]byte(c.source), c.casename+".asl.hcl", func(opts *aslconv.LoadOptions) {
+				opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+					return nil
+				}
+			})
+			var diags hcl.Diagnostics
+			require.ErrorAs(t, err, &diags)
+			require.Contains(t, diagnosticSummaries(diags), c.expected)
+		})
+	}
+}
@@ -7,12 +7,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclwrite"
-	"github.com/samber/lo"
+	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/function/stdlib"
 )
@@ -73,15 +74,26 @@ func (f Format) Exts() []string {
 type LoadOptions struct {
 	HCLEvalContext                 *hcl.EvalContext
 	HCLDiagnosticWriterInitializer func(*hclparse.Parser) hcl.DiagnosticWriter
+	// Validate reports the diagnostics of AmazonStatesLanguage.Validate after loading, in the same way as decode diagnostics.
+	Validate bool
+	// Variables are the values of the input variables declared by `variable` blocks, that are referred as `var.name`.
+	Variables map[string]cty.Value
+	// EnvironVariables are the values of the input variables taken from the environment, e.g. by VariablesFromEnviron.
+	// They are overridden by Variables, and unlike Variables, the values for undeclared variables are ignored silently.
+	EnvironVariables map[string]cty.Value
+	// NoStandardFunctions disables StandardFunctions and FileFunctions, only the functions of HCLEvalContext and Functions are available.
+	NoStandardFunctions bool
+	// Functions are the additional functions available in the HCL files, that override the functions of the same name.
+	Functions map[string]function.Function
 }
 
 func newLoadOptions() *LoadOptions {
+	functions := ASLFunctions()
+	functions["jsonencode"] = stdlib.JSONEncodeFunc
+	functions["jsondecode"] = stdlib.JSONDecodeFunc
 	opts := &LoadOptions{
 		HCLEvalContext: &hcl.EvalContext{
-			Functions: map[string]function.Function{
-				"jsonencode": stdlib.JSONEncodeFunc,
-				"jsondecode": stdlib.JSONDecodeFunc,
-			},
+			Functions: functions,
 		},
 		HCLDiagnosticWriterInitializer: func(parser *hclparse.Parser) hcl.DiagnosticWriter {
 			return hcl.NewDiagnosticTextWriter(os.Stderr, parser.Files(), 400, true)
@@ -97,6 +109,25 @@ func (opt *LoadOptions) apply(optFns ...func(*LoadOptions)) *LoadOptions {
 	return opt
 }
 
+// variables returns the values of the input variables declared in the body, EnvironVariables are overridden by Variables.
+func (opt *LoadOptions) variables(body hcl.Body) map[string]cty.Value {
+	if len(opt.EnvironVariables) == 0 {
+		return opt.Variables
+	}
+	// the diagnostics of the declarations are reported by EvalContextWithVariables
+	declared, _ := decodeVariableBlocks(body)
+	values := make(map[string]cty.Value, len(declared)+len(opt.Variables))
+	for _, v := range declared {
+		if value, ok := opt.EnvironVariables[v.Name]; ok {
+			values[v.Name] = value
+		}
+	}
+	for name, value := range opt.Variables {
+		values[name] = value
+	}
+	return values
+}
+
 func LoadASLWithPath(path string, optFns ...func(*LoadOptions)) (*AmazonStatesLanguage, error) {
 	format, err := DetectFormat(path)
 	if err != nil {
@@ -108,45 +139,7 @@ func LoadASLWithPath(path string, optFns ...func(*LoadOptions)) (*AmazonStatesLa
 func (f Format) LoadASLWithPath(path string, optFns ...func(*LoadOptions)) (*AmazonStatesLanguage, error) {
 	opts := newLoadOptions().apply(optFns...)
 	if stats, err := os.Stat(path); f == FormatHCL && err == nil && stats.IsDir() {
-		entries, err := os.ReadDir(path)
-		if err != nil {
-			return nil, err
-		}
-		parser := hclparse.NewParser()
-		var diags hcl.Diagnostics
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
-			filename := filepath.Join(path, entry.Name())
-			if ext := filepath.Ext(filename); ext != ".json" && ext != ".hcl" {
-				continue
-			}
-			switch filepath.Ext(path) {
-			case ".json":
-				_, parseDiags := parser.ParseJSONFile(filename)
-				diags = append(diags, parseDiags...)
-			case ".hcl":
-				_, parseDiags := parser.ParseHCLFile(filename)
-				diags = append(diags, parseDiags...)
-			default:
-				diags = append(diags, &hcl.Diagnostic{
-					Severity: hcl.DiagError,
-					Summary:  "Can not open file",
-					Detail:   "unknown hcl format",
-					Subject: &hcl.Range{
-						Filename: filename,
-					},
-				})
-			}
-		}
-		if diags.HasErrors() {
-			return nil, convertDiagnosticsToError(diags, parser, opts)
-		}
-		body := hcl.MergeBodies(lo.Map(lo.Values(parser.Files()), func(file *hcl.File, _ int) hcl.Body {
-			return file.Body
-		}))
-		return loadASLWithBody(body, opts)
+		return loadASLWithDir(path, opts)
 	}
 	switch f {
 	default:
@@ -195,7 +188,14 @@ func (f Format) loadASLWithBytes(data []byte, path string, opts *LoadOptions) (*
 		if err := json.Unmarshal(data, &asl); err != nil {
 			return nil, err
 		}
-		return &asl, nil
+		if !opts.Validate {
+			return &asl, nil
+		}
+		diags := asl.Validate()
+		if diags.HasErrors() {
+			return nil, convertDiagnosticsToError(diags, hclparse.NewParser(), opts)
+		}
+		return &asl, convertDiagnosticsToError(diags, hclparse.NewParser(), opts)
 	case FormatHCL:
 		if path == "" {
 			path = "asl.hcl"
@@ -212,7 +212,7 @@ func (f Format) loadASLWithBytes(data []byte, path string, opts *LoadOptions) (*
 		if diags.HasErrors() {
 			return nil, convertDiagnosticsToError(diags, parser, opts)
 		}
-		asl, loadDiags := loadASLWithBody(file.Body, opts)
+		asl, loadDiags := loadASLWithBody(file.Body, filepath.Dir(path), opts)
 		diags = append(diags, loadDiags...)
 		if diags.HasErrors() {
 			return nil, convertDiagnosticsToError(diags, parser, opts)
@@ -241,13 +241,44 @@ func convertDiagnosticsToError(diags hcl.Diagnostics, parser *hclparse.Parser, o
 	return nil
 }
 
-func loadASLWithBody(body hcl.Body, opts *LoadOptions) (*AmazonStatesLanguage, hcl.Diagnostics) {
-	var asl AmazonStatesLanguage
-	diags := asl.DecodeBody(body, opts.HCLEvalContext)
-	return &asl, diags
+// loadASLWithBody decodes the body, baseDir is the directory of the HCL files, that is the root of the filesystem functions.
+// The body may have a state_machine block, but not more than one.
+func loadASLWithBody(body hcl.Body, baseDir string, opts *LoadOptions) (*AmazonStatesLanguage, hcl.Diagnostics) {
+	machines, diags := loadProjectWithBody(body, baseDir, "", opts)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	if len(machines) != 1 {
+		names := make([]string, 0, len(machines))
+		for name := range machines {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Multiple state machines",
+			Detail:   fmt.Sprintf("The configuration declares %d state machines [%s], use LoadProject to load all of them.", len(names), strings.Join(names, ", ")),
+		})
+	}
+	for _, asl := range machines {
+		return asl, diags
+	}
+	return nil, diags
+}
+
+type WriteOptions struct {
+	// StateOrder is the order of the states in the output.
+	StateOrder StateOrder
 }
 
-func (f Format) WriteASL(writer io.Writer, asl *AmazonStatesLanguage) error {
+func (f Format) WriteASL(writer io.Writer, asl *AmazonStatesLanguage, optFns ...func(*WriteOptions)) error {
+	opts := &WriteOptions{
+		StateOrder: StateOrderSource,
+	}
+	for _, optFn := range optFns {
+		optFn(opts)
+	}
+	asl = asl.sortedCopy(opts.StateOrder)
 	switch f {
 	case FormatJSON:
 		encoder := json.NewEncoder(writer)
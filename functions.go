@@ -0,0 +1,292 @@
+package aslconv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// StandardFunctions returns the functions available in the HCL files by default, with the same names as Terraform.
+// They are the go-cty stdlib functions, the encoding functions such as base64encode and yamldecode, and try and can.
+// The filesystem functions are provided by FileFunctions.
+func StandardFunctions() map[string]function.Function {
+	return map[string]function.Function{
+		"abs":                    stdlib.AbsoluteFunc,
+		"base64decode":           base64DecodeFunc,
+		"base64encode":           base64EncodeFunc,
+		"can":                    tryfunc.CanFunc,
+		"ceil":                   stdlib.CeilFunc,
+		"chomp":                  stdlib.ChompFunc,
+		"chunklist":              stdlib.ChunklistFunc,
+		"coalesce":               stdlib.CoalesceFunc,
+		"coalescelist":           stdlib.CoalesceListFunc,
+		"compact":                stdlib.CompactFunc,
+		"concat":                 stdlib.ConcatFunc,
+		"contains":               stdlib.ContainsFunc,
+		"csvdecode":              stdlib.CSVDecodeFunc,
+		"distinct":               stdlib.DistinctFunc,
+		"element":                stdlib.ElementFunc,
+		"flatten":                stdlib.FlattenFunc,
+		"floor":                  stdlib.FloorFunc,
+		"format":                 stdlib.FormatFunc,
+		"formatdate":             stdlib.FormatDateFunc,
+		"formatlist":             stdlib.FormatListFunc,
+		"indent":                 stdlib.IndentFunc,
+		"join":                   stdlib.JoinFunc,
+		"jsondecode":             stdlib.JSONDecodeFunc,
+		"jsonencode":             stdlib.JSONEncodeFunc,
+		"keys":                   stdlib.KeysFunc,
+		"length":                 stdlib.LengthFunc,
+		"log":                    stdlib.LogFunc,
+		"lookup":                 stdlib.LookupFunc,
+		"lower":                  stdlib.LowerFunc,
+		"max":                    stdlib.MaxFunc,
+		"merge":                  stdlib.MergeFunc,
+		"min":                    stdlib.MinFunc,
+		"parseint":               stdlib.ParseIntFunc,
+		"pow":                    stdlib.PowFunc,
+		"range":                  stdlib.RangeFunc,
+		"regex":                  stdlib.RegexFunc,
+		"regexall":               stdlib.RegexAllFunc,
+		"replace":                stdlib.ReplaceFunc,
+		"reverse":                stdlib.ReverseListFunc,
+		"setintersection":        stdlib.SetIntersectionFunc,
+		"setproduct":             stdlib.SetProductFunc,
+		"setsubtract":            stdlib.SetSubtractFunc,
+		"setsymmetricdifference": stdlib.SetSymmetricDifferenceFunc,
+		"setunion":               stdlib.SetUnionFunc,
+		"signum":                 stdlib.SignumFunc,
+		"slice":                  stdlib.SliceFunc,
+		"sort":                   stdlib.SortFunc,
+		"split":                  stdlib.SplitFunc,
+		"strrev":                 stdlib.ReverseFunc,
+		"substr":                 stdlib.SubstrFunc,
+		"timeadd":                stdlib.TimeAddFunc,
+		"title":                  stdlib.TitleFunc,
+		"tobool":                 stdlib.MakeToFunc(cty.Bool),
+		"tolist":                 stdlib.MakeToFunc(cty.List(cty.DynamicPseudoType)),
+		"tomap":                  stdlib.MakeToFunc(cty.Map(cty.DynamicPseudoType)),
+		"tonumber":               stdlib.MakeToFunc(cty.Number),
+		"toset":                  stdlib.MakeToFunc(cty.Set(cty.DynamicPseudoType)),
+		"tostring":               stdlib.MakeToFunc(cty.String),
+		"trim":                   stdlib.TrimFunc,
+		"trimprefix":             stdlib.TrimPrefixFunc,
+		"trimspace":              stdlib.TrimSpaceFunc,
+		"trimsuffix":             stdlib.TrimSuffixFunc,
+		"try":                    tryfunc.TryFunc,
+		"upper":                  stdlib.UpperFunc,
+		"urlencode":              urlEncodeFunc,
+		"values":                 stdlib.ValuesFunc,
+		"yamldecode":             ctyyaml.YAMLDecodeFunc,
+		"yamlencode":             ctyyaml.YAMLEncodeFunc,
+		"zipmap":                 stdlib.ZipmapFunc,
+	}
+}
+
+var base64EncodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(base64.StdEncoding.EncodeToString([]byte(args[0].AsString()))), nil
+	},
+})
+
+var base64DecodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		bs, err := base64.StdEncoding.DecodeString(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.String), fmt.Errorf("failed to decode base64 data: %w", err)
+		}
+		if !utf8.Valid(bs) {
+			return cty.UnknownVal(cty.String), fmt.Errorf("the decoded data is not valid UTF-8")
+		}
+		return cty.StringVal(string(bs)), nil
+	},
+})
+
+var urlEncodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(url.QueryEscape(args[0].AsString())), nil
+	},
+})
+
+// FileFunctions returns the filesystem functions file, filebase64, fileexists and templatefile.
+// The paths are relative to baseDir, the directory of the HCL files, and the paths outside of baseDir are not allowed.
+func FileFunctions(baseDir string) map[string]function.Function {
+	functions := map[string]function.Function{
+		"file":       makeFileFunc(baseDir, false),
+		"filebase64": makeFileFunc(baseDir, true),
+		"fileexists": makeFileExistsFunc(baseDir),
+	}
+	functions["templatefile"] = makeTemplateFileFunc(baseDir, func() map[string]function.Function {
+		// templatefile is not available in the template, to avoid the recursion
+		templateFunctions := StandardFunctions()
+		for name, f := range functions {
+			if name != "templatefile" {
+				templateFunctions[name] = f
+			}
+		}
+		return templateFunctions
+	})
+	return functions
+}
+
+// scopedPath returns the path joined to baseDir, or an error if the path is absolute or outside of baseDir.
+func scopedPath(baseDir, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("the absolute path %s is not allowed, use the path relative to the config directory", path)
+	}
+	joined := filepath.Join(baseDir, path)
+	rel, err := filepath.Rel(baseDir, joined)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("the path %s is outside of the config directory", path)
+	}
+	return joined, nil
+}
+
+func makeFileFunc(baseDir string, encodeBase64 bool) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			path, err := scopedPath(baseDir, args[0].AsString())
+			if err != nil {
+				return cty.UnknownVal(cty.String), err
+			}
+			bs, err := os.ReadFile(path)
+			if err != nil {
+				return cty.UnknownVal(cty.String), err
+			}
+			if encodeBase64 {
+				return cty.StringVal(base64.StdEncoding.EncodeToString(bs)), nil
+			}
+			if !utf8.Valid(bs) {
+				return cty.UnknownVal(cty.String), fmt.Errorf("the file %s is not valid UTF-8, use filebase64 instead", args[0].AsString())
+			}
+			return cty.StringVal(string(bs)), nil
+		},
+	})
+}
+
+func makeFileExistsFunc(baseDir string) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			path, err := scopedPath(baseDir, args[0].AsString())
+			if err != nil {
+				return cty.UnknownVal(cty.Bool), err
+			}
+			stat, err := os.Stat(path)
+			if os.IsNotExist(err) {
+				return cty.False, nil
+			}
+			if err != nil {
+				return cty.UnknownVal(cty.Bool), err
+			}
+			return cty.BoolVal(stat.Mode().IsRegular()), nil
+		},
+	})
+}
+
+func makeTemplateFileFunc(baseDir string, functions func() map[string]function.Function) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "vars", Type: cty.DynamicPseudoType},
+		},
+		Type: function.StaticReturnType(cty.DynamicPseudoType),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			path, err := scopedPath(baseDir, args[0].AsString())
+			if err != nil {
+				return cty.DynamicVal, err
+			}
+			vars := args[1]
+			if !vars.Type().IsMapType() && !vars.Type().IsObjectType() {
+				return cty.DynamicVal, fmt.Errorf("the vars must be a map or an object, but %s is given", vars.Type().FriendlyName())
+			}
+			bs, err := os.ReadFile(path)
+			if err != nil {
+				return cty.DynamicVal, err
+			}
+			expr, diags := hclsyntax.ParseTemplate(bs, path, hcl.InitialPos)
+			if diags.HasErrors() {
+				return cty.DynamicVal, diags
+			}
+			ctx := &hcl.EvalContext{
+				Variables: vars.AsValueMap(),
+				Functions: functions(),
+			}
+			value, diags := expr.Value(ctx)
+			if diags.HasErrors() {
+				return cty.DynamicVal, diags
+			}
+			return value, nil
+		},
+	})
+}
+
+// evalContextWithFunctions returns the child context of ctx, that has the standard functions and the filesystem functions for baseDir,
+// except the functions already defined in ctx, and the additional functions.
+func evalContextWithFunctions(ctx *hcl.EvalContext, baseDir string, opts *LoadOptions) *hcl.EvalContext {
+	defined := evalContextFunctions(ctx)
+	functions := make(map[string]function.Function)
+	if !opts.NoStandardFunctions {
+		for name, f := range StandardFunctions() {
+			functions[name] = f
+		}
+		for name, f := range FileFunctions(baseDir) {
+			functions[name] = f
+		}
+		for name := range defined {
+			delete(functions, name)
+		}
+	}
+	for name, f := range opts.Functions {
+		functions[name] = f
+	}
+	child := ctx.NewChild()
+	child.Functions = functions
+	return child
+}
+
+// evalContextFunctions returns the functions available in the context, including the parent contexts.
+func evalContextFunctions(ctx *hcl.EvalContext) map[string]function.Function {
+	functions := make(map[string]function.Function)
+	var contexts []*hcl.EvalContext
+	for c := ctx; c != nil; c = c.Parent() {
+		contexts = append(contexts, c)
+	}
+	for i := len(contexts) - 1; i >= 0; i-- {
+		for name, f := range contexts[i].Functions {
+			functions[name] = f
+		}
+	}
+	return functions
+}
@@ -0,0 +1,125 @@
+package aslconv_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+func TestLoadASLWithFunctions(t *testing.T) {
+	src := []byte(`
+start_at = state.pass.Pass
+
+locals {
+  config = yamldecode(file("config.yaml"))
+}
+
+state "pass" "Pass" {
+  comment = templatefile("message.tftpl", { name = "aslconv" })
+  result = jsonencode(merge(local.config, {
+    name    = format("%s-%s", upper("notify"), join("-", ["a", "b"]))
+    encoded = base64encode("hello")
+    region  = lookup({ default = "us-east-1" }, "region", "ap-northeast-1")
+    exists  = fileexists("missing.yaml")
+  }))
+  end = true
+}
+`)
+	asl, err := aslconv.FormatHCL.LoadASLWithBytes(src, "testdata/functions/functions.asl.hcl")
+	require.NoError(t, err)
+	require.Equal(t, "Hello, ASLCONV!", *asl.States[0].Comment)
+	require.JSONEq(t, `{
+		"channel": "#alerts",
+		"retries": 3,
+		"name": "NOTIFY-a-b",
+		"encoded": "aGVsbG8=",
+		"region": "ap-northeast-1",
+		"exists": false
+	}`, string(asl.States[0].Result))
+}
+
+func TestLoadASLWithFunctionsOptions(t *testing.T) {
+	cases := []struct {
+		casename string
+		comment  string
+		optFn    func(*aslconv.LoadOptions)
+		expected string
+		errorMsg string
+	}{
+		{
+			casename: "outside_of_config_dir",
+			comment:  `file("../sample.asl.hcl")`,
+			errorMsg: "Error in function call",
+		},
+		{
+			casename: "absolute_path",
+			comment:  `file("/etc/hostname")`,
+			errorMsg: "Error in function call",
+		},
+		{
+			casename: "no_standard_functions",
+			comment:  `upper("aslconv")`,
+			optFn: func(opts *aslconv.LoadOptions) {
+				opts.NoStandardFunctions = true
+			},
+			errorMsg: "Call to unknown function",
+		},
+		{
+			casename: "jsonencode_without_standard_functions",
+			comment:  `jsonencode("aslconv")`,
+			optFn: func(opts *aslconv.LoadOptions) {
+				opts.NoStandardFunctions = true
+			},
+			expected: `"aslconv"`,
+		},
+		{
+			casename: "custom_function",
+			comment:  `upper("aslconv")`,
+			optFn: func(opts *aslconv.LoadOptions) {
+				opts.Functions = map[string]function.Function{
+					"upper": function.New(&function.Spec{
+						Params: []function.Parameter{{Name: "str", Type: cty.String}},
+						Type:   function.StaticReturnType(cty.String),
+						Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+							return cty.StringVal("custom " + args[0].AsString()), nil
+						},
+					}),
+				}
+			},
+			expected: "custom aslconv",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			src := []byte(`
+start_at = state.pass.Pass
+
+state "pass" "Pass" {
+  comment = ` + c.comment + `
+  end     = true
+}
+`)
+			asl, err := aslconv.FormatHCL.LoadASLWithBytes(src, "testdata/functions/functions.asl.hcl", func(opts *aslconv.LoadOptions) {
+				opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+					return nil
+				}
+				if c.optFn != nil {
+					c.optFn(opts)
+				}
+			})
+			if c.errorMsg != "" {
+				var diags hcl.Diagnostics
+				require.ErrorAs(t, err, &diags)
+				require.Contains(t, diagnosticSummaries(diags), c.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, *asl.States[0].Comment)
+		})
+	}
+}
@@ -7,11 +7,11 @@ require (
 	github.com/awalterschulze/gographviz v2.0.3+incompatible
 	github.com/google/go-cmp v0.5.9
 	github.com/hashicorp/hcl/v2 v2.14.0
-	github.com/samber/lo v1.31.0
 	github.com/sebdah/goldie/v2 v2.5.3
 	github.com/sergi/go-diff v1.0.0
 	github.com/stretchr/testify v1.8.0
 	github.com/zclconf/go-cty v1.8.0
+	github.com/zclconf/go-cty-yaml v1.0.2
 )
 
 require (
@@ -19,7 +19,6 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/exp v0.0.0-20220303212507-bbda1eaf7a17 // indirect
 	golang.org/x/text v0.3.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,9 +1,15 @@
 package aslconv
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
 )
 
 type bodySchemaProvider interface {
@@ -71,3 +77,159 @@ func decodeExpression(expr hcl.Expression, ctx *hcl.EvalContext, v interface{})
 	}
 	return gohcl.DecodeExpression(expr, ctx, v)
 }
+
+// sourceRanges holds the source ranges of decoded HCL attributes keyed by the attribute name.
+// The empty key holds the range of the block definition.
+type sourceRanges map[string]*hcl.Range
+
+func (r sourceRanges) get(name string) *hcl.Range {
+	if r == nil {
+		return nil
+	}
+	if rng, ok := r[name]; ok {
+		return rng
+	}
+	return r[""]
+}
+
+// decodeBody decodes the body into the struct pointed by v by the hcl struct tags like gohcl.DecodeBody.
+// Unlike gohcl.DecodeBody, each attribute is decoded by decodeExpression, so that fields such as RawMessage can be used,
+// and the `extra` attribute is decoded into the unknown fields.
+func decodeBody(body hcl.Body, ctx *hcl.EvalContext, v interface{}) hcl.Diagnostics {
+	rv := reflect.ValueOf(v).Elem()
+	schema, _ := gohcl.ImpliedBodySchema(v)
+	content, diags := body.Content(schema)
+	if diags.HasErrors() {
+		return diags
+	}
+	blockRanges := make(map[string]*hcl.Range)
+	for i := 0; i < rv.NumField(); i++ {
+		name, kind, ok := hclTag(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		field := rv.Field(i)
+		switch kind {
+		case "block":
+			for _, block := range content.Blocks.OfType(name) {
+				switch field.Kind() {
+				case reflect.Slice:
+					elem := reflect.New(field.Type().Elem().Elem())
+					diags = append(diags, decodeBody(block.Body, ctx, elem.Interface())...)
+					field.Set(reflect.Append(field, elem))
+				default:
+					if r, ok := blockRanges[name]; ok {
+						diags = append(diags, &hcl.Diagnostic{
+							Severity: hcl.DiagError,
+							Summary:  fmt.Sprintf(`Duplicate "%s" block`, name),
+							Detail:   fmt.Sprintf(`Only one "%s" block is allowed. Another was defined at %s`, name, r.String()),
+							Subject:  block.DefRange.Ptr(),
+						})
+						continue
+					}
+					blockRanges[name] = block.DefRange.Ptr()
+					elem := reflect.New(field.Type().Elem())
+					diags = append(diags, decodeBody(block.Body, ctx, elem.Interface())...)
+					field.Set(elem)
+				}
+			}
+		default:
+			attr, ok := content.Attributes[name]
+			if !ok {
+				continue
+			}
+			if extra, ok := field.Addr().Interface().(*map[string]RawMessage); ok && name == "extra" {
+				diags = append(diags, decodeExtra(attr.Expr, ctx, knownJSONNames(v), extra)...)
+				continue
+			}
+			diags = append(diags, decodeExpression(attr.Expr, ctx, field.Addr().Interface())...)
+		}
+	}
+	return diags
+}
+
+// encodeAsBlock encodes the struct pointed by v as a block like gohcl.EncodeAsBlock,
+// except that RawMessage fields and the extra fields are written as HCL values.
+func encodeAsBlock(v interface{}, blockType string) (*hclwrite.Block, error) {
+	block := hclwrite.NewBlock(blockType, nil)
+	body := block.Body()
+	rv := reflect.ValueOf(v).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		name, kind, ok := hclTag(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		field := rv.Field(i)
+		switch {
+		case kind == "block":
+			if field.IsNil() {
+				continue
+			}
+			nested, err := encodeAsBlock(field.Interface(), name)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%w", name, err)
+			}
+			body.AppendBlock(nested)
+		case field.Type() == reflect.TypeOf(RawMessage{}):
+			if field.IsNil() {
+				continue
+			}
+			if err := field.Interface().(RawMessage).setAttribute(body, name); err != nil {
+				return nil, fmt.Errorf("%s:%w", name, err)
+			}
+		case field.Type() == reflect.TypeOf(map[string]RawMessage{}):
+			if field.Len() == 0 {
+				continue
+			}
+			value, err := extraCtyValue(field.Interface().(map[string]RawMessage))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%w", name, err)
+			}
+			body.SetAttributeValue(name, value)
+		default:
+			if (field.Kind() == reflect.Ptr || field.Kind() == reflect.Slice) && field.IsNil() {
+				continue
+			}
+			value := reflect.Indirect(field).Interface()
+			ty, err := gocty.ImpliedType(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%w", name, err)
+			}
+			ctyValue, err := gocty.ToCtyValue(value, ty)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%w", name, err)
+			}
+			body.SetAttributeValue(name, ctyValue)
+		}
+	}
+	return block, nil
+}
+
+// sortAttributes moves the attributes of the body into the order of the struct fields of v, that is a pointer to a struct.
+// It must be called before any blocks are appended, because the attributes are appended again at the end of the body.
+func sortAttributes(body *hclwrite.Body, v interface{}) {
+	attrs := body.Attributes()
+	rt := reflect.TypeOf(v).Elem()
+	for i := 0; i < rt.NumField(); i++ {
+		name, kind, ok := hclTag(rt.Field(i))
+		if !ok || kind == "block" || kind == "label" {
+			continue
+		}
+		attr, ok := attrs[name]
+		if !ok {
+			continue
+		}
+		tokens := attr.Expr().BuildTokens(nil)
+		body.RemoveAttribute(name)
+		body.SetAttributeRaw(name, tokens)
+	}
+}
+
+func hclTag(field reflect.StructField) (string, string, bool) {
+	tag, ok := field.Tag.Lookup("hcl")
+	if !ok {
+		return "", "", false
+	}
+	name, kind, _ := strings.Cut(tag, ",")
+	return name, kind, true
+}
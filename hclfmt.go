@@ -0,0 +1,337 @@
+package aslconv
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+type FormatOptions struct {
+	// StateOrder is the order of the state blocks, the default is StateOrderSource as the conversion.
+	StateOrder StateOrder
+}
+
+// FormatHCLSource rewrites the HCL source of the state machine into the canonical format, like `terraform fmt`.
+// It works at the syntax level and never evaluates expressions, so that locals, functions and comments are kept as they are.
+// Attributes are sorted in the same order as EncodeBody, blocks are separated by a blank line, and state blocks are sorted by the StateOrder.
+// The bodies that have comments not attached to any attribute or block are not reordered, so that no comment is moved away.
+func FormatHCLSource(src []byte, filename string, optFns ...func(*FormatOptions)) ([]byte, hcl.Diagnostics) {
+	opts := &FormatOptions{
+		StateOrder: StateOrderSource,
+	}
+	for _, optFn := range optFns {
+		optFn(opts)
+	}
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	formatScopeBody(file.Body(), topAttributeOrder, opts)
+	return hclwrite.Format(file.Bytes()), diags
+}
+
+var (
+	topAttributeOrder = []string{"version", "comment", "query_language", "timeout_seconds", "start_at", "extra"}
+	// stateBlockOrder is the order of the blocks in a state block, the same as State.EncodeAsBlock.
+	stateBlockOrder = []string{"retry", "catch", "choice", "branch", "iterator", "item_reader", "item_batcher", "result_writer", "item_processor"}
+	// nestedBodyStructs are the structs that define the attribute order of the blocks.
+	nestedBodyStructs = map[string]interface{}{
+		"retry":            Retrier{},
+		"catch":            Catcher{},
+		"processor_config": ProcessorConfig{},
+		"item_reader":      ItemReader{},
+		"reader_config":    ReaderConfig{},
+		"item_batcher":     ItemBatcher{},
+		"result_writer":    ResultWriter{},
+	}
+)
+
+// hclAttributeOrder returns the attribute names of the struct in the field order.
+func hclAttributeOrder(v interface{}) []string {
+	rt := reflect.TypeOf(v)
+	names := make([]string, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		name, kind, ok := hclTag(rt.Field(i))
+		if !ok || kind == "block" || kind == "label" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func stateAttributeOrder() []string {
+	names := []string{"for_each"}
+	for _, name := range hclAttributeOrder(State{}) {
+		names = append(names, name)
+		switch name {
+		case "cause":
+			// legacy attributes
+			names = append(names, "retry", "catch", "choices")
+		}
+	}
+	return names
+}
+
+func choiceAttributeOrder() []string {
+	operators := make([]string, 0, len(choiceOperatorsByHCLName))
+	for name := range choiceOperatorsByHCLName {
+		operators = append(operators, name)
+	}
+	sort.Strings(operators)
+	names := []string{"comment", "condition", "variable"}
+	names = append(names, operators...)
+	return append(names, "assign", "output", "next")
+}
+
+// formatScopeBody formats the body that declares states.
+func formatScopeBody(body *hclwrite.Body, attrOrder []string, opts *FormatOptions) {
+	for _, block := range body.Blocks() {
+		switch block.Type() {
+		case "state":
+			formatStateBlock(block, opts)
+		case "variable":
+			for _, validation := range block.Body().Blocks() {
+				formatBody(validation.Body(), []string{"condition", "error_message"}, nil, nil)
+			}
+			formatBody(block.Body(), []string{"description", "type", "default"}, nil, nil)
+		case "module":
+			formatBody(block.Body(), []string{"source", "next"}, nil, nil)
+		case "state_machine":
+			formatScopeBody(block.Body(), topAttributeOrder, opts)
+		default:
+			if structure, ok := nestedBodyStructs[block.Type()]; ok {
+				formatBody(block.Body(), hclAttributeOrder(structure), nil, nil)
+			}
+		}
+	}
+	formatBody(body, attrOrder, nil, func(blocks []*hclwrite.Block) []*hclwrite.Block {
+		var others, configs, machines, states []*hclwrite.Block
+		for _, block := range blocks {
+			switch block.Type() {
+			case "state", "module":
+				states = append(states, block)
+			case "processor_config":
+				configs = append(configs, block)
+			case "state_machine":
+				machines = append(machines, block)
+			default:
+				others = append(others, block)
+			}
+		}
+		sorted := append(append(others, configs...), machines...)
+		return append(sorted, sortStateBlocks(body, states, opts.StateOrder)...)
+	})
+}
+
+func formatStateBlock(block *hclwrite.Block, opts *FormatOptions) {
+	for _, nested := range block.Body().Blocks() {
+		switch nested.Type() {
+		case "branch", "iterator", "item_processor":
+			formatScopeBody(nested.Body(), topAttributeOrder, opts)
+		case "choice":
+			formatChoiceBody(nested.Body())
+		default:
+			if structure, ok := nestedBodyStructs[nested.Type()]; ok {
+				formatBody(nested.Body(), hclAttributeOrder(structure), nil, nil)
+			}
+		}
+	}
+	formatBody(block.Body(), stateAttributeOrder(), stateBlockOrder, nil)
+}
+
+func formatChoiceBody(body *hclwrite.Body) {
+	for _, nested := range body.Blocks() {
+		switch nested.Type() {
+		case "and", "or", "not":
+			formatChoiceBody(nested.Body())
+		}
+	}
+	formatBody(body, choiceAttributeOrder(), []string{"and", "or", "not"}, nil)
+}
+
+// formatBody rebuilds the body with the attributes in attrOrder and the blocks separated by a blank line.
+// Unknown attributes follow in the alphabetical order. The blocks are sorted by blockOrder, or sortBlocks if given.
+func formatBody(body *hclwrite.Body, attrOrder []string, blockOrder []string, sortBlocks func([]*hclwrite.Block) []*hclwrite.Block) {
+	if hasFloatingComments(body) {
+		return
+	}
+	attrs := body.Attributes()
+	blocks := body.Blocks()
+	if len(attrs) == 0 && len(blocks) == 0 {
+		return
+	}
+	// the body of a block starts with the newline after the opening brace
+	tokens := body.BuildTokens(nil)
+	leadingNewline := len(tokens) > 0 && tokens[0].Type == hclsyntax.TokenNewline
+	names := make([]string, 0, len(attrs))
+	for _, name := range attrOrder {
+		if _, ok := attrs[name]; ok {
+			names = append(names, name)
+		}
+	}
+	var unknown []string
+	for name := range attrs {
+		if indexOf(attrOrder, name) < 0 {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	names = append(names, unknown...)
+
+	if sortBlocks != nil {
+		blocks = sortBlocks(blocks)
+	} else if blockOrder != nil {
+		sort.SliceStable(blocks, func(i, j int) bool {
+			return blockRank(blockOrder, blocks[i].Type()) < blockRank(blockOrder, blocks[j].Type())
+		})
+	}
+	body.Clear()
+	if leadingNewline {
+		body.AppendNewline()
+	}
+	for _, name := range names {
+		body.AppendUnstructuredTokens(attrs[name].BuildTokens(nil))
+	}
+	for i, block := range blocks {
+		if i > 0 || len(names) > 0 {
+			body.AppendNewline()
+		}
+		body.AppendBlock(block)
+	}
+}
+
+// hasFloatingComments reports whether the body has comments, that belong to neither attributes nor blocks.
+func hasFloatingComments(body *hclwrite.Body) bool {
+	count := func(tokens hclwrite.Tokens) int {
+		var n int
+		for _, token := range tokens {
+			if token.Type == hclsyntax.TokenComment {
+				n++
+			}
+		}
+		return n
+	}
+	var attached int
+	for _, attr := range body.Attributes() {
+		attached += count(attr.BuildTokens(nil))
+	}
+	for _, block := range body.Blocks() {
+		attached += count(block.BuildTokens(nil))
+	}
+	return count(body.BuildTokens(nil)) != attached
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func blockRank(order []string, blockType string) int {
+	if i := indexOf(order, blockType); i >= 0 {
+		return i
+	}
+	return len(order)
+}
+
+// stateBlockKey identifies a state block or a module block, that is referred by `state.task.Name` or `module.name.start`.
+type stateBlockKey struct {
+	blockType string
+	name      string
+}
+
+// sortStateBlocks sorts the state and module blocks by the order, only by the syntax of the references such as `state.task.Name`.
+func sortStateBlocks(body *hclwrite.Body, blocks []*hclwrite.Block, order StateOrder) []*hclwrite.Block {
+	switch order {
+	case StateOrderAlphabetical:
+		sort.SliceStable(blocks, func(i, j int) bool {
+			return blocks[i].Labels()[len(blocks[i].Labels())-1] < blocks[j].Labels()[len(blocks[j].Labels())-1]
+		})
+	case StateOrderTraversal:
+		byName := make(map[stateBlockKey]*hclwrite.Block, len(blocks))
+		for _, block := range blocks {
+			labels := block.Labels()
+			if len(labels) == 0 {
+				continue
+			}
+			key := stateBlockKey{blockType: block.Type(), name: labels[len(labels)-1]}
+			if _, ok := byName[key]; !ok {
+				byName[key] = block
+			}
+		}
+		var queue []stateBlockKey
+		if attr := body.GetAttribute("start_at"); attr != nil {
+			queue = stateReferences(attr.Expr())
+		}
+		visited := make(map[*hclwrite.Block]bool, len(blocks))
+		sorted := make([]*hclwrite.Block, 0, len(blocks))
+		for len(queue) > 0 {
+			block, ok := byName[queue[0]]
+			queue = queue[1:]
+			if !ok || visited[block] {
+				continue
+			}
+			visited[block] = true
+			sorted = append(sorted, block)
+			queue = append(queue, bodyStateReferences(block.Body())...)
+		}
+		for _, block := range blocks {
+			if !visited[block] {
+				sorted = append(sorted, block)
+			}
+		}
+		return sorted
+	}
+	return blocks
+}
+
+// bodyStateReferences returns the states and modules referred in the body, except in the nested state scopes.
+func bodyStateReferences(body *hclwrite.Body) []stateBlockKey {
+	var names []stateBlockKey
+	attrs := body.Attributes()
+	attrNames := make([]string, 0, len(attrs))
+	for name := range attrs {
+		attrNames = append(attrNames, name)
+	}
+	order := append(stateAttributeOrder(), choiceAttributeOrder()...)
+	sort.SliceStable(attrNames, func(i, j int) bool {
+		return blockRank(order, attrNames[i]) < blockRank(order, attrNames[j])
+	})
+	for _, name := range attrNames {
+		names = append(names, stateReferences(attrs[name].Expr())...)
+	}
+	for _, block := range body.Blocks() {
+		if stateScopeBlockTypes[block.Type()] {
+			continue
+		}
+		names = append(names, bodyStateReferences(block.Body())...)
+	}
+	return names
+}
+
+// stateReferences returns the states and modules of the references such as `state.task.Name` and `module.name.start` in the expression.
+func stateReferences(expr *hclwrite.Expression) []stateBlockKey {
+	var names []stateBlockKey
+	for _, traversal := range expr.Variables() {
+		var idents []string
+		for _, token := range traversal.BuildTokens(nil) {
+			if token.Type == hclsyntax.TokenIdent {
+				idents = append(idents, string(token.Bytes))
+			}
+		}
+		switch {
+		case len(idents) >= 3 && idents[0] == "state":
+			names = append(names, stateBlockKey{blockType: "state", name: idents[2]})
+		case len(idents) >= 2 && idents[0] == "module":
+			names = append(names, stateBlockKey{blockType: "module", name: idents[1]})
+		}
+	}
+	return names
+}
@@ -0,0 +1,232 @@
+package aslconv_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestFormatHCLSource(t *testing.T) {
+	cases := []struct {
+		casename string
+		source   string
+		order    aslconv.StateOrder
+		expected string
+	}{
+		{
+			casename: "canonical",
+			order:    aslconv.StateOrderTraversal,
+			source: `locals {
+  arn = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+}
+start_at = state.choice.Choice
+comment = "formatted"
+state "succeed" "Done" {}
+state "task" "Task" {
+  end = true
+  # the function
+  resource = local.arn
+  retry {
+    max_attempts = 2
+    error_equals = ["States.ALL"]
+  }
+  catch {
+    next = state.succeed.Done
+    error_equals = ["States.ALL"]
+  }
+  parameters = jsonencode({ "input.$" = "$" })
+}
+state "choice" "Choice" {
+  choice {
+    next = state.task.Task
+    numeric_equals = 1
+    variable = "$.foo"
+  }
+  default = state.succeed.Done
+}
+`,
+			expected: `comment  = "formatted"
+start_at = state.choice.Choice
+
+locals {
+  arn = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+}
+
+state "choice" "Choice" {
+  default = state.succeed.Done
+
+  choice {
+    variable       = "$.foo"
+    numeric_equals = 1
+    next           = state.task.Task
+  }
+}
+
+state "succeed" "Done" {}
+
+state "task" "Task" {
+  # the function
+  resource   = local.arn
+  end        = true
+  parameters = jsonencode({ "input.$" = "$" })
+
+  retry {
+    error_equals = ["States.ALL"]
+    max_attempts = 2
+  }
+
+  catch {
+    error_equals = ["States.ALL"]
+    next         = state.succeed.Done
+  }
+}
+`,
+		},
+		{
+			casename: "alphabetical",
+			order:    aslconv.StateOrderAlphabetical,
+			source: `start_at = state.pass.B
+state "pass" "B" {
+  next = state.pass.A
+}
+state "pass" "A" {
+  end = true
+}
+`,
+			expected: `start_at = state.pass.B
+
+state "pass" "A" {
+  end = true
+}
+
+state "pass" "B" {
+  next = state.pass.A
+}
+`,
+		},
+		{
+			casename: "state_machine",
+			order:    aslconv.StateOrderAlphabetical,
+			source: `state_machine "orders" {
+  state "pass" "B" {
+    next = state.pass.A
+  }
+  start_at = state.pass.B
+  state "pass" "A" {
+    end = true
+  }
+}
+locals {
+  prefix = "orders"
+}
+`,
+			expected: `locals {
+  prefix = "orders"
+}
+
+state_machine "orders" {
+  start_at = state.pass.B
+
+  state "pass" "A" {
+    end = true
+  }
+
+  state "pass" "B" {
+    next = state.pass.A
+  }
+}
+`,
+		},
+		{
+			casename: "floating_comment",
+			order:    aslconv.StateOrderTraversal,
+			source: `start_at = state.pass.B
+
+state "pass" "A" {
+  end = true
+}
+
+# this comment belongs to no block
+
+state "pass" "B" {
+  next = state.pass.A
+}
+`,
+			expected: `start_at = state.pass.B
+
+state "pass" "A" {
+  end = true
+}
+
+# this comment belongs to no block
+
+state "pass" "B" {
+  next = state.pass.A
+}
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			actual, diags := aslconv.FormatHCLSource([]byte(c.source), c.casename+".asl.hcl", func(opts *aslconv.FormatOptions) {
+				opts.StateOrder = c.order
+			})
+			require.False(t, diags.HasErrors(), diags.Error())
+			require.Equal(t, c.expected, string(actual))
+		})
+	}
+}
+
+func TestFormatHCLSourceDefaultStateOrder(t *testing.T) {
+	src := `start_at = state.pass.First
+
+state "succeed" "Done" {}
+
+state "pass" "First" {
+  next = state.succeed.Done
+}
+`
+	actual, diags := aslconv.FormatHCLSource([]byte(src), "default.asl.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Equal(t, src, string(actual))
+}
+
+func TestFormatHCLSourceIdempotent(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*.asl.hcl")
+	require.NoError(t, err)
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			require.NoError(t, err)
+			formatted, diags := aslconv.FormatHCLSource(src, path)
+			require.False(t, diags.HasErrors(), diags.Error())
+			again, diags := aslconv.FormatHCLSource(formatted, path)
+			require.False(t, diags.HasErrors(), diags.Error())
+			require.Equal(t, string(formatted), string(again))
+
+			var values map[string]cty.Value
+			if varFile := strings.TrimSuffix(path, ".asl.hcl") + ".tfvars"; fileExists(varFile) {
+				values, err = aslconv.LoadVariablesFile(varFile)
+				require.NoError(t, err)
+			}
+			optFn := func(opts *aslconv.LoadOptions) {
+				opts.Variables = values
+			}
+			expected, err := aslconv.LoadASLWithBytes(src, path, optFn)
+			require.NoError(t, err)
+			actual, err := aslconv.LoadASLWithBytes(formatted, path, optFn)
+			require.NoError(t, err)
+			requireASLEq(t, expected, actual)
+		})
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,617 @@
+package intrinsic
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	mathrand "math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/intrinsic-functions.html
+
+// valueType is the type of the arguments and the results of the intrinsic functions, that is known statically.
+type valueType int
+
+const (
+	typeAny valueType = iota
+	typeString
+	typeNumber
+	typeInteger
+	typeBoolean
+	typeArray
+	typeObject
+	typeNull
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeString:
+		return "a string"
+	case typeNumber:
+		return "a number"
+	case typeInteger:
+		return "an integer"
+	case typeBoolean:
+		return "a boolean"
+	case typeArray:
+		return "an array"
+	case typeObject:
+		return "an object"
+	case typeNull:
+		return "null"
+	}
+	return "any value"
+}
+
+// accepts reports whether the parameter accepts the argument of the type.
+func (t valueType) accepts(argType valueType, arg *Arg) bool {
+	switch {
+	case t == typeAny || argType == typeAny || t == argType:
+		return true
+	case t == typeInteger && argType == typeNumber:
+		_, ok := toInt(arg.Value)
+		return ok
+	case t == typeNumber && argType == typeInteger:
+		return true
+	}
+	return false
+}
+
+type function struct {
+	params []valueType
+	// optional is the number of the optional parameters at the end of params.
+	optional int
+	// variadic is true when the last parameter can be repeated, zero or more times.
+	variadic bool
+	result   valueType
+	// rawTemplate is true when the first argument is the template, that is passed with the escapes.
+	rawTemplate bool
+	// check checks the arguments statically, in addition to the types.
+	check func(args []*Arg) error
+	eval  func(args []interface{}, opts Options) (interface{}, error)
+}
+
+func (fn *function) minArgs() int {
+	n := len(fn.params) - fn.optional
+	if fn.variadic {
+		n--
+	}
+	return n
+}
+
+func (fn *function) param(i int) valueType {
+	if i >= len(fn.params) {
+		return fn.params[len(fn.params)-1]
+	}
+	return fn.params[i]
+}
+
+func (fn *function) arity() string {
+	switch {
+	case fn.variadic:
+		return fmt.Sprintf("at least %d arguments", fn.minArgs())
+	case fn.optional > 0:
+		return fmt.Sprintf("%d to %d arguments", fn.minArgs(), len(fn.params))
+	}
+	return fmt.Sprintf("exactly %d arguments", len(fn.params))
+}
+
+// maxStringLength is the limit of the length of the strings for the encoding and the hash functions.
+const maxStringLength = 10000
+
+// maxArrayRange is the limit of the number of the elements by States.ArrayRange.
+const maxArrayRange = 1000
+
+var hashAlgorithms = map[string]func() hash.Hash{
+	"MD5":     md5.New,
+	"SHA-1":   sha1.New,
+	"SHA-256": sha256.New,
+	"SHA-384": sha512.New384,
+	"SHA-512": sha512.New,
+}
+
+var functions = map[string]*function{
+	"States.Format": {
+		params:      []valueType{typeString, typeAny},
+		variadic:    true,
+		result:      typeString,
+		rawTemplate: true,
+		check: func(args []*Arg) error {
+			if args[0].Kind != ArgString {
+				return nil
+			}
+			if n := countPlaceholders(args[0].raw); n != len(args)-1 {
+				return fmt.Errorf("the template has %d placeholders, but got %d arguments", n, len(args)-1)
+			}
+			return nil
+		},
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			template, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return format(template, args[1:])
+		},
+	},
+	"States.StringToJson": {
+		params: []valueType{typeString},
+		result: typeAny,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			s, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			decoder := json.NewDecoder(strings.NewReader(s))
+			decoder.UseNumber()
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, fmt.Errorf("the argument is not JSON: %w", err)
+			}
+			if decoder.More() {
+				return nil, errors.New("the argument is not JSON: unexpected data after the JSON value")
+			}
+			return value, nil
+		},
+	},
+	"States.JsonToString": {
+		params: []valueType{typeAny},
+		result: typeString,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			bs, err := json.Marshal(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return string(bs), nil
+		},
+	},
+	"States.Array": {
+		params:   []valueType{typeAny},
+		variadic: true,
+		result:   typeArray,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			return append([]interface{}{}, args...), nil
+		},
+	},
+	"States.ArrayPartition": {
+		params: []valueType{typeArray, typeInteger},
+		result: typeArray,
+		check: func(args []*Arg) error {
+			if n, ok := toInt(args[1].Value); ok && n <= 0 {
+				return errors.New("the chunk size must be positive")
+			}
+			return nil
+		},
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			array, err := arrayArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			size, err := intArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			if size <= 0 {
+				return nil, errors.New("the chunk size must be positive")
+			}
+			chunks := []interface{}{}
+			for i := 0; i < len(array); i += int(size) {
+				end := i + int(size)
+				if end > len(array) {
+					end = len(array)
+				}
+				chunks = append(chunks, append([]interface{}{}, array[i:end]...))
+			}
+			return chunks, nil
+		},
+	},
+	"States.ArrayContains": {
+		params: []valueType{typeArray, typeAny},
+		result: typeBoolean,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			array, err := arrayArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			key := valueKey(args[1])
+			for _, element := range array {
+				if valueKey(element) == key {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	},
+	"States.ArrayRange": {
+		params: []valueType{typeInteger, typeInteger, typeInteger},
+		result: typeArray,
+		check: func(args []*Arg) error {
+			if n, ok := toInt(args[2].Value); ok && n == 0 {
+				return errors.New("the step must not be zero")
+			}
+			return nil
+		},
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			var bounds [3]int64
+			for i := range bounds {
+				n, err := intArg(args, i)
+				if err != nil {
+					return nil, err
+				}
+				bounds[i] = n
+			}
+			start, end, step := bounds[0], bounds[1], bounds[2]
+			if step == 0 {
+				return nil, errors.New("the step must not be zero")
+			}
+			array := []interface{}{}
+			for n := start; (step > 0 && n <= end) || (step < 0 && n >= end); n += step {
+				if len(array) == maxArrayRange {
+					return nil, fmt.Errorf("the range has more than %d elements", maxArrayRange)
+				}
+				array = append(array, intNumber(n))
+			}
+			return array, nil
+		},
+	},
+	"States.ArrayGetItem": {
+		params: []valueType{typeArray, typeInteger},
+		result: typeAny,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			array, err := arrayArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			index, err := intArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			if index < 0 || index >= int64(len(array)) {
+				return nil, fmt.Errorf("the index %d is out of the array of length %d", index, len(array))
+			}
+			return array[index], nil
+		},
+	},
+	"States.ArrayLength": {
+		params: []valueType{typeArray},
+		result: typeInteger,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			array, err := arrayArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return intNumber(int64(len(array))), nil
+		},
+	},
+	"States.ArrayUnique": {
+		params: []valueType{typeArray},
+		result: typeArray,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			array, err := arrayArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			unique := []interface{}{}
+			seen := make(map[string]bool, len(array))
+			for _, element := range array {
+				key := valueKey(element)
+				if !seen[key] {
+					seen[key] = true
+					unique = append(unique, element)
+				}
+			}
+			return unique, nil
+		},
+	},
+	"States.Base64Encode": {
+		params: []valueType{typeString},
+		result: typeString,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			s, err := limitedStringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return base64.StdEncoding.EncodeToString([]byte(s)), nil
+		},
+	},
+	"States.Base64Decode": {
+		params: []valueType{typeString},
+		result: typeString,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			s, err := limitedStringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			bs, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("the argument is not base64: %w", err)
+			}
+			return string(bs), nil
+		},
+	},
+	"States.Hash": {
+		params: []valueType{typeString, typeString},
+		result: typeString,
+		check: func(args []*Arg) error {
+			if algorithm, ok := args[1].Value.(string); ok && args[1].Kind == ArgString {
+				if _, ok := hashAlgorithms[algorithm]; !ok {
+					return fmt.Errorf("the algorithm %s is not supported, must be one of MD5, SHA-1, SHA-256, SHA-384 and SHA-512", algorithm)
+				}
+			}
+			return nil
+		},
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			data, err := limitedStringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			algorithm, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			newHash, ok := hashAlgorithms[algorithm]
+			if !ok {
+				return nil, fmt.Errorf("the algorithm %s is not supported", algorithm)
+			}
+			h := newHash()
+			h.Write([]byte(data))
+			return hex.EncodeToString(h.Sum(nil)), nil
+		},
+	},
+	"States.JsonMerge": {
+		params: []valueType{typeObject, typeObject, typeBoolean},
+		result: typeObject,
+		check: func(args []*Arg) error {
+			if args[2].Value == true {
+				return errors.New("the deep merge is not supported, the third argument must be false")
+			}
+			return nil
+		},
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			merged := map[string]interface{}{}
+			for i := 0; i < 2; i++ {
+				object, ok := args[i].(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("the argument %d must be an object", i+1)
+				}
+				for key, value := range object {
+					merged[key] = value
+				}
+			}
+			if deep, ok := args[2].(bool); !ok || deep {
+				return nil, errors.New("the deep merge is not supported, the third argument must be false")
+			}
+			return merged, nil
+		},
+	},
+	"States.MathRandom": {
+		params:   []valueType{typeInteger, typeInteger, typeInteger},
+		optional: 1,
+		result:   typeInteger,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			start, err := intArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			end, err := intArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			if end <= start {
+				return nil, errors.New("the end must be greater than the start")
+			}
+			if len(args) <= 2 && opts.Random != nil {
+				n := int64(opts.Random() * float64(end-start))
+				if n >= end-start {
+					n = end - start - 1
+				}
+				return intNumber(start + n), nil
+			}
+			seed := time.Now().UnixNano()
+			if len(args) > 2 {
+				if seed, err = intArg(args, 2); err != nil {
+					return nil, err
+				}
+			}
+			return intNumber(start + mathrand.New(mathrand.NewSource(seed)).Int63n(end-start)), nil
+		},
+	},
+	"States.MathAdd": {
+		params: []valueType{typeInteger, typeInteger},
+		result: typeInteger,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			a, err := intArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			b, err := intArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return intNumber(a + b), nil
+		},
+	},
+	"States.StringSplit": {
+		params: []valueType{typeString, typeString},
+		result: typeArray,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			s, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			delimiters, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			fields := strings.FieldsFunc(s, func(r rune) bool {
+				return strings.ContainsRune(delimiters, r)
+			})
+			array := make([]interface{}, len(fields))
+			for i, field := range fields {
+				array[i] = field
+			}
+			return array, nil
+		},
+	},
+	"States.UUID": {
+		params: []valueType{},
+		result: typeString,
+		eval: func(args []interface{}, opts Options) (interface{}, error) {
+			var b [16]byte
+			if opts.Random != nil {
+				for i := range b {
+					b[i] = byte(opts.Random() * 256)
+				}
+			} else if _, err := rand.Read(b[:]); err != nil {
+				return nil, err
+			}
+			// version 4 and the variant of RFC 4122
+			b[6] = (b[6] & 0x0f) | 0x40
+			b[8] = (b[8] & 0x3f) | 0x80
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+		},
+	},
+}
+
+// format replaces the placeholders `{}` of the template by the arguments. `\{`, `\}`, `\'` and `\\` are the escaped characters.
+func format(template string, args []interface{}) (string, error) {
+	var b strings.Builder
+	next := 0
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case c == '\\' && i+1 < len(template):
+			i++
+			b.WriteByte(template[i])
+		case c == '{' && i+1 < len(template) && template[i+1] == '}':
+			if next >= len(args) {
+				return "", fmt.Errorf("the template has more placeholders than %d arguments", len(args))
+			}
+			s, err := formatValue(args[next])
+			if err != nil {
+				return "", fmt.Errorf("the argument %d %w", next+2, err)
+			}
+			b.WriteString(s)
+			next++
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if next != len(args) {
+		return "", fmt.Errorf("the template has %d placeholders, but got %d arguments", next, len(args))
+	}
+	return b.String(), nil
+}
+
+func countPlaceholders(template string) int {
+	n := 0
+	for i := 0; i < len(template); i++ {
+		switch {
+		case template[i] == '\\':
+			i++
+		case template[i] == '{' && i+1 < len(template) && template[i+1] == '}':
+			n++
+			i++
+		}
+	}
+	return n
+}
+
+func formatValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "null", nil
+	}
+	return "", errors.New("must be a string, a number, a boolean or null")
+}
+
+// valueKey returns the key of the value to compare the equality, the JSON encoding with the sorted keys of the objects.
+func valueKey(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	bs, _ := json.Marshal(v)
+	return string(bs)
+}
+
+func stringArg(args []interface{}, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("the argument %d must be a string", i+1)
+	}
+	return s, nil
+}
+
+func limitedStringArg(args []interface{}, i int) (string, error) {
+	s, err := stringArg(args, i)
+	if err != nil {
+		return "", err
+	}
+	if len(s) > maxStringLength {
+		return "", fmt.Errorf("the argument %d is longer than %d characters", i+1, maxStringLength)
+	}
+	return s, nil
+}
+
+func arrayArg(args []interface{}, i int) ([]interface{}, error) {
+	array, ok := args[i].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the argument %d must be an array", i+1)
+	}
+	return array, nil
+}
+
+func intArg(args []interface{}, i int) (int64, error) {
+	n, ok := toInt(args[i])
+	if !ok {
+		return 0, fmt.Errorf("the argument %d must be an integer", i+1)
+	}
+	return n, nil
+}
+
+// toInt returns the integer of the number decoded by encoding/json, with or without UseNumber.
+func toInt(v interface{}) (int64, bool) {
+	var f float64
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, true
+		}
+		var err error
+		if f, err = v.Float64(); err != nil {
+			return 0, false
+		}
+	case float64:
+		f = v
+	default:
+		return 0, false
+	}
+	if f != math.Trunc(f) || math.Abs(f) > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+func intNumber(n int64) json.Number {
+	return json.Number(strconv.FormatInt(n, 10))
+}
@@ -0,0 +1,368 @@
+// Package intrinsic implements the intrinsic functions of the Amazon States Language,
+// such as `States.Format('Hello, {}', $.name)`, that are used in the payload templates of JSONPath states.
+//
+// The arguments of the functions are the literals of strings, numbers, booleans and null,
+// the paths of the input and the context object, and the nested intrinsic functions.
+package intrinsic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mashiike/aslconv/jsonpath"
+)
+
+// SyntaxError is the error of parsing an intrinsic function.
+type SyntaxError struct {
+	Expr string
+	// Offset is the byte offset in Expr, where the error is found.
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid intrinsic function %s at offset %d: %s", e.Expr, e.Offset, e.Msg)
+}
+
+// IsIntrinsic reports whether the string is an intrinsic function call.
+func IsIntrinsic(s string) bool {
+	return strings.HasPrefix(s, "States.")
+}
+
+// ArgKind is the kind of the argument of an intrinsic function.
+type ArgKind int
+
+const (
+	ArgString ArgKind = iota
+	ArgNumber
+	ArgBoolean
+	ArgNull
+	ArgPath
+	ArgCall
+)
+
+// Arg is an argument of an intrinsic function.
+type Arg struct {
+	Kind ArgKind
+	// Value is the value of the literal, that is string, json.Number, bool or nil.
+	Value interface{}
+	Path  *jsonpath.Path
+	Call  *Call
+	// Offset is the byte offset of the argument in the expression.
+	Offset int
+	// raw is the string literal with the escapes, that States.Format uses as the template.
+	raw string
+}
+
+// Call is a parsed intrinsic function call.
+type Call struct {
+	Name string
+	Args []*Arg
+	// Offset is the byte offset of the call in the expression.
+	Offset int
+}
+
+// Parse parses the intrinsic function call.
+func Parse(expr string) (*Call, error) {
+	p := &parser{src: expr}
+	p.skipSpaces()
+	call, err := p.parseCall()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(expr) {
+		return nil, p.errorf("unexpected character %q after the function call", expr[p.pos])
+	}
+	return call, nil
+}
+
+// Validate checks the intrinsic function call statically, the syntax, the function names, the number of the arguments
+// and the types of the literals and the nested function calls.
+func Validate(expr string) error {
+	call, err := Parse(expr)
+	if err != nil {
+		return err
+	}
+	return call.Validate()
+}
+
+// Options are the options of the evaluation.
+type Options struct {
+	// Random returns a pseudo-random number in [0.0, 1.0) for States.MathRandom without the seed and States.UUID.
+	// If it is nil, States.MathRandom is seeded by the current time and States.UUID reads crypto/rand.
+	Random func() float64
+}
+
+// Evaluate evaluates the intrinsic function call with the input and the context object.
+// The signature is the same as jsonpath.IntrinsicFunc.
+func Evaluate(expr string, input, contextObject interface{}) (interface{}, error) {
+	return Options{}.Evaluate(expr, input, contextObject)
+}
+
+// Evaluate evaluates the intrinsic function call with the options, the method value is a jsonpath.IntrinsicFunc.
+func (opts Options) Evaluate(expr string, input, contextObject interface{}) (interface{}, error) {
+	call, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return call.EvalWithOptions(input, contextObject, opts)
+}
+
+// Validate checks the function name, the number of the arguments and the types of the arguments that are known statically.
+func (c *Call) Validate() error {
+	fn, ok := functions[c.Name]
+	if !ok {
+		return fmt.Errorf("unknown intrinsic function %s", c.Name)
+	}
+	if len(c.Args) < fn.minArgs() || (!fn.variadic && len(c.Args) > len(fn.params)) {
+		return fmt.Errorf("%s takes %s, but got %d", c.Name, fn.arity(), len(c.Args))
+	}
+	for i, arg := range c.Args {
+		if arg.Kind == ArgCall {
+			if err := arg.Call.Validate(); err != nil {
+				return err
+			}
+		}
+		if t := arg.staticType(); !fn.param(i).accepts(t, arg) {
+			return fmt.Errorf("%s: the argument %d must be %s, but got %s", c.Name, i+1, fn.param(i), t)
+		}
+	}
+	if fn.check != nil {
+		if err := fn.check(c.Args); err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// Eval evaluates the function call with the input and the context object.
+func (c *Call) Eval(input, contextObject interface{}) (interface{}, error) {
+	return c.EvalWithOptions(input, contextObject, Options{})
+}
+
+// EvalWithOptions evaluates the function call with the input, the context object and the options.
+func (c *Call) EvalWithOptions(input, contextObject interface{}, opts Options) (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	fn := functions[c.Name]
+	values := make([]interface{}, len(c.Args))
+	for i, arg := range c.Args {
+		switch arg.Kind {
+		case ArgPath:
+			value, err := arg.Path.Get(input, contextObject)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", c.Name, err)
+			}
+			values[i] = value
+		case ArgCall:
+			value, err := arg.Call.EvalWithOptions(input, contextObject, opts)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		case ArgString:
+			values[i] = arg.Value
+			if i == 0 && fn.rawTemplate {
+				values[i] = arg.raw
+			}
+		default:
+			values[i] = arg.Value
+		}
+	}
+	value, err := fn.eval(values, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Name, err)
+	}
+	return value, nil
+}
+
+func (arg *Arg) staticType() valueType {
+	switch arg.Kind {
+	case ArgString:
+		return typeString
+	case ArgNumber:
+		return typeNumber
+	case ArgBoolean:
+		return typeBoolean
+	case ArgNull:
+		return typeNull
+	case ArgCall:
+		if fn, ok := functions[arg.Call.Name]; ok {
+			return fn.result
+		}
+	}
+	return typeAny
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Expr: p.src, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) parseCall() (*Call, error) {
+	start := p.pos
+	if !strings.HasPrefix(p.src[p.pos:], "States.") {
+		return nil, p.errorf("the intrinsic function must start with States.")
+	}
+	for p.pos < len(p.src) && (isIdentChar(p.src[p.pos]) || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	call := &Call{Name: p.src[start:p.pos], Offset: start}
+	if call.Name == "States." {
+		return nil, p.errorf("the function name is empty")
+	}
+	p.skipSpaces()
+	if p.pos >= len(p.src) || p.src[p.pos] != '(' {
+		return nil, p.errorf("expected ( after the function name")
+	}
+	p.pos++
+	p.skipSpaces()
+	if p.pos < len(p.src) && p.src[p.pos] == ')' {
+		p.pos++
+		return call, nil
+	}
+	for {
+		p.skipSpaces()
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		p.skipSpaces()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("the function call is not closed")
+		}
+		switch p.src[p.pos] {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return call, nil
+		default:
+			return nil, p.errorf("expected , or )")
+		}
+	}
+}
+
+func (p *parser) parseArg() (*Arg, error) {
+	start := p.pos
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected an argument")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '\'':
+		value, raw, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &Arg{Kind: ArgString, Value: value, raw: raw, Offset: start}, nil
+	case c == '$':
+		end := p.scanPath()
+		path, err := jsonpath.Parse(p.src[start:end])
+		if err != nil {
+			var syntaxErr *jsonpath.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, &SyntaxError{Expr: p.src, Offset: start + syntaxErr.Offset, Msg: syntaxErr.Msg}
+			}
+			return nil, err
+		}
+		p.pos = end
+		return &Arg{Kind: ArgPath, Path: path, Offset: start}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		literal := p.src[start:p.pos]
+		if _, err := strconv.ParseFloat(literal, 64); err != nil {
+			return nil, &SyntaxError{Expr: p.src, Offset: start, Msg: fmt.Sprintf("invalid number %s", literal)}
+		}
+		return &Arg{Kind: ArgNumber, Value: json.Number(literal), Offset: start}, nil
+	case strings.HasPrefix(p.src[p.pos:], "States."):
+		call, err := p.parseCall()
+		if err != nil {
+			return nil, err
+		}
+		return &Arg{Kind: ArgCall, Call: call, Offset: start}, nil
+	}
+	for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+		p.pos++
+	}
+	switch p.src[start:p.pos] {
+	case "true":
+		return &Arg{Kind: ArgBoolean, Value: true, Offset: start}, nil
+	case "false":
+		return &Arg{Kind: ArgBoolean, Value: false, Offset: start}, nil
+	case "null":
+		return &Arg{Kind: ArgNull, Offset: start}, nil
+	}
+	p.pos = start
+	return nil, p.errorf("expected a string, a number, a boolean, null, a path or an intrinsic function")
+}
+
+// scanPath returns the end of the path argument, that is the comma or the closing parenthesis out of the brackets and the quotes.
+func (p *parser) scanPath() int {
+	depth := 0
+	var quote byte
+	for i := p.pos; i < len(p.src); i++ {
+		c := p.src[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case (c == ']' || c == ')') && depth > 0:
+			depth--
+		case depth == 0 && (c == ',' || c == ')' || c == ' ' || c == '\t'):
+			return i
+		}
+	}
+	return len(p.src)
+}
+
+// parseString parses the single quoted string, and returns the unescaped value and the raw string without the quotes.
+func (p *parser) parseString() (string, string, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == '\'':
+			p.pos++
+			return b.String(), p.src[start+1 : p.pos-1], nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", "", &SyntaxError{Expr: p.src, Offset: start, Msg: "the string is not closed"}
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
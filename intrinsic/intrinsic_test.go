@@ -0,0 +1,164 @@
+package intrinsic_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mashiike/aslconv/intrinsic"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var v interface{}
+	require.NoError(t, decoder.Decode(&v))
+	return v
+}
+
+const input = `{
+	"name": "Alice",
+	"age": 20,
+	"items": [1, 2, 2, 3, {"a": 1}, {"a": 1}],
+	"json": "{\"a\": [1, 2]}",
+	"a": {"x": 1, "y": 2},
+	"b": {"y": 3, "z": 4},
+	"delimited": "This.is+a,test=string"
+}`
+
+func TestEvaluate(t *testing.T) {
+	cases := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: `States.Format('Hello, {}! You\'re {} years old.', $.name, $.age)`, expected: `"Hello, Alice! You're 20 years old."`},
+		{expr: `States.Format('\{\} {} {} {}', true, null, 'x')`, expected: `"{} true null x"`},
+		{expr: `States.Format('{}', States.Format('{}-{}', $.name, $$.Execution.Name))`, expected: `"Alice-test"`},
+		{expr: `States.StringToJson($.json)`, expected: `{"a": [1, 2]}`},
+		{expr: `States.JsonToString($.a)`, expected: `"{\"x\":1,\"y\":2}"`},
+		{expr: `States.Array(1, 'two', $.name, States.Array())`, expected: `[1, "two", "Alice", []]`},
+		{expr: `States.ArrayPartition($.items, 4)`, expected: `[[1, 2, 2, 3], [{"a": 1}, {"a": 1}]]`},
+		{expr: `States.ArrayContains($.items, 3)`, expected: `true`},
+		{expr: `States.ArrayContains($.items, $.a)`, expected: `false`},
+		{expr: `States.ArrayRange(1, 9, 2)`, expected: `[1, 3, 5, 7, 9]`},
+		{expr: `States.ArrayRange(3, 1, -1)`, expected: `[3, 2, 1]`},
+		{expr: `States.ArrayGetItem($.items, 4)`, expected: `{"a": 1}`},
+		{expr: `States.ArrayLength($.items)`, expected: `6`},
+		{expr: `States.ArrayUnique($.items)`, expected: `[1, 2, 3, {"a": 1}]`},
+		{expr: `States.Base64Encode('Data to encode')`, expected: `"RGF0YSB0byBlbmNvZGU="`},
+		{expr: `States.Base64Decode('RGF0YSB0byBlbmNvZGU=')`, expected: `"Data to encode"`},
+		{expr: `States.Hash('input data', 'SHA-1')`, expected: `"aaff4a450a104cd177d28d18d74485e8cae074b7"`},
+		{expr: `States.JsonMerge($.a, $.b, false)`, expected: `{"x": 1, "y": 3, "z": 4}`},
+		{expr: `States.MathAdd($.age, -5)`, expected: `15`},
+		{expr: `States.MathAdd(States.ArrayLength($.items), 1)`, expected: `7`},
+		{expr: `States.StringSplit($.delimited, '.+,=')`, expected: `["This", "is", "a", "test", "string"]`},
+		{expr: ` States.ArrayGetItem( States.StringSplit('a,b', ',') , 1 ) `, expected: `"b"`},
+	}
+	contextObject := decode(t, `{"Execution": {"Name": "test"}}`)
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			require.NoError(t, intrinsic.Validate(c.expr))
+			actual, err := intrinsic.Evaluate(c.expr, decode(t, input), contextObject)
+			require.NoError(t, err)
+			require.Equal(t, decode(t, c.expected), actual)
+		})
+	}
+}
+
+func TestEvaluateRandom(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		actual, err := intrinsic.Evaluate(`States.MathRandom(1, 10)`, nil, nil)
+		require.NoError(t, err)
+		n, err := actual.(json.Number).Int64()
+		require.NoError(t, err)
+		require.True(t, n >= 1 && n < 10, n)
+	}
+	first, err := intrinsic.Evaluate(`States.MathRandom(1, 1000, 42)`, nil, nil)
+	require.NoError(t, err)
+	second, err := intrinsic.Evaluate(`States.MathRandom(1, 1000, 42)`, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, first, second, "the same seed must return the same number")
+
+	uuid, err := intrinsic.Evaluate(`States.UUID()`, nil, nil)
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, uuid)
+}
+
+func TestEvaluateWithRandom(t *testing.T) {
+	opts := intrinsic.Options{Random: func() float64 { return 0.5 }}
+	cases := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{expr: `States.MathRandom(1, 11)`, expected: json.Number("6")},
+		{expr: `States.MathRandom(0, 3)`, expected: json.Number("1")},
+		{expr: `States.UUID()`, expected: "80808080-8080-4080-8080-808080808080"},
+		{expr: `States.Format('{}-{}', States.UUID(), States.MathRandom(0, 2))`, expected: "80808080-8080-4080-8080-808080808080-1"},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			actual, err := opts.Evaluate(c.expr, nil, nil)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, actual)
+		})
+	}
+	upper := intrinsic.Options{Random: func() float64 { return 0.9999999999999999 }}
+	actual, err := upper.Evaluate(`States.MathRandom(1, 10)`, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, json.Number("9"), actual)
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		expr     string
+		errorMsg string
+	}{
+		{expr: `States.Unknown()`, errorMsg: "unknown intrinsic function States.Unknown"},
+		{expr: `States.UUID(1)`, errorMsg: "States.UUID takes exactly 0 arguments, but got 1"},
+		{expr: `States.MathRandom(1)`, errorMsg: "States.MathRandom takes 2 to 3 arguments, but got 1"},
+		{expr: `States.Format()`, errorMsg: "States.Format takes at least 1 arguments, but got 0"},
+		{expr: `States.Format('{} {}', $.name)`, errorMsg: "the template has 2 placeholders, but got 1 arguments"},
+		{expr: `States.MathAdd(1.5, 1)`, errorMsg: "the argument 1 must be an integer, but got a number"},
+		{expr: `States.MathAdd('1', 1)`, errorMsg: "the argument 1 must be an integer, but got a string"},
+		{expr: `States.ArrayLength(States.UUID())`, errorMsg: "the argument 1 must be an array, but got a string"},
+		{expr: `States.Base64Encode(States.Array(States.Unknown()))`, errorMsg: "unknown intrinsic function States.Unknown"},
+		{expr: `States.Hash('data', 'SHA-3')`, errorMsg: "the algorithm SHA-3 is not supported"},
+		{expr: `States.JsonMerge($.a, $.b, true)`, errorMsg: "the deep merge is not supported"},
+		{expr: `States.ArrayRange(1, 10, 0)`, errorMsg: "the step must not be zero"},
+		{expr: `States.ArrayPartition($.items, 0)`, errorMsg: "the chunk size must be positive"},
+		{expr: `States.Format('{}', $.name`, errorMsg: "the function call is not closed"},
+		{expr: `States.Format('{}, $.name)`, errorMsg: "the string is not closed"},
+		{expr: `States.Format('{}', $.[name)`, errorMsg: "at offset 23"},
+		{expr: `States.Array(1) x`, errorMsg: "unexpected character"},
+		{expr: `States.Array(foo)`, errorMsg: "expected a string, a number, a boolean, null, a path or an intrinsic function"},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			err := intrinsic.Validate(c.expr)
+			require.ErrorContains(t, err, c.errorMsg)
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	cases := []struct {
+		expr     string
+		errorMsg string
+	}{
+		{expr: `States.MathAdd($.name, 1)`, errorMsg: "States.MathAdd: the argument 1 must be an integer"},
+		{expr: `States.ArrayGetItem($.items, 10)`, errorMsg: "the index 10 is out of the array of length 6"},
+		{expr: `States.StringToJson($.name)`, errorMsg: "the argument is not JSON"},
+		{expr: `States.Format('{}', $.a)`, errorMsg: "the argument 2 must be a string, a number, a boolean or null"},
+		{expr: `States.Format('{}', $.missing)`, errorMsg: "the path does not match the input"},
+		{expr: `States.Base64Decode($.name)`, errorMsg: "the argument is not base64"},
+		{expr: `States.ArrayRange(1, 2000, 1)`, errorMsg: "more than 1000 elements"},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			_, err := intrinsic.Evaluate(c.expr, decode(t, input), nil)
+			require.ErrorContains(t, err, c.errorMsg)
+		})
+	}
+}
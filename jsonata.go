@@ -0,0 +1,166 @@
+package aslconv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// https://docs.aws.amazon.com/step-functions/latest/dg/transforming-data.html
+const (
+	QueryLanguageJSONPath = "JSONPath"
+	QueryLanguageJSONata  = "JSONata"
+)
+
+// isJSONataExpression reports whether the string is a JSONata expression enclosed in "{%" and "%}".
+func isJSONataExpression(s string) bool {
+	return strings.HasPrefix(s, "{%") && strings.HasSuffix(s, "%}") && len(s) >= 4
+}
+
+// resolveQueryLanguage returns the query language of the state machine or the state, that overrides the inherited one.
+func resolveQueryLanguage(path string, inherited string, queryLanguage *string, subject *hcl.Range) (string, hcl.Diagnostics) {
+	if queryLanguage == nil {
+		return inherited, nil
+	}
+	switch *queryLanguage {
+	case QueryLanguageJSONPath:
+		if inherited == QueryLanguageJSONata {
+			return inherited, hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid QueryLanguage",
+				Detail:   fmt.Sprintf(`%sQueryLanguage can not be "JSONPath" in a JSONata state machine.`, path),
+				Subject:  subject,
+			}}
+		}
+		return *queryLanguage, nil
+	case QueryLanguageJSONata:
+		return *queryLanguage, nil
+	}
+	return inherited, hcl.Diagnostics{&hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid QueryLanguage",
+		Detail:   fmt.Sprintf(`%sQueryLanguage must be "JSONPath" or "JSONata", but got "%s".`, path, *queryLanguage),
+		Subject:  subject,
+	}}
+}
+
+// validateQueryLanguage checks that the state uses only the fields of the query language.
+func (state *State) validateQueryLanguage(path string, queryLanguage string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	unsupported := func(field string, hclName string, hint string) {
+		detail := fmt.Sprintf(`%s.%s can not be used in a %s state.`, path, field, queryLanguage)
+		if hint != "" {
+			detail += " " + hint
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported field",
+			Detail:   detail,
+			Subject:  state.ranges.get(hclName),
+		})
+	}
+	if queryLanguage == QueryLanguageJSONata {
+		for _, field := range []struct {
+			json, hcl string
+			isSet     bool
+			hint      string
+		}{
+			{"InputPath", "input_path", state.InputPath != nil, "Use Arguments instead."},
+			{"Parameters", "parameters", state.Parameters != nil, "Use Arguments instead."},
+			{"ResultSelector", "result_selector", state.ResultSelector != nil, "Use Output instead."},
+			{"ResultPath", "result_path", state.ResultPath != nil, "Use Output or Assign instead."},
+			{"OutputPath", "output_path", state.OutputPath != nil, "Use Output instead."},
+			{"ItemsPath", "items_path", state.ItemsPath != nil, "Use Items instead."},
+			{"TimeoutSecondsPath", "timeout_seconds_path", state.TimeoutSecondsPath != nil, "Use TimeoutSeconds instead."},
+			{"HeartbeatSecondsPath", "heartbeat_seconds_path", state.HeartbeatSecondsPath != nil, "Use HeartbeatSeconds instead."},
+			{"SecondsPath", "seconds_path", state.SecondsPath != nil, "Use Seconds instead."},
+			{"TimestampPath", "timestamp_path", state.TimestampPath != nil, "Use Timestamp instead."},
+			{"MaxConcurrencyPath", "max_concurrency_path", state.MaxConcurrencyPath != nil, "Use MaxConcurrency instead."},
+			{"ToleratedFailureCountPath", "tolerated_failure_count_path", state.ToleratedFailureCountPath != nil, "Use ToleratedFailureCount instead."},
+			{"ToleratedFailurePercentagePath", "tolerated_failure_percentage_path", state.ToleratedFailurePercentagePath != nil, "Use ToleratedFailurePercentage instead."},
+			{"ItemReader.ReaderConfig.MaxItemsPath", "item_reader", state.ItemReader != nil && state.ItemReader.ReaderConfig != nil && state.ItemReader.ReaderConfig.MaxItemsPath != nil, "Use MaxItems instead."},
+			{"ItemBatcher.MaxItemsPerBatchPath", "item_batcher", state.ItemBatcher != nil && state.ItemBatcher.MaxItemsPerBatchPath != nil, "Use MaxItemsPerBatch instead."},
+			{"ItemBatcher.MaxInputBytesPerBatchPath", "item_batcher", state.ItemBatcher != nil && state.ItemBatcher.MaxInputBytesPerBatchPath != nil, "Use MaxInputBytesPerBatch instead."},
+		} {
+			if field.isSet {
+				unsupported(field.json, field.hcl, field.hint)
+			}
+		}
+		for i, rule := range state.Choices {
+			if rule.Condition == nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Missing Condition",
+					Detail:   fmt.Sprintf(`%s.Choices[%d] has no Condition. A choice rule of a JSONata state must have Condition.`, path, i),
+					Subject:  rule.ranges.get(""),
+				})
+			}
+		}
+		for i, catcher := range state.Catch {
+			if catcher.ResultPath != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported field",
+					Detail:   fmt.Sprintf(`%s.Catch[%d].ResultPath can not be used in a JSONata state. Use Output or Assign instead.`, path, i),
+					Subject:  catcher.ranges.get(""),
+				})
+			}
+		}
+		return diags
+	}
+	for _, field := range []struct {
+		json, hcl string
+		isSet     bool
+	}{
+		{"Arguments", "arguments", state.Arguments != nil},
+		{"Output", "output", state.Output != nil},
+		{"Items", "items", state.Items != nil},
+	} {
+		if field.isSet {
+			unsupported(field.json, field.hcl, `Set QueryLanguage to "JSONata" to use it.`)
+		}
+	}
+	for _, field := range []struct {
+		json, hcl    string
+		isExpression bool
+	}{
+		{"TimeoutSeconds", "timeout_seconds", state.TimeoutSeconds.IsExpression()},
+		{"HeartbeatSeconds", "heartbeat_seconds", state.HeartbeatSeconds.IsExpression()},
+		{"Seconds", "seconds", state.Seconds.IsExpression()},
+		{"Timestamp", "timestamp", state.Timestamp != nil && isJSONataExpression(*state.Timestamp)},
+		{"MaxConcurrency", "max_concurrency", state.MaxConcurrency.IsExpression()},
+		{"ToleratedFailureCount", "tolerated_failure_count", state.ToleratedFailureCount.IsExpression()},
+		{"ToleratedFailurePercentage", "tolerated_failure_percentage", state.ToleratedFailurePercentage.IsExpression()},
+	} {
+		if field.isExpression {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported JSONata expression",
+				Detail:   fmt.Sprintf(`%s.%s can be a JSONata expression only in a JSONata state.`, path, field.json),
+				Subject:  state.ranges.get(field.hcl),
+			})
+		}
+	}
+	for i, rule := range state.Choices {
+		if rule.Condition != nil || rule.Output != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported field",
+				Detail:   fmt.Sprintf(`%s.Choices[%d] has Condition or Output, that can be used only in a JSONata state.`, path, i),
+				Subject:  rule.ranges.get(""),
+			})
+		}
+	}
+	for i, catcher := range state.Catch {
+		if catcher.Output != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported field",
+				Detail:   fmt.Sprintf(`%s.Catch[%d].Output can be used only in a JSONata state.`, path, i),
+				Subject:  catcher.ranges.get(""),
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,274 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// filterExpr is the expression of the filter `[?(...)]`, evaluated for each element as `@`.
+type filterExpr interface {
+	match(root, current interface{}) bool
+}
+
+type orExpr []filterExpr
+
+func (e orExpr) match(root, current interface{}) bool {
+	for _, expr := range e {
+		if expr.match(root, current) {
+			return true
+		}
+	}
+	return false
+}
+
+type andExpr []filterExpr
+
+func (e andExpr) match(root, current interface{}) bool {
+	for _, expr := range e {
+		if !expr.match(root, current) {
+			return false
+		}
+	}
+	return true
+}
+
+type notExpr struct {
+	expr filterExpr
+}
+
+func (e *notExpr) match(root, current interface{}) bool {
+	return !e.expr.match(root, current)
+}
+
+// existsExpr matches when the path exists, e.g. `[?(@.isbn)]`.
+type existsExpr struct {
+	operand *operand
+}
+
+func (e *existsExpr) match(root, current interface{}) bool {
+	_, ok := e.operand.value(root, current)
+	return ok
+}
+
+type compareExpr struct {
+	op          string
+	left, right *operand
+}
+
+func (e *compareExpr) match(root, current interface{}) bool {
+	left, ok := e.left.value(root, current)
+	if !ok {
+		return false
+	}
+	right, ok := e.right.value(root, current)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+	c, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// operand is the path `@...` and `$...`, or the literal of the comparison.
+type operand struct {
+	path    *Path
+	current bool
+	literal interface{}
+}
+
+func (o *operand) value(root, current interface{}) (interface{}, bool) {
+	if o.path == nil {
+		return o.literal, true
+	}
+	base := root
+	if o.current {
+		base = current
+	}
+	nodes := o.path.eval(root, base)
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	return nodes[0], true
+}
+
+func equal(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		switch {
+		case !ok:
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	x, ok := a.(string)
+	y, ok2 := b.(string)
+	if !ok || !ok2 {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// toFloat returns the number decoded by encoding/json, with or without UseNumber.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func (p *parser) parseOr() (filterExpr, error) {
+	var exprs orExpr
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		p.skipSpaces()
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *parser) parseAnd() (filterExpr, error) {
+	var exprs andExpr
+	for {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		p.skipSpaces()
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *parser) parseUnary() (filterExpr, error) {
+	p.skipSpaces()
+	if p.peek() == '!' && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{expr: expr}, nil
+	}
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return expr, nil
+	}
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			p.skipSpaces()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &compareExpr{op: op, left: left, right: right}, nil
+		}
+	}
+	if left.path == nil {
+		return nil, p.errorf("expected a comparison operator")
+	}
+	return &existsExpr{operand: left}, nil
+}
+
+func (p *parser) parseOperand() (*operand, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		path, err := p.parsePath(true)
+		if err != nil {
+			return nil, err
+		}
+		return &operand{path: path, current: c == '@'}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &operand{literal: s}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, &SyntaxError{Path: p.src, Offset: start, Msg: "invalid number"}
+		}
+		return &operand{literal: f}, nil
+	}
+	for _, keyword := range []string{"true", "false", "null"} {
+		if p.consume(keyword) {
+			var literal interface{}
+			switch keyword {
+			case "true":
+				literal = true
+			case "false":
+				literal = false
+			}
+			return &operand{literal: literal}, nil
+		}
+	}
+	return nil, p.errorf("expected a path or a literal")
+}
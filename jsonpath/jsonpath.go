@@ -0,0 +1,549 @@
+// Package jsonpath implements the paths of the Amazon States Language, the subset of JSONPath used by Step Functions.
+//
+// A Path such as InputPath, OutputPath and ItemsPath may select multiple nodes by wildcards, slices, unions, filters
+// and recursive descent, e.g. `$.items[*].id` or `$..book[?(@.price < 10)]`.
+// A Reference Path such as ResultPath and Variable of choice rules selects a single node,
+// only by the field names and the array indexes, e.g. `$.detail.items[0]['first name']`.
+// Paths starting with `$$` refer the context object instead of the input.
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned when the path does not match any node of the input.
+var ErrNotFound = errors.New("the path does not match the input")
+
+// SyntaxError is the error of parsing a path.
+type SyntaxError struct {
+	Path string
+	// Offset is the byte offset in Path, where the error is found.
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid path %s at offset %d: %s", e.Path, e.Offset, e.Msg)
+}
+
+// Path is a parsed path.
+type Path struct {
+	raw      string
+	context  bool
+	segments []*segment
+}
+
+// segment is a step of the path, the selectors are the union of the bracket notation, e.g. `['a','b']`.
+type segment struct {
+	offset    int
+	recursive bool
+	selectors []*selector
+}
+
+type selectorKind int
+
+const (
+	selectName selectorKind = iota
+	selectIndex
+	selectWildcard
+	selectSlice
+	selectFilter
+)
+
+type selector struct {
+	kind  selectorKind
+	name  string
+	index int
+	// start, end and step of the slice, nil means the default
+	slice  [3]*int
+	filter filterExpr
+}
+
+// Parse parses the path.
+func Parse(s string) (*Path, error) {
+	p := &parser{src: s}
+	path, err := p.parsePath(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(s) {
+		return nil, p.errorf("unexpected character %q", s[p.pos])
+	}
+	path.raw = s
+	return path, nil
+}
+
+// ParseReference parses the path, that must be a Reference Path.
+func ParseReference(s string) (*Path, error) {
+	path, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range path.segments {
+		if !seg.isDefinite() {
+			return nil, &SyntaxError{Path: s, Offset: seg.offset, Msg: "a reference path can have only field names and array indexes"}
+		}
+	}
+	return path, nil
+}
+
+// MustParse is like Parse but panics if the path can not be parsed.
+func MustParse(s string) *Path {
+	path, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+func (p *Path) String() string {
+	return p.raw
+}
+
+// IsContext reports whether the path refers the context object by `$$`.
+func (p *Path) IsContext() bool {
+	return p.context
+}
+
+// IsReference reports whether the path is a Reference Path, that selects a single node.
+func (p *Path) IsReference() bool {
+	for _, seg := range p.segments {
+		if !seg.isDefinite() {
+			return false
+		}
+	}
+	return true
+}
+
+func (seg *segment) isDefinite() bool {
+	if seg.recursive || len(seg.selectors) != 1 {
+		return false
+	}
+	kind := seg.selectors[0].kind
+	return kind == selectName || kind == selectIndex
+}
+
+// Get returns the value of the path in the input, or in the context object if the path starts with `$$`.
+// The value of a Reference Path is the node itself, and ErrNotFound is returned if it does not exist.
+// The value of the other paths is the array of the matched nodes, that may be empty.
+func (p *Path) Get(input, contextObject interface{}) (interface{}, error) {
+	root := input
+	if p.context {
+		root = contextObject
+	}
+	nodes := p.eval(root, root)
+	if !p.IsReference() {
+		if nodes == nil {
+			nodes = []interface{}{}
+		}
+		return nodes, nil
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("%s: %w", p.raw, ErrNotFound)
+	}
+	return nodes[0], nil
+}
+
+// Lookup is like Get, but reports whether the node of the Reference Path exists instead of the error.
+func (p *Path) Lookup(input, contextObject interface{}) (interface{}, bool) {
+	value, err := p.Get(input, contextObject)
+	return value, err == nil
+}
+
+func (p *Path) eval(root, current interface{}) []interface{} {
+	nodes := []interface{}{current}
+	for _, seg := range p.segments {
+		var next []interface{}
+		for _, node := range nodes {
+			if seg.recursive {
+				for _, descendant := range descendants(node) {
+					next = append(next, seg.apply(root, descendant)...)
+				}
+				continue
+			}
+			next = append(next, seg.apply(root, node)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// descendants returns the node and all of the nodes in it, in the depth-first order.
+func descendants(node interface{}) []interface{} {
+	nodes := []interface{}{node}
+	for _, child := range children(node) {
+		nodes = append(nodes, descendants(child)...)
+	}
+	return nodes
+}
+
+// children returns the elements of the array, or the values of the object in the order of the keys.
+func children(node interface{}) []interface{} {
+	switch node := node.(type) {
+	case []interface{}:
+		return node
+	case map[string]interface{}:
+		keys := sortedKeys(node)
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = node[key]
+		}
+		return values
+	}
+	return nil
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (seg *segment) apply(root, node interface{}) []interface{} {
+	var nodes []interface{}
+	for _, sel := range seg.selectors {
+		nodes = append(nodes, sel.apply(root, node)...)
+	}
+	return nodes
+}
+
+func (sel *selector) apply(root, node interface{}) []interface{} {
+	switch sel.kind {
+	case selectName:
+		if object, ok := node.(map[string]interface{}); ok {
+			if value, ok := object[sel.name]; ok {
+				return []interface{}{value}
+			}
+		}
+	case selectIndex:
+		if array, ok := node.([]interface{}); ok {
+			index := sel.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index >= 0 && index < len(array) {
+				return []interface{}{array[index]}
+			}
+		}
+	case selectWildcard:
+		return children(node)
+	case selectSlice:
+		array, ok := node.([]interface{})
+		if !ok {
+			return nil
+		}
+		start, end, step := 0, len(array), 1
+		if sel.slice[2] != nil {
+			step = *sel.slice[2]
+		}
+		if sel.slice[0] != nil {
+			start = normalizeIndex(*sel.slice[0], len(array))
+		}
+		if sel.slice[1] != nil {
+			end = normalizeIndex(*sel.slice[1], len(array))
+		}
+		var nodes []interface{}
+		for i := start; i < end; i += step {
+			nodes = append(nodes, array[i])
+		}
+		return nodes
+	case selectFilter:
+		var nodes []interface{}
+		for _, child := range children(node) {
+			if sel.filter.match(root, child) {
+				nodes = append(nodes, child)
+			}
+		}
+		return nodes
+	}
+	return nil
+}
+
+func normalizeIndex(index, length int) int {
+	if index < 0 {
+		index += length
+	}
+	if index < 0 {
+		return 0
+	}
+	if index > length {
+		return length
+	}
+	return index
+}
+
+// Set returns the copy of the input, that has the value at the Reference Path.
+// The objects on the path are created if they do not exist, and the array elements must exist.
+// The input itself is not modified, only the objects and the arrays on the path are copied.
+func (p *Path) Set(input, value interface{}) (interface{}, error) {
+	if p.context {
+		return nil, fmt.Errorf("%s: the context object can not be modified", p.raw)
+	}
+	if !p.IsReference() {
+		return nil, fmt.Errorf("%s: the path must be a reference path", p.raw)
+	}
+	return p.set(input, p.segments, value)
+}
+
+func (p *Path) set(node interface{}, segments []*segment, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	sel := segments[0].selectors[0]
+	if sel.kind == selectIndex {
+		array, ok := node.([]interface{})
+		index := sel.index
+		if ok && index < 0 {
+			index += len(array)
+		}
+		if !ok || index < 0 || index >= len(array) {
+			return nil, fmt.Errorf("%s: the array element [%d] does not exist in the input", p.raw, sel.index)
+		}
+		child, err := p.set(array[index], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		copied := append([]interface{}(nil), array...)
+		copied[index] = child
+		return copied, nil
+	}
+	if node == nil {
+		node = map[string]interface{}{}
+	}
+	object, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: the field %s can not be set to the value that is not an object", p.raw, sel.name)
+	}
+	child, err := p.set(object[sel.name], segments[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	copied := make(map[string]interface{}, len(object)+1)
+	for key, v := range object {
+		copied[key] = v
+	}
+	copied[sel.name] = child
+	return copied, nil
+}
+
+// parser is the recursive descent parser of the paths and the filter expressions.
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Path: p.src, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// parsePath parses the path starting with `$`, `$$`, or `@` in the filter expressions.
+func (p *parser) parsePath(inFilter bool) (*Path, error) {
+	path := &Path{}
+	switch {
+	case inFilter && p.consume("@"):
+	case p.consume("$$"):
+		path.context = true
+	case p.consume("$"):
+	default:
+		return nil, p.errorf("the path must start with $")
+	}
+	for p.pos < len(p.src) {
+		offset := p.pos
+		switch {
+		case p.consume(".."):
+			seg, err := p.parseSegmentAfterDot(inFilter)
+			if err != nil {
+				return nil, err
+			}
+			seg.offset, seg.recursive = offset, true
+			path.segments = append(path.segments, seg)
+		case p.consume("."):
+			seg, err := p.parseSegmentAfterDot(inFilter)
+			if err != nil {
+				return nil, err
+			}
+			seg.offset = offset
+			path.segments = append(path.segments, seg)
+		case p.peek() == '[':
+			seg, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			seg.offset = offset
+			path.segments = append(path.segments, seg)
+		default:
+			return path, nil
+		}
+	}
+	return path, nil
+}
+
+// parseSegmentAfterDot parses the field name, `*` or the bracket notation after the dots.
+func (p *parser) parseSegmentAfterDot(inFilter bool) (*segment, error) {
+	if p.peek() == '[' {
+		return p.parseBracket()
+	}
+	if p.consume("*") {
+		return &segment{selectors: []*selector{{kind: selectWildcard}}}, nil
+	}
+	start := p.pos
+	terminators := ".["
+	if inFilter {
+		terminators = ".[ )=!<>&|,"
+	}
+	for p.pos < len(p.src) && !strings.ContainsRune(terminators, rune(p.src[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("the field name is empty")
+	}
+	return &segment{selectors: []*selector{{kind: selectName, name: p.src[start:p.pos]}}}, nil
+}
+
+// parseBracket parses the bracket notation, e.g. `['name']`, `[0]`, `[*]`, `[1:3]`, `[0,1]` and `[?(@.price < 10)]`.
+func (p *parser) parseBracket() (*segment, error) {
+	p.consume("[")
+	seg := &segment{}
+	for {
+		p.skipSpaces()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		seg.selectors = append(seg.selectors, sel)
+		p.skipSpaces()
+		if p.consume("]") {
+			break
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ] or ,")
+		}
+	}
+	if len(seg.selectors) > 1 {
+		for _, sel := range seg.selectors {
+			if sel.kind != selectName && sel.kind != selectIndex {
+				return nil, &SyntaxError{Path: p.src, Offset: p.pos - 1, Msg: "a union can have only field names and array indexes"}
+			}
+		}
+	}
+	return seg, nil
+}
+
+func (p *parser) parseSelector() (*selector, error) {
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		return &selector{kind: selectWildcard}, nil
+	case c == '\'' || c == '"':
+		name, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &selector{kind: selectName, name: name}, nil
+	case c == '?':
+		p.pos++
+		if !p.consume("(") {
+			return nil, p.errorf("expected ( of the filter expression")
+		}
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, p.errorf("expected ) of the filter expression")
+		}
+		return &selector{kind: selectFilter, filter: filter}, nil
+	}
+	var bounds [3]*int
+	for i := 0; i < 3; i++ {
+		if n, ok, err := p.parseInt(); err != nil {
+			return nil, err
+		} else if ok {
+			bounds[i] = &n
+		}
+		if i == 0 && p.peek() != ':' {
+			if bounds[0] == nil {
+				return nil, p.errorf("expected a field name, an index, * or a filter")
+			}
+			return &selector{kind: selectIndex, index: *bounds[0]}, nil
+		}
+		if i == 2 || !p.consume(":") {
+			break
+		}
+	}
+	if bounds[2] != nil && *bounds[2] <= 0 {
+		return nil, p.errorf("the step of the slice must be positive")
+	}
+	return &selector{kind: selectSlice, slice: bounds}, nil
+}
+
+func (p *parser) parseInt() (int, bool, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	digits := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == digits {
+		p.pos = start
+		return 0, false, nil
+	}
+	var n int
+	if _, err := fmt.Sscanf(p.src[start:p.pos], "%d", &n); err != nil {
+		return 0, false, &SyntaxError{Path: p.src, Offset: start, Msg: err.Error()}
+	}
+	return n, true, nil
+}
+
+// parseString parses the quoted string, that has the escaped characters by backslash.
+func (p *parser) parseString() (string, error) {
+	quote := p.src[p.pos]
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", &SyntaxError{Path: p.src, Offset: start, Msg: "the string is not closed"}
+}
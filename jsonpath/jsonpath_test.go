@@ -0,0 +1,155 @@
+package jsonpath_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mashiike/aslconv/jsonpath"
+	"github.com/stretchr/testify/require"
+)
+
+const store = `{
+	"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+			{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+			{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99}
+		],
+		"bicycle": {"color": "red", "price": 19.95}
+	},
+	"first name": "Alice"
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func TestGet(t *testing.T) {
+	input := decode(t, store)
+	contextObject := decode(t, `{"Execution": {"Name": "test"}, "Map": {"Item": {"Index": 1}}}`)
+	cases := []struct {
+		path      string
+		expected  string
+		reference bool
+	}{
+		{path: "$", expected: store, reference: true},
+		{path: "$.store.bicycle.color", expected: `"red"`, reference: true},
+		{path: "$['first name']", expected: `"Alice"`, reference: true},
+		{path: `$["store"]['book'][0].title`, expected: `"Sayings of the Century"`, reference: true},
+		{path: "$.store.book[-1].author", expected: `"Herman Melville"`, reference: true},
+		{path: "$$.Execution.Name", expected: `"test"`, reference: true},
+		{path: "$$.Map.Item.Index", expected: `1`, reference: true},
+		{path: "$.store.book[*].author", expected: `["Nigel Rees", "Evelyn Waugh", "Herman Melville"]`},
+		{path: "$.store.bicycle.*", expected: `["red", 19.95]`},
+		{path: "$..price", expected: `[19.95, 8.95, 12.99, 8.99]`},
+		{path: "$.store..author", expected: `["Nigel Rees", "Evelyn Waugh", "Herman Melville"]`},
+		{path: "$.store.book[0,2].title", expected: `["Sayings of the Century", "Moby Dick"]`},
+		{path: "$.store.book[1:].title", expected: `["Sword of Honour", "Moby Dick"]`},
+		{path: "$.store.book[:2].title", expected: `["Sayings of the Century", "Sword of Honour"]`},
+		{path: "$.store.book[::2].title", expected: `["Sayings of the Century", "Moby Dick"]`},
+		{path: "$.store.book[?(@.price < 10)].title", expected: `["Sayings of the Century", "Moby Dick"]`},
+		{path: "$.store.book[?(@.isbn)].title", expected: `["Moby Dick"]`},
+		{path: "$.store.book[?(!@.isbn)].title", expected: `["Sayings of the Century", "Sword of Honour"]`},
+		{path: "$.store.book[?(@.category == 'fiction' && @.price > 10)].title", expected: `["Sword of Honour"]`},
+		{path: "$.store.book[?(@.category == 'reference' || @.price > 10)].title", expected: `["Sayings of the Century", "Sword of Honour"]`},
+		{path: "$.store.book[?(@.price > $.store.bicycle.price)]", expected: `[]`},
+		{path: "$.store.book[5:].title", expected: `[]`},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			path, err := jsonpath.Parse(c.path)
+			require.NoError(t, err)
+			require.Equal(t, c.path, path.String())
+			require.Equal(t, c.reference, path.IsReference())
+			actual, err := path.Get(input, contextObject)
+			require.NoError(t, err)
+			require.Equal(t, decode(t, c.expected), actual)
+		})
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	input := decode(t, store)
+	for _, p := range []string{"$.store.car", "$.store.book[3]", "$.store.bicycle[0]", "$$.Execution.Name"} {
+		t.Run(p, func(t *testing.T) {
+			path := jsonpath.MustParse(p)
+			_, err := path.Get(input, nil)
+			require.True(t, errors.Is(err, jsonpath.ErrNotFound))
+			_, found := path.Lookup(input, nil)
+			require.False(t, found)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		path      string
+		reference bool
+		offset    int
+	}{
+		{path: "", offset: 0},
+		{path: "store.book", offset: 0},
+		{path: "$.", offset: 2},
+		{path: "$.store.", offset: 8},
+		{path: "$.store[", offset: 8},
+		{path: "$.store['book", offset: 8},
+		{path: "$.store[0", offset: 9},
+		{path: "$.store[?(@.price <)]", offset: 19},
+		{path: "$.store[::0]", offset: 11},
+		{path: "$.store[*,0]", offset: 11},
+		{path: "$ .store", offset: 1},
+		{path: "$.store[*]", reference: true, offset: 7},
+		{path: "$..book", reference: true, offset: 1},
+		{path: "$.book[0:1].title", reference: true, offset: 6},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			parse := jsonpath.Parse
+			if c.reference {
+				parse = jsonpath.ParseReference
+			}
+			_, err := parse(c.path)
+			var syntaxErr *jsonpath.SyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			require.Equal(t, c.path, syntaxErr.Path)
+			require.Equal(t, c.offset, syntaxErr.Offset, syntaxErr.Msg)
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	cases := []struct {
+		path     string
+		input    string
+		expected string
+		errorMsg string
+	}{
+		{path: "$", input: `{"a": 1}`, expected: `"result"`},
+		{path: "$.result", input: `{"a": 1}`, expected: `{"a": 1, "result": "result"}`},
+		{path: "$.a", input: `{"a": 1}`, expected: `{"a": "result"}`},
+		{path: "$.x.y['z z']", input: `{"a": 1}`, expected: `{"a": 1, "x": {"y": {"z z": "result"}}}`},
+		{path: "$.items[1].result", input: `{"items": [{}, {"b": 2}]}`, expected: `{"items": [{}, {"b": 2, "result": "result"}]}`},
+		{path: "$.items[2]", input: `{"items": [{}, {}]}`, errorMsg: "does not exist"},
+		{path: "$.a.b", input: `{"a": 1}`, errorMsg: "not an object"},
+		{path: "$.items[*]", input: `{"items": []}`, errorMsg: "reference path"},
+		{path: "$$.Execution", input: `{}`, errorMsg: "context object"},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			input := decode(t, c.input)
+			original := decode(t, c.input)
+			actual, err := jsonpath.MustParse(c.path).Set(input, "result")
+			if c.errorMsg != "" {
+				require.ErrorContains(t, err, c.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, decode(t, c.expected), actual)
+			require.Equal(t, original, input, "the input must not be modified")
+		})
+	}
+}
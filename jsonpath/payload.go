@@ -0,0 +1,132 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IntrinsicFunc evaluates the intrinsic function, such as `States.Format('{}', $.name)`, in the payload template.
+type IntrinsicFunc func(expr string, input, contextObject interface{}) (interface{}, error)
+
+// PayloadError is the error of the field ending with `.$` in the payload template.
+type PayloadError struct {
+	// Key is the key of the field, e.g. `name.$`.
+	Key string
+	// Value is the path or the intrinsic function of the field.
+	Value string
+	Err   error
+}
+
+func (e *PayloadError) Error() string {
+	return fmt.Sprintf("the field %s: %s", e.Key, e.Err)
+}
+
+func (e *PayloadError) Unwrap() error {
+	return e.Err
+}
+
+// IsIntrinsic reports whether the value of the field ending with `.$` is an intrinsic function.
+func IsIntrinsic(value string) bool {
+	return strings.HasPrefix(value, "States.")
+}
+
+// ResolvePayload returns the copy of the payload template, such as Parameters, ResultSelector and ItemSelector,
+// that has the fields ending with `.$` replaced by the values of the paths or the intrinsic functions.
+// The key of the replaced field does not have the `.$` suffix.
+// The error of the field is *PayloadError, and intrinsic is nil if the intrinsic functions are not supported.
+func ResolvePayload(template, input, contextObject interface{}, intrinsic IntrinsicFunc) (interface{}, error) {
+	switch template := template.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(template))
+		for key, value := range template {
+			if !strings.HasSuffix(key, ".$") {
+				v, err := ResolvePayload(value, input, contextObject, intrinsic)
+				if err != nil {
+					return nil, err
+				}
+				resolved[key] = v
+				continue
+			}
+			v, err := resolveField(key, value, input, contextObject, intrinsic)
+			if err != nil {
+				return nil, err
+			}
+			resolved[strings.TrimSuffix(key, ".$")] = v
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(template))
+		for i, value := range template {
+			v, err := ResolvePayload(value, input, contextObject, intrinsic)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = v
+		}
+		return resolved, nil
+	}
+	return template, nil
+}
+
+func resolveField(key string, value, input, contextObject interface{}, intrinsic IntrinsicFunc) (interface{}, error) {
+	expr, ok := value.(string)
+	if !ok {
+		return nil, &PayloadError{Key: key, Err: fmt.Errorf("the value must be a path or an intrinsic function")}
+	}
+	if IsIntrinsic(expr) {
+		if intrinsic == nil {
+			return nil, &PayloadError{Key: key, Value: expr, Err: fmt.Errorf("the intrinsic functions are not supported")}
+		}
+		v, err := intrinsic(expr, input, contextObject)
+		if err != nil {
+			return nil, &PayloadError{Key: key, Value: expr, Err: err}
+		}
+		return v, nil
+	}
+	path, err := Parse(expr)
+	if err != nil {
+		return nil, &PayloadError{Key: key, Value: expr, Err: err}
+	}
+	v, err := path.Get(input, contextObject)
+	if err != nil {
+		return nil, &PayloadError{Key: key, Value: expr, Err: err}
+	}
+	return v, nil
+}
+
+// ValidatePayload returns the errors of the fields ending with `.$` in the payload template, that are not valid paths.
+// The intrinsic functions are checked by validateIntrinsic, if it is not nil.
+func ValidatePayload(template interface{}, validateIntrinsic func(expr string) error) []*PayloadError {
+	var errs []*PayloadError
+	switch template := template.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(template) {
+			value := template[key]
+			if !strings.HasSuffix(key, ".$") {
+				errs = append(errs, ValidatePayload(value, validateIntrinsic)...)
+				continue
+			}
+			expr, ok := value.(string)
+			switch {
+			case !ok:
+				errs = append(errs, &PayloadError{Key: key, Err: fmt.Errorf("the value must be a path or an intrinsic function")})
+			case IsIntrinsic(expr):
+				if validateIntrinsic == nil {
+					continue
+				}
+				if err := validateIntrinsic(expr); err != nil {
+					errs = append(errs, &PayloadError{Key: key, Value: expr, Err: err})
+				}
+			default:
+				if _, err := Parse(expr); err != nil {
+					errs = append(errs, &PayloadError{Key: key, Value: expr, Err: err})
+				}
+			}
+		}
+	case []interface{}:
+		for _, value := range template {
+			errs = append(errs, ValidatePayload(value, validateIntrinsic)...)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,78 @@
+package jsonpath_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mashiike/aslconv/jsonpath"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePayload(t *testing.T) {
+	template := decode(t, `{
+		"static": "value",
+		"name.$": "$.name",
+		"ids.$": "$.items[*].id",
+		"execution.$": "$$.Execution.Name",
+		"nested": [{"first.$": "$.items[0]"}],
+		"greeting.$": "States.Format('Hello, {}', $.name)"
+	}`)
+	input := decode(t, `{"name": "Alice", "items": [{"id": 1}, {"id": 2}]}`)
+	contextObject := decode(t, `{"Execution": {"Name": "test"}}`)
+	intrinsic := func(expr string, input, _ interface{}) (interface{}, error) {
+		return fmt.Sprintf("%s with %v", expr, input.(map[string]interface{})["name"]), nil
+	}
+	actual, err := jsonpath.ResolvePayload(template, input, contextObject, intrinsic)
+	require.NoError(t, err)
+	require.Equal(t, decode(t, `{
+		"static": "value",
+		"name": "Alice",
+		"ids": [1, 2],
+		"execution": "test",
+		"nested": [{"first": {"id": 1}}],
+		"greeting": "States.Format('Hello, {}', $.name) with Alice"
+	}`), actual)
+}
+
+func TestResolvePayloadErrors(t *testing.T) {
+	cases := []struct {
+		casename string
+		template string
+		key      string
+		errorMsg string
+	}{
+		{casename: "not_found", template: `{"name.$": "$.missing"}`, key: "name.$", errorMsg: "does not match"},
+		{casename: "invalid_path", template: `{"a": {"name.$": "$.[x"}}`, key: "name.$", errorMsg: "invalid path"},
+		{casename: "not_string", template: `{"name.$": 1}`, key: "name.$", errorMsg: "must be a path"},
+		{casename: "intrinsic", template: `{"id.$": "States.UUID()"}`, key: "id.$", errorMsg: "not supported"},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			_, err := jsonpath.ResolvePayload(decode(t, c.template), decode(t, `{}`), nil, nil)
+			var payloadErr *jsonpath.PayloadError
+			require.True(t, errors.As(err, &payloadErr))
+			require.Equal(t, c.key, payloadErr.Key)
+			require.ErrorContains(t, err, c.errorMsg)
+		})
+	}
+}
+
+func TestValidatePayload(t *testing.T) {
+	template := decode(t, `{
+		"ok.$": "$.items[*]",
+		"bad.$": "items",
+		"list": [{"also_bad.$": "$["}],
+		"number.$": 1,
+		"intrinsic.$": "States.Unknown()"
+	}`)
+	errs := jsonpath.ValidatePayload(template, func(expr string) error {
+		return errors.New("unknown function")
+	})
+	keys := make([]string, 0, len(errs))
+	for _, err := range errs {
+		keys = append(keys, err.Key)
+	}
+	require.Equal(t, []string{"bad.$", "intrinsic.$", "also_bad.$", "number.$"}, keys)
+	require.Len(t, jsonpath.ValidatePayload(template, nil), 3)
+}
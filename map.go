@@ -0,0 +1,186 @@
+package aslconv
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// https://states-language.net/spec.html#map-state
+// https://docs.aws.amazon.com/step-functions/latest/dg/amazon-states-language-map-state.html
+
+// ProcessorConfig is the configuration of ItemProcessor.
+type ProcessorConfig struct {
+	// Mode is "INLINE" or "DISTRIBUTED"
+	Mode *string `json:"Mode,omitempty" hcl:"mode"`
+	// ExecutionType is "STANDARD" or "EXPRESS", required in the DISTRIBUTED mode
+	ExecutionType *string `json:"ExecutionType,omitempty" hcl:"execution_type"`
+}
+
+// ItemReader reads the dataset of Distributed Map, e.g. a JSON file or objects in Amazon S3.
+type ItemReader struct {
+	Resource     string        `json:"Resource" hcl:"resource"`
+	Parameters   RawMessage    `json:"Parameters,omitempty" hcl:"parameters,optional"`
+	ReaderConfig *ReaderConfig `json:"ReaderConfig,omitempty" hcl:"reader_config,block"`
+}
+
+type ReaderConfig struct {
+	InputType         *string  `json:"InputType,omitempty" hcl:"input_type"`
+	CSVHeaderLocation *string  `json:"CSVHeaderLocation,omitempty" hcl:"csv_header_location"`
+	CSVHeaders        []string `json:"CSVHeaders,omitempty" hcl:"csv_headers,optional"`
+	MaxItems          *int64   `json:"MaxItems,omitempty" hcl:"max_items"`
+	MaxItemsPath      *string  `json:"MaxItemsPath,omitempty" hcl:"max_items_path"`
+}
+
+// ItemBatcher groups the items into batches of Distributed Map.
+type ItemBatcher struct {
+	MaxItemsPerBatch          *int64     `json:"MaxItemsPerBatch,omitempty" hcl:"max_items_per_batch"`
+	MaxItemsPerBatchPath      *string    `json:"MaxItemsPerBatchPath,omitempty" hcl:"max_items_per_batch_path"`
+	MaxInputBytesPerBatch     *int64     `json:"MaxInputBytesPerBatch,omitempty" hcl:"max_input_bytes_per_batch"`
+	MaxInputBytesPerBatchPath *string    `json:"MaxInputBytesPerBatchPath,omitempty" hcl:"max_input_bytes_per_batch_path"`
+	BatchInput                RawMessage `json:"BatchInput,omitempty" hcl:"batch_input,optional"`
+}
+
+// ResultWriter exports the results of child workflow executions of Distributed Map to Amazon S3.
+type ResultWriter struct {
+	Resource   string     `json:"Resource" hcl:"resource"`
+	Parameters RawMessage `json:"Parameters,omitempty" hcl:"parameters,optional"`
+}
+
+func (state *State) isDistributedMap() bool {
+	return state.ItemProcessor != nil &&
+		state.ItemProcessor.ProcessorConfig != nil &&
+		state.ItemProcessor.ProcessorConfig.Mode != nil &&
+		*state.ItemProcessor.ProcessorConfig.Mode == "DISTRIBUTED"
+}
+
+func (state *State) validateMap(path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if (state.Iterator == nil) == (state.ItemProcessor == nil) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid item processor",
+			Detail:   fmt.Sprintf(`%s must have exactly one of ItemProcessor or Iterator.`, path),
+			Subject:  state.ranges.get(""),
+		})
+	}
+	if state.ItemProcessor != nil && state.ItemProcessor.ProcessorConfig != nil {
+		diags = append(diags, state.ItemProcessor.ProcessorConfig.validate(path+".ItemProcessor.ProcessorConfig", state.ranges.get("item_processor"))...)
+	}
+	for _, pair := range []struct {
+		static, path string
+		hasStatic    bool
+		hasPath      bool
+	}{
+		{"MaxConcurrency", "MaxConcurrencyPath", state.MaxConcurrency != nil, state.MaxConcurrencyPath != nil},
+		{"ToleratedFailureCount", "ToleratedFailureCountPath", state.ToleratedFailureCount != nil, state.ToleratedFailureCountPath != nil},
+		{"ToleratedFailurePercentage", "ToleratedFailurePercentagePath", state.ToleratedFailurePercentage != nil, state.ToleratedFailurePercentagePath != nil},
+	} {
+		if pair.hasStatic && pair.hasPath {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Conflicting fields",
+				Detail:   fmt.Sprintf(`%s has both %s and %s. Only one of them can be used.`, path, pair.static, pair.path),
+				Subject:  state.ranges.get(toSnakeCase(pair.path)),
+			})
+		}
+	}
+	if percentage := state.ToleratedFailurePercentage; percentage != nil && !percentage.IsExpression() && (percentage.Value < 0 || percentage.Value > 100) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid ToleratedFailurePercentage",
+			Detail:   fmt.Sprintf(`%s.ToleratedFailurePercentage must be between 0 and 100.`, path),
+			Subject:  state.ranges.get("tolerated_failure_percentage"),
+		})
+	}
+	if state.Label != nil && len(*state.Label) > 40 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid Label",
+			Detail:   fmt.Sprintf(`%s.Label must be up to 40 characters.`, path),
+			Subject:  state.ranges.get("label"),
+		})
+	}
+	if state.ItemBatcher != nil && state.ItemBatcher.MaxItemsPerBatch != nil && state.ItemBatcher.MaxItemsPerBatchPath != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Conflicting fields",
+			Detail:   fmt.Sprintf(`%s.ItemBatcher has both MaxItemsPerBatch and MaxItemsPerBatchPath. Only one of them can be used.`, path),
+			Subject:  state.ranges.get("item_batcher"),
+		})
+	}
+	if state.ItemBatcher != nil && state.ItemBatcher.MaxInputBytesPerBatch != nil && state.ItemBatcher.MaxInputBytesPerBatchPath != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Conflicting fields",
+			Detail:   fmt.Sprintf(`%s.ItemBatcher has both MaxInputBytesPerBatch and MaxInputBytesPerBatchPath. Only one of them can be used.`, path),
+			Subject:  state.ranges.get("item_batcher"),
+		})
+	}
+	if !state.isDistributedMap() {
+		for _, field := range []struct {
+			json, hcl string
+			isSet     bool
+		}{
+			{"ItemReader", "item_reader", state.ItemReader != nil},
+			{"ItemBatcher", "item_batcher", state.ItemBatcher != nil},
+			{"ResultWriter", "result_writer", state.ResultWriter != nil},
+			{"ToleratedFailureCount", "tolerated_failure_count", state.ToleratedFailureCount != nil},
+			{"ToleratedFailureCountPath", "tolerated_failure_count_path", state.ToleratedFailureCountPath != nil},
+			{"ToleratedFailurePercentage", "tolerated_failure_percentage", state.ToleratedFailurePercentage != nil},
+			{"ToleratedFailurePercentagePath", "tolerated_failure_percentage_path", state.ToleratedFailurePercentagePath != nil},
+			{"Label", "label", state.Label != nil},
+		} {
+			if field.isSet {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported field",
+					Detail:   fmt.Sprintf(`%s.%s is allowed only in a Distributed Map state, which ItemProcessor.ProcessorConfig.Mode is "DISTRIBUTED".`, path, field.json),
+					Subject:  state.ranges.get(field.hcl),
+				})
+			}
+		}
+	}
+	return diags
+}
+
+func (config *ProcessorConfig) validate(path string, subject *hcl.Range) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	mode := "INLINE"
+	if config.Mode != nil {
+		mode = *config.Mode
+	}
+	if mode != "INLINE" && mode != "DISTRIBUTED" {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid Mode",
+			Detail:   fmt.Sprintf(`%s.Mode must be "INLINE" or "DISTRIBUTED", but got "%s".`, path, mode),
+			Subject:  subject,
+		})
+	}
+	if config.ExecutionType != nil && *config.ExecutionType != "STANDARD" && *config.ExecutionType != "EXPRESS" {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid ExecutionType",
+			Detail:   fmt.Sprintf(`%s.ExecutionType must be "STANDARD" or "EXPRESS", but got "%s".`, path, *config.ExecutionType),
+			Subject:  subject,
+		})
+	}
+	if mode == "DISTRIBUTED" && config.ExecutionType == nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing ExecutionType",
+			Detail:   fmt.Sprintf(`%s.ExecutionType is required in the DISTRIBUTED mode.`, path),
+			Subject:  subject,
+		})
+	}
+	if mode == "INLINE" && config.ExecutionType != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported field",
+			Detail:   fmt.Sprintf(`%s.ExecutionType is allowed only in the DISTRIBUTED mode.`, path),
+			Subject:  subject,
+		})
+	}
+	return diags
+}
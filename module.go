@@ -0,0 +1,191 @@
+package aslconv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// module is the states loaded by a `module` block.
+//
+//	module "notify" {
+//	  source  = "./modules/notify"
+//	  next    = state.task.Done
+//	  channel = "#alerts"
+//	}
+//
+// The source is the directory of *.asl.hcl files or a file, relative to the file declaring the block.
+// The other arguments than source and next are the input variables of the module.
+// The state names of the module are prefixed by the module name, e.g. `notify_SendSlack`,
+// and the states with End = true transition to next, or stay terminal if next is not given.
+type module struct {
+	Name   string
+	Source string
+	Next   *string
+	ASL    *AmazonStatesLanguage
+}
+
+var moduleBlockSchema = hcl.BlockHeaderSchema{
+	Type:       "module",
+	LabelNames: []string{"name"},
+}
+
+// loadModule loads the module block. stack is the module sources being loaded, to detect the cyclic modules.
+func loadModule(block *hcl.Block, ctx *hcl.EvalContext, stack []string) (*module, hcl.Diagnostics) {
+	m := &module{Name: block.Labels[0]}
+	attrs, diags := block.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	sourceAttr, ok := attrs["source"]
+	if !ok {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing module source",
+			Detail:   fmt.Sprintf(`The "source" argument is required in the module "%s".`, m.Name),
+			Subject:  block.DefRange.Ptr(),
+		})
+	}
+	source, sourceDiags := sourceAttr.Expr.Value(nil)
+	if sourceDiags.HasErrors() || source.Type() != cty.String || source.IsNull() {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid module source",
+			Detail:   "The module source must be a literal string.",
+			Subject:  sourceAttr.Expr.Range().Ptr(),
+		})
+	}
+	m.Source = source.AsString()
+	path := m.Source
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(block.DefRange.Filename), path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	for _, loading := range stack {
+		if loading == path {
+			return nil, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Module cycle",
+				Detail:   fmt.Sprintf(`The module "%s" loads "%s", that is already being loaded.`, m.Name, m.Source),
+				Subject:  sourceAttr.Expr.Range().Ptr(),
+			})
+		}
+	}
+
+	inputs := make(map[string]cty.Value, len(attrs))
+	for name, attr := range attrs {
+		switch name {
+		case "source":
+		case "next":
+			var next string
+			decodeDiags := decodeExpression(attr.Expr, ctx, &next)
+			diags = append(diags, decodeDiags...)
+			m.Next = &next
+		default:
+			value, valueDiags := attr.Expr.Value(ctx)
+			diags = append(diags, valueDiags...)
+			inputs[name] = value
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	body, dir, parseDiags := parseModuleFiles(path, sourceAttr.Expr.Range())
+	diags = append(diags, parseDiags...)
+	if parseDiags.HasErrors() {
+		return nil, diags
+	}
+	// the module is evaluated in its own scope, that shares only the functions with the caller.
+	// the filesystem functions are replaced to refer the files of the module.
+	functions := evalContextFunctions(ctx)
+	for name, f := range FileFunctions(dir) {
+		if _, ok := functions[name]; ok {
+			functions[name] = f
+		}
+	}
+	moduleCtx, variablesDiags := EvalContextWithVariables(body, &hcl.EvalContext{Functions: functions}, inputs)
+	diags = append(diags, variablesDiags...)
+	if variablesDiags.HasErrors() {
+		return nil, diags
+	}
+	m.ASL = &AmazonStatesLanguage{
+		moduleSources: append(append([]string{}, stack...), path),
+	}
+	diags = append(diags, m.ASL.DecodeBody(body, moduleCtx)...)
+	return m, diags
+}
+
+// parseModuleFiles parses the file, or the project files in the directory, and returns the directory of the files.
+func parseModuleFiles(path string, subject hcl.Range) (hcl.Body, string, hcl.Diagnostics) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, "", hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to load module",
+			Detail:   err.Error(),
+			Subject:  subject.Ptr(),
+		}}
+	}
+	parser := hclparse.NewParser()
+	if stat.IsDir() {
+		body, diags := parseProjectDir(parser, path, subject.Ptr())
+		return body, path, diags
+	}
+	body, diags := parseProjectFiles(parser, []string{path})
+	return body, filepath.Dir(path), diags
+}
+
+func (m *module) prefixed(name string) string {
+	return m.Name + "_" + name
+}
+
+// start returns the namespaced name of the first state of the module.
+func (m *module) start() string {
+	return m.prefixed(m.ASL.StartAt)
+}
+
+// states returns the states of the module, namespaced and wired to Next of the module.
+func (m *module) states() States {
+	for _, state := range m.ASL.States {
+		state.Name = m.prefixed(state.Name)
+		state.mapTransitions(m.prefixed)
+		if state.End != nil && *state.End && m.Next != nil {
+			next := *m.Next
+			state.End = nil
+			state.Next = &next
+		}
+		if state.QueryLanguage == nil && m.ASL.QueryLanguage != nil {
+			queryLanguage := *m.ASL.QueryLanguage
+			state.QueryLanguage = &queryLanguage
+		}
+	}
+	return m.ASL.States
+}
+
+// mapTransitions replaces the targets of the transitions, that are listed by transitions.
+func (state *State) mapTransitions(fn func(string) string) {
+	if state.Next != nil {
+		next := fn(*state.Next)
+		state.Next = &next
+	}
+	if state.Default != nil {
+		next := fn(*state.Default)
+		state.Default = &next
+	}
+	for _, rule := range state.Choices {
+		if rule.Next != nil {
+			next := fn(*rule.Next)
+			rule.Next = &next
+		}
+	}
+	for _, catcher := range state.Catch {
+		catcher.Next = fn(catcher.Next)
+	}
+}
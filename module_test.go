@@ -0,0 +1,128 @@
+package aslconv_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadASLWithModules(t *testing.T) {
+	expected := loadASL(t, "testdata/module.asl.json")
+	actual, err := aslconv.LoadASLWithPath("testdata/module.asl.hcl", func(opts *aslconv.LoadOptions) {
+		opts.Validate = true
+	})
+	require.NoError(t, err)
+	requireASLEq(t, expected, actual)
+}
+
+func TestLoadASLWithModulesErrors(t *testing.T) {
+	cases := []struct {
+		casename string
+		source   string
+		expected []string
+	}{
+		{
+			casename: "missing_source",
+			source: `
+start_at = module.notify.start
+
+module "notify" {
+  channel = "#alerts"
+}
+`,
+			expected: []string{"Missing module source"},
+		},
+		{
+			casename: "missing_module_variable",
+			source: `
+start_at = module.notify.start
+
+module "notify" {
+  source = "./modules/notify"
+}
+`,
+			expected: []string{"No value for required variable"},
+		},
+		{
+			casename: "duplicate_module",
+			source: `
+start_at = module.notify.start
+
+module "notify" {
+  source  = "./modules/notify"
+  channel = "#alerts"
+}
+
+module "notify" {
+  source  = "./modules/notify"
+  channel = "#alerts"
+}
+`,
+			expected: []string{`Duplicate "module" name`},
+		},
+		{
+			casename: "duplicate_state",
+			source: `
+start_at = module.notify.start
+
+module "notify" {
+  source  = "./modules/notify"
+  channel = "#alerts"
+}
+
+state "pass" "notify_Ignore" {
+  end = true
+}
+`,
+			expected: []string{`Duplicate "state" name`},
+		},
+		{
+			casename: "cycle",
+			source: `
+start_at = module.cycle.start
+
+module "cycle" {
+  source = "./modules/cycle"
+}
+`,
+			expected: []string{"Module cycle"},
+		},
+		{
+			casename: "nested_module",
+			source: `
+start_at = state.parallel.Parallel
+
+state "parallel" "Parallel" {
+  end = true
+
+  branch {
+    start_at = module.notify.start
+
+    module "notify" {
+      source  = "./modules/notify"
+      channel = "#alerts"
+    }
+  }
+}
+`,
+			expected: []string{`Unexpected "module" block`},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			_, err := aslconv.FormatHCL.LoadASLWithBytes([]byte(c.source), "testdata/"+c.casename+".asl.hcl", func(opts *aslconv.LoadOptions) {
+				opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+					return nil
+				}
+			})
+			var diags hcl.Diagnostics
+			require.ErrorAs(t, err, &diags)
+			for _, expected := range c.expected {
+				require.Contains(t, diagnosticSummaries(diags), expected)
+			}
+		})
+	}
+}
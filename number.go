@@ -0,0 +1,143 @@
+package aslconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Integer is the value of an integer field such as Seconds and TimeoutSeconds.
+// In JSONata states, the field can be a JSONata expression e.g. "{% $states.input.seconds %}" instead of the integer.
+type Integer struct {
+	Value int64
+	// Expression is the JSONata expression, that is set instead of Value.
+	Expression string
+}
+
+// Number is the value of a number field such as ToleratedFailurePercentage, that can be a JSONata expression as Integer.
+type Number struct {
+	Value float64
+	// Expression is the JSONata expression, that is set instead of Value.
+	Expression string
+}
+
+// IsExpression reports whether the value is a JSONata expression. It is false for nil.
+func (i *Integer) IsExpression() bool {
+	return i != nil && i.Expression != ""
+}
+
+// IsExpression reports whether the value is a JSONata expression. It is false for nil.
+func (n *Number) IsExpression() bool {
+	return n != nil && n.Expression != ""
+}
+
+func (i Integer) MarshalJSON() ([]byte, error) {
+	if i.Expression != "" {
+		return json.Marshal(i.Expression)
+	}
+	return json.Marshal(i.Value)
+}
+
+func (i *Integer) UnmarshalJSON(data []byte) error {
+	expression, ok, err := unmarshalJSONataExpression(data)
+	if err != nil || ok {
+		*i = Integer{Expression: expression}
+		return err
+	}
+	*i = Integer{}
+	return json.Unmarshal(data, &i.Value)
+}
+
+func (n Number) MarshalJSON() ([]byte, error) {
+	if n.Expression != "" {
+		return json.Marshal(n.Expression)
+	}
+	return json.Marshal(n.Value)
+}
+
+func (n *Number) UnmarshalJSON(data []byte) error {
+	expression, ok, err := unmarshalJSONataExpression(data)
+	if err != nil || ok {
+		*n = Number{Expression: expression}
+		return err
+	}
+	*n = Number{}
+	return json.Unmarshal(data, &n.Value)
+}
+
+// unmarshalJSONataExpression unmarshals the JSON string of a JSONata expression. ok is false if the data is not a string.
+func unmarshalJSONataExpression(data []byte) (string, bool, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return "", false, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", false, err
+	}
+	if !isJSONataExpression(s) {
+		return "", false, fmt.Errorf(`"%s" is neither a number nor a JSONata expression`, s)
+	}
+	return s, true, nil
+}
+
+func (i *Integer) ctyValue() cty.Value {
+	if i.Expression != "" {
+		return cty.StringVal(i.Expression)
+	}
+	return cty.NumberIntVal(i.Value)
+}
+
+func (n *Number) ctyValue() cty.Value {
+	if n.Expression != "" {
+		return cty.StringVal(n.Expression)
+	}
+	return cty.NumberFloatVal(n.Value)
+}
+
+// decodeInteger decodes the integer or the JSONata expression, the null value is decoded as nil.
+func decodeInteger(expr hcl.Expression, ctx *hcl.EvalContext, v **Integer) hcl.Diagnostics {
+	expression, ok, diags := decodeJSONataExpression(expr, ctx)
+	if ok || diags.HasErrors() {
+		*v = &Integer{Expression: expression}
+		return diags
+	}
+	var value *int64
+	diags = gohcl.DecodeExpression(expr, ctx, &value)
+	*v = nil
+	if value != nil {
+		*v = &Integer{Value: *value}
+	}
+	return diags
+}
+
+// decodeNumber decodes the number or the JSONata expression, the null value is decoded as nil.
+func decodeNumber(expr hcl.Expression, ctx *hcl.EvalContext, v **Number) hcl.Diagnostics {
+	expression, ok, diags := decodeJSONataExpression(expr, ctx)
+	if ok || diags.HasErrors() {
+		*v = &Number{Expression: expression}
+		return diags
+	}
+	var value *float64
+	diags = gohcl.DecodeExpression(expr, ctx, &value)
+	*v = nil
+	if value != nil {
+		*v = &Number{Value: *value}
+	}
+	return diags
+}
+
+// decodeJSONataExpression decodes the expression as a JSONata expression. ok is false if the value is not a JSONata expression.
+func decodeJSONataExpression(expr hcl.Expression, ctx *hcl.EvalContext) (string, bool, hcl.Diagnostics) {
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return "", false, diags
+	}
+	if value.Type() != cty.String || value.IsNull() || !value.IsKnown() || !isJSONataExpression(value.AsString()) {
+		return "", false, nil
+	}
+	return value.AsString(), true, diags
+}
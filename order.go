@@ -0,0 +1,199 @@
+package aslconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StateOrder is the order of the states in the output of EncodeBody and MarshalJSON.
+type StateOrder int
+
+const (
+	// StateOrderSource keeps the order of the states in the source file.
+	StateOrderSource StateOrder = iota
+	// StateOrderTraversal orders the states by the traversal from StartAt.
+	StateOrderTraversal
+	// StateOrderAlphabetical orders the states by the name.
+	StateOrderAlphabetical
+	stateOrderInvalid
+)
+
+func StateOrders() []StateOrder {
+	orders := make([]StateOrder, 0, stateOrderInvalid)
+	for i := StateOrder(0); i < stateOrderInvalid; i++ {
+		orders = append(orders, i)
+	}
+	return orders
+}
+
+func GetStateOrder(name string) (StateOrder, bool) {
+	switch strings.ToLower(name) {
+	case "", "source":
+		return StateOrderSource, true
+	case "traversal":
+		return StateOrderTraversal, true
+	case "alphabetical":
+		return StateOrderAlphabetical, true
+	}
+	return stateOrderInvalid, false
+}
+
+func (o StateOrder) String() string {
+	switch o {
+	case StateOrderSource:
+		return "source"
+	case StateOrderTraversal:
+		return "traversal"
+	case StateOrderAlphabetical:
+		return "alphabetical"
+	}
+	return ""
+}
+
+// SortStates reorders the states, including the states of Branches, Iterator and ItemProcessor.
+// StateOrderSource keeps the current order, that is the order in the source file when the state machine is decoded.
+func (top *AmazonStatesLanguage) SortStates(order StateOrder) {
+	top.sortTopStates(order)
+	for _, state := range top.States {
+		for _, branch := range state.Branches {
+			branch.SortStates(order)
+		}
+		if state.Iterator != nil {
+			state.Iterator.SortStates(order)
+		}
+		if state.ItemProcessor != nil {
+			state.ItemProcessor.SortStates(order)
+		}
+	}
+}
+
+func (top *AmazonStatesLanguage) sortTopStates(order StateOrder) {
+	switch order {
+	case StateOrderTraversal:
+		top.States = top.States.traversalOrder(top.StartAt)
+	case StateOrderAlphabetical:
+		sort.SliceStable(top.States, func(i, j int) bool {
+			return top.States[i].Name < top.States[j].Name
+		})
+	}
+}
+
+// sortedCopy returns the copy of the state machine whose states are sorted like SortStates, and top is not changed.
+// The copy shares the states with top, except the states that have Branches, Iterator or ItemProcessor to be sorted.
+func (top *AmazonStatesLanguage) sortedCopy(order StateOrder) *AmazonStatesLanguage {
+	sorted := *top
+	sorted.States = make(States, len(top.States))
+	for i, state := range top.States {
+		if len(state.Branches) == 0 && state.Iterator == nil && state.ItemProcessor == nil {
+			sorted.States[i] = state
+			continue
+		}
+		copied := *state
+		if len(state.Branches) > 0 {
+			copied.Branches = make([]*AmazonStatesLanguage, len(state.Branches))
+			for j, branch := range state.Branches {
+				copied.Branches[j] = branch.sortedCopy(order)
+			}
+		}
+		if state.Iterator != nil {
+			copied.Iterator = state.Iterator.sortedCopy(order)
+		}
+		if state.ItemProcessor != nil {
+			copied.ItemProcessor = state.ItemProcessor.sortedCopy(order)
+		}
+		sorted.States[i] = &copied
+	}
+	sorted.sortTopStates(order)
+	return &sorted
+}
+
+// traversalOrder returns the states in the breadth-first order from startAt.
+// The states that can not be reached from startAt follow in the current order.
+func (states States) traversalOrder(startAt string) States {
+	byName := make(map[string]*State, len(states))
+	for _, state := range states {
+		if _, ok := byName[state.Name]; !ok {
+			byName[state.Name] = state
+		}
+	}
+	ordered := make(States, 0, len(states))
+	visited := make(map[*State]bool, len(states))
+	queue := []string{startAt}
+	for len(queue) > 0 {
+		state, ok := byName[queue[0]]
+		queue = queue[1:]
+		if !ok || visited[state] {
+			continue
+		}
+		visited[state] = true
+		ordered = append(ordered, state)
+		for _, t := range state.transitions() {
+			queue = append(queue, t.next)
+		}
+	}
+	for _, state := range states {
+		if !visited[state] {
+			ordered = append(ordered, state)
+		}
+	}
+	return ordered
+}
+
+// jsonObjectKeys returns the keys of the JSON object in the order of appearance.
+func jsonObjectKeys(data []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("not a JSON object")
+	}
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected token %v", token)
+		}
+		keys = append(keys, key)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// orderedStates is marshaled into the JSON object keyed by the state names, in the order of the states.
+type orderedStates States
+
+func (states orderedStates) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, s := range states {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(s.Name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		bs, err := json.Marshal(s)
+		if err != nil {
+			return nil, fmt.Errorf("States[\"%s\"]:%w", s.Name, err)
+		}
+		buf.Write(bs)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
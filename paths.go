@@ -0,0 +1,192 @@
+package aslconv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/mashiike/aslconv/intrinsic"
+	"github.com/mashiike/aslconv/jsonpath"
+)
+
+// https://states-language.net/spec.html#path
+// https://docs.aws.amazon.com/step-functions/latest/dg/amazon-states-language-paths.html
+
+// validatePaths checks the paths and the payload templates of the JSONPath state.
+func (state *State) validatePaths(path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, field := range []struct {
+		json, hcl string
+		value     *string
+		reference bool
+	}{
+		{"InputPath", "input_path", state.InputPath, false},
+		{"OutputPath", "output_path", state.OutputPath, false},
+		{"ItemsPath", "items_path", state.ItemsPath, false},
+		{"ResultPath", "result_path", state.ResultPath, true},
+		{"TimeoutSecondsPath", "timeout_seconds_path", state.TimeoutSecondsPath, true},
+		{"HeartbeatSecondsPath", "heartbeat_seconds_path", state.HeartbeatSecondsPath, true},
+		{"SecondsPath", "seconds_path", state.SecondsPath, true},
+		{"TimestampPath", "timestamp_path", state.TimestampPath, true},
+		{"MaxConcurrencyPath", "max_concurrency_path", state.MaxConcurrencyPath, true},
+		{"ToleratedFailureCountPath", "tolerated_failure_count_path", state.ToleratedFailureCountPath, true},
+		{"ToleratedFailurePercentagePath", "tolerated_failure_percentage_path", state.ToleratedFailurePercentagePath, true},
+	} {
+		if field.value == nil {
+			continue
+		}
+		diags = append(diags, validatePath(path+"."+field.json, *field.value, field.reference, state.ranges.get(field.hcl))...)
+	}
+	if state.ResultPath != nil {
+		diags = append(diags, validateResultPath(path+".ResultPath", *state.ResultPath, state.ranges.get("result_path"))...)
+	}
+	if state.ItemReader != nil && state.ItemReader.ReaderConfig != nil && state.ItemReader.ReaderConfig.MaxItemsPath != nil {
+		diags = append(diags, validatePath(path+".ItemReader.ReaderConfig.MaxItemsPath", *state.ItemReader.ReaderConfig.MaxItemsPath, true, state.ranges.get("item_reader"))...)
+	}
+	if batcher := state.ItemBatcher; batcher != nil {
+		if batcher.MaxItemsPerBatchPath != nil {
+			diags = append(diags, validatePath(path+".ItemBatcher.MaxItemsPerBatchPath", *batcher.MaxItemsPerBatchPath, true, state.ranges.get("item_batcher"))...)
+		}
+		if batcher.MaxInputBytesPerBatchPath != nil {
+			diags = append(diags, validatePath(path+".ItemBatcher.MaxInputBytesPerBatchPath", *batcher.MaxInputBytesPerBatchPath, true, state.ranges.get("item_batcher"))...)
+		}
+	}
+	for _, field := range []struct {
+		json, hcl string
+		value     RawMessage
+	}{
+		{"Parameters", "parameters", state.Parameters},
+		{"ResultSelector", "result_selector", state.ResultSelector},
+		{"ItemSelector", "item_selector", state.ItemSelector},
+	} {
+		diags = append(diags, validatePayload(path+"."+field.json, field.value, state.ranges.get(field.hcl))...)
+	}
+	for i, rule := range state.Choices {
+		diags = append(diags, rule.validatePaths(fmt.Sprintf("%s.Choices[%d]", path, i))...)
+	}
+	for i, catcher := range state.Catch {
+		if catcher.ResultPath != nil {
+			catchPath := fmt.Sprintf("%s.Catch[%d].ResultPath", path, i)
+			diags = append(diags, validatePath(catchPath, *catcher.ResultPath, true, catcher.ranges.get("result_path"))...)
+			diags = append(diags, validateResultPath(catchPath, *catcher.ResultPath, catcher.ranges.get("result_path"))...)
+		}
+	}
+	return diags
+}
+
+func (rule *ChoiceRule) validatePaths(path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if rule.Variable != nil {
+		diags = append(diags, validatePath(path+".Variable", *rule.Variable, true, rule.ranges.get("variable"))...)
+	}
+	if choiceOperators[rule.Operator] == choiceOperandPath {
+		// the operand that is not a path string is reported by validateOperand
+		var operand string
+		if err := json.Unmarshal(rule.Value, &operand); err == nil && operand != "" && operand[0] == '$' {
+			diags = append(diags, validatePath(path+"."+rule.Operator, operand, true, rule.ranges.get(toSnakeCase(rule.Operator)))...)
+		}
+	}
+	for i, nested := range rule.And {
+		diags = append(diags, nested.validatePaths(fmt.Sprintf("%s.And[%d]", path, i))...)
+	}
+	for i, nested := range rule.Or {
+		diags = append(diags, nested.validatePaths(fmt.Sprintf("%s.Or[%d]", path, i))...)
+	}
+	if rule.Not != nil {
+		diags = append(diags, rule.Not.validatePaths(path+".Not")...)
+	}
+	return diags
+}
+
+func validatePath(path string, value string, reference bool, subject *hcl.Range) hcl.Diagnostics {
+	parse, kind := jsonpath.Parse, "path"
+	if reference {
+		parse, kind = jsonpath.ParseReference, "reference path"
+	}
+	if _, err := parse(value); err != nil {
+		return hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid path",
+			Detail:   fmt.Sprintf(`%s "%s" is not a valid %s: %s.`, path, value, kind, syntaxErrorMessage(err)),
+			Subject:  pathSubject(subject, value, err),
+		}}
+	}
+	return nil
+}
+
+// validateResultPath checks that the ResultPath does not refer the context object, that can not be modified.
+func validateResultPath(path string, value string, subject *hcl.Range) hcl.Diagnostics {
+	parsed, err := jsonpath.Parse(value)
+	if err != nil || !parsed.IsContext() {
+		return nil
+	}
+	return hcl.Diagnostics{&hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid path",
+		Detail:   fmt.Sprintf(`%s "%s" refers the context object. The result can be placed only in the state input.`, path, value),
+		Subject:  subject,
+	}}
+}
+
+func validatePayload(path string, raw RawMessage, subject *hcl.Range) hcl.Diagnostics {
+	if len(raw) == 0 {
+		return nil
+	}
+	var template interface{}
+	if err := json.Unmarshal(raw, &template); err != nil {
+		// the invalid JSON is reported on decoding
+		return nil
+	}
+	var diags hcl.Diagnostics
+	for _, err := range jsonpath.ValidatePayload(template, intrinsic.Validate) {
+		summary := "Invalid path"
+		detail := fmt.Sprintf(`%s has the field "%s", that must be a path or an intrinsic function.`, path, err.Key)
+		switch {
+		case intrinsic.IsIntrinsic(err.Value):
+			summary = "Invalid intrinsic function"
+			detail = fmt.Sprintf(`%s has the field "%s" with "%s", that is not a valid intrinsic function: %s.`, path, err.Key, err.Value, syntaxErrorMessage(err.Err))
+		case err.Value != "":
+			detail = fmt.Sprintf(`%s has the field "%s" with "%s", that is not a valid path: %s.`, path, err.Key, err.Value, syntaxErrorMessage(err.Err))
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  summary,
+			Detail:   detail,
+			Subject:  subject,
+		})
+	}
+	return diags
+}
+
+func syntaxErrorMessage(err error) string {
+	var syntaxErr *jsonpath.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Sprintf("%s at offset %d", syntaxErr.Msg, syntaxErr.Offset)
+	}
+	var intrinsicErr *intrinsic.SyntaxError
+	if errors.As(err, &intrinsicErr) {
+		return fmt.Sprintf("%s at offset %d", intrinsicErr.Msg, intrinsicErr.Offset)
+	}
+	return err.Error()
+}
+
+// pathSubject narrows the range of the path to the character of the syntax error,
+// when the path is written as a quoted string without escapes and templates.
+func pathSubject(subject *hcl.Range, value string, err error) *hcl.Range {
+	var syntaxErr *jsonpath.SyntaxError
+	if subject == nil || !errors.As(err, &syntaxErr) {
+		return subject
+	}
+	if subject.Start.Line != subject.End.Line || subject.End.Byte-subject.Start.Byte != len(value)+2 {
+		return subject
+	}
+	start := subject.Start
+	start.Byte += 1 + syntaxErr.Offset
+	start.Column += 1 + utf8.RuneCountInString(value[:syntaxErr.Offset])
+	end := start
+	end.Byte++
+	end.Column++
+	return &hcl.Range{Filename: subject.Filename, Start: start, End: end}
+}
@@ -0,0 +1,160 @@
+package aslconv_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePaths(t *testing.T) {
+	cases := []struct {
+		casename string
+		state    *aslconv.State
+		expected []string
+	}{
+		{
+			casename: "valid",
+			state: &aslconv.State{
+				Name:       "Pass",
+				Type:       "Pass",
+				InputPath:  ptr("$.items[?(@.price < 10)]"),
+				OutputPath: ptr("$['result']"),
+				ResultPath: ptr("$.detail.result"),
+				Parameters: aslconv.RawMessage(`{"ids.$": "$..id", "name.$": "$$.Execution.Name", "static": {"nested.$": "$.name"}}`),
+				End:        ptr(true),
+			},
+		},
+		{
+			casename: "invalid_input_path",
+			state: &aslconv.State{
+				Name:      "Pass",
+				Type:      "Pass",
+				InputPath: ptr("$.items["),
+				End:       ptr(true),
+			},
+			expected: []string{"Invalid path"},
+		},
+		{
+			casename: "result_path_not_reference",
+			state: &aslconv.State{
+				Name:       "Pass",
+				Type:       "Pass",
+				ResultPath: ptr("$.items[*]"),
+				End:        ptr(true),
+			},
+			expected: []string{"Invalid path"},
+		},
+		{
+			casename: "result_path_context_object",
+			state: &aslconv.State{
+				Name:       "Pass",
+				Type:       "Pass",
+				ResultPath: ptr("$$.Execution"),
+				End:        ptr(true),
+			},
+			expected: []string{"Invalid path"},
+		},
+		{
+			casename: "invalid_payload",
+			state: &aslconv.State{
+				Name:           "Task",
+				Type:           "Task",
+				Resource:       ptr("arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"),
+				Parameters:     aslconv.RawMessage(`{"name.$": "name", "list": [{"id.$": 1}]}`),
+				ResultSelector: aslconv.RawMessage(`{"id.$": "$.id", "format.$": "States.Format('{}', $.id)"}`),
+				Catch: aslconv.Catchers{
+					{ErrorEquals: []string{"States.ALL"}, ResultPath: ptr("$..error"), Next: "Task"},
+				},
+				End: ptr(true),
+			},
+			expected: []string{"Invalid path", "Invalid path", "Invalid path"},
+		},
+		{
+			casename: "invalid_intrinsic_functions",
+			state: &aslconv.State{
+				Name:         "Map",
+				Type:         "Map",
+				ItemSelector: aslconv.RawMessage(`{"id.$": "States.UUID(1)", "sum.$": "States.MathAdd($.a, 'b')", "valid.$": "States.Format('{}', States.ArrayLength($.items))"}`),
+				ItemProcessor: &aslconv.AmazonStatesLanguage{
+					StartAt: "Pass",
+					States:  aslconv.States{{Name: "Pass", Type: "Pass", End: ptr(true)}},
+				},
+				Parameters: aslconv.RawMessage(`{"unknown.$": "States.Unknown()"}`),
+				End:        ptr(true),
+			},
+			expected: []string{"Invalid intrinsic function", "Invalid intrinsic function", "Invalid intrinsic function"},
+		},
+		{
+			casename: "choice",
+			state: &aslconv.State{
+				Name: "Choice",
+				Type: "Choice",
+				Choices: aslconv.ChoiceRules{
+					{Variable: ptr("$.items[*]"), Operator: "IsPresent", Value: aslconv.RawMessage(`true`), Next: ptr("Choice")},
+					{
+						Not:  &aslconv.ChoiceRule{Variable: ptr("$.a"), Operator: "StringEqualsPath", Value: aslconv.RawMessage(`"$.b["`)},
+						Next: ptr("Choice"),
+					},
+				},
+				Default: ptr("Choice"),
+			},
+			expected: []string{"Invalid path", "Invalid path"},
+		},
+		{
+			casename: "jsonata",
+			state: &aslconv.State{
+				Name:          "Pass",
+				Type:          "Pass",
+				QueryLanguage: ptr("JSONata"),
+				Output:        aslconv.RawMessage(`{"name.$": "name"}`),
+				End:           ptr(true),
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			asl := &aslconv.AmazonStatesLanguage{
+				StartAt: c.state.Name,
+				States:  aslconv.States{c.state},
+			}
+			diags := asl.Validate()
+			for _, diag := range diags {
+				t.Log(diag.Error())
+			}
+			require.ElementsMatch(t, c.expected, diagnosticSummaries(diags))
+		})
+	}
+}
+
+func TestValidatePathsWithRange(t *testing.T) {
+	src := []byte(`
+start_at = state.pass.First
+
+state "pass" "First" {
+  input_path  = "$.items[?(@.price <)]"
+  result_path = "$.items[*]"
+  end         = true
+}
+`)
+	var diags hcl.Diagnostics
+	_, err := aslconv.FormatHCL.LoadASLWithBytes(src, "invalid.asl.hcl", func(opts *aslconv.LoadOptions) {
+		opts.Validate = true
+		opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+			return nil
+		}
+	})
+	require.ErrorAs(t, err, &diags)
+	require.Len(t, diags, 2)
+	for _, diag := range diags {
+		t.Log(diag.Error())
+	}
+	require.Equal(t, "Invalid path", diags[0].Summary)
+	require.Equal(t, hcl.Pos{Line: 5, Column: 37, Byte: 89}, diags[0].Subject.Start)
+	require.Equal(t, hcl.Pos{Line: 5, Column: 38, Byte: 90}, diags[0].Subject.End)
+	require.Equal(t, "Invalid path", diags[1].Summary)
+	require.Equal(t, 6, diags[1].Subject.Start.Line)
+	require.Equal(t, 25, diags[1].Subject.Start.Column)
+}
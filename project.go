@@ -0,0 +1,307 @@
+package aslconv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ProjectFileSuffixes are the suffixes of the files loaded from a project directory.
+var ProjectFileSuffixes = []string{".asl.hcl", ".asl.hcl.json"}
+
+// ProjectFiles returns the *.asl.hcl and *.asl.hcl.json files in the directory, sorted by name.
+// The subdirectories, such as the module directories, are not included.
+func ProjectFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var filenames []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, suffix := range ProjectFileSuffixes {
+			if strings.HasSuffix(entry.Name(), suffix) {
+				filenames = append(filenames, filepath.Join(dir, entry.Name()))
+				break
+			}
+		}
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+// parseProjectDir parses the project files in the directory, and returns the merged body of the files.
+// subject is the range of the diagnostics for the directory itself, e.g. the source of a module block, or nil.
+func parseProjectDir(parser *hclparse.Parser, dir string, subject *hcl.Range) (hcl.Body, hcl.Diagnostics) {
+	filenames, err := ProjectFiles(dir)
+	if err != nil {
+		return nil, hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to read directory",
+			Detail:   err.Error(),
+			Subject:  subject,
+		}}
+	}
+	if len(filenames) == 0 {
+		return nil, hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "No configuration files",
+			Detail:   fmt.Sprintf("The directory %s has no *.asl.hcl or *.asl.hcl.json files.", dir),
+			Subject:  subject,
+		}}
+	}
+	return parseProjectFiles(parser, filenames)
+}
+
+// parseProjectFiles parses the files in order, and returns the merged body of the files.
+// The blocks of the merged body are in the order of the files, so the states are in the order of the files too.
+func parseProjectFiles(parser *hclparse.Parser, filenames []string) (hcl.Body, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	files := make([]*hcl.File, 0, len(filenames))
+	for _, filename := range filenames {
+		var file *hcl.File
+		var parseDiags hcl.Diagnostics
+		if strings.HasSuffix(filename, ".json") {
+			file, parseDiags = parser.ParseJSONFile(filename)
+		} else {
+			file, parseDiags = parser.ParseHCLFile(filename)
+		}
+		diags = append(diags, parseDiags...)
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+	return hcl.MergeFiles(files), diags
+}
+
+// loadASLWithDir loads the project directory, all of the project files are merged as one state machine.
+func loadASLWithDir(dir string, opts *LoadOptions) (*AmazonStatesLanguage, error) {
+	parser := hclparse.NewParser()
+	body, diags := parseProjectDir(parser, dir, nil)
+	if diags.HasErrors() {
+		return nil, convertDiagnosticsToError(diags, parser, opts)
+	}
+	asl, loadDiags := loadASLWithBody(body, dir, opts)
+	diags = append(diags, loadDiags...)
+	if diags.HasErrors() {
+		return nil, convertDiagnosticsToError(diags, parser, opts)
+	}
+	return asl, convertDiagnosticsToError(diags, parser, opts)
+}
+
+var stateMachineBlockSchema = hcl.BlockHeaderSchema{
+	Type:       "state_machine",
+	LabelNames: []string{"name"},
+}
+
+// projectBodySchema is the schema of the top level of the project that declares state_machine blocks.
+// The state machines share the input variables, the locals and the modules of the top level.
+var projectBodySchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		stateMachineBlockSchema,
+		variableBlockSchema,
+		moduleBlockSchema,
+		{Type: "locals"},
+	},
+}
+
+// LoadProject loads the state machines of the file or the project directory, keyed by the state machine names.
+//
+//	locals {
+//	  function = lambda_arn("process", "us-east-1", "123456789012")
+//	}
+//
+//	state_machine "orders" {
+//	  start_at = state.task.Process
+//
+//	  state "task" "Process" {
+//	    resource = local.function
+//	    end      = true
+//	  }
+//	}
+//
+// The module blocks at the top level are shared by the state machines, that refer them as module.<name>.
+// The file without state_machine blocks is a state machine itself, that is named by the file or directory name without the extensions,
+// e.g. `orders` for orders.asl.hcl. JSON files are loaded in the same way.
+func LoadProject(path string, optFns ...func(*LoadOptions)) (map[string]*AmazonStatesLanguage, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	opts := newLoadOptions().apply(optFns...)
+	name := projectName(path)
+	if format != FormatHCL {
+		asl, err := format.LoadASLWithPath(path, optFns...)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]*AmazonStatesLanguage{name: asl}, nil
+	}
+	parser := hclparse.NewParser()
+	var body hcl.Body
+	var diags hcl.Diagnostics
+	baseDir := filepath.Dir(path)
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		baseDir = path
+		body, diags = parseProjectDir(parser, path, nil)
+	} else {
+		body, diags = parseProjectFiles(parser, []string{path})
+	}
+	if diags.HasErrors() {
+		return nil, convertDiagnosticsToError(diags, parser, opts)
+	}
+	machines, loadDiags := loadProjectWithBody(body, baseDir, name, opts)
+	diags = append(diags, loadDiags...)
+	if diags.HasErrors() {
+		return nil, convertDiagnosticsToError(diags, parser, opts)
+	}
+	return machines, convertDiagnosticsToError(diags, parser, opts)
+}
+
+// projectName returns the file or directory name without the extensions.
+func projectName(path string) string {
+	name := filepath.Base(path)
+	for _, ext := range []string{".asl.hcl.json", ".asl.hcl", ".asl.json", ".hcl.json", ".hcl", ".json"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+// loadProjectWithBody decodes the state_machine blocks of the body, or the body itself as the state machine named name.
+func loadProjectWithBody(body hcl.Body, baseDir string, name string, opts *LoadOptions) (map[string]*AmazonStatesLanguage, hcl.Diagnostics) {
+	ctx := evalContextWithFunctions(opts.HCLEvalContext, baseDir, opts)
+	ctx, diags := EvalContextWithVariables(body, ctx, opts.variables(body))
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	content, _, _ := body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{stateMachineBlockSchema},
+	})
+	if len(content.Blocks) == 0 {
+		asl := &AmazonStatesLanguage{}
+		diags = append(diags, asl.DecodeBody(body, ctx)...)
+		if opts.Validate && !diags.HasErrors() {
+			diags = append(diags, asl.Validate()...)
+		}
+		return map[string]*AmazonStatesLanguage{name: asl}, diags
+	}
+	content, contentDiags := body.Content(projectBodySchema)
+	diags = append(diags, contentDiags...)
+	if contentDiags.HasErrors() {
+		return nil, diags
+	}
+	var localBlocks, moduleBlocks hcl.Blocks
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "locals":
+			localBlocks = append(localBlocks, block)
+		case "module":
+			moduleBlocks = append(moduleBlocks, block)
+		}
+	}
+	ctx, localDiags := evalContextWithLocals(localBlocks, ctx)
+	diags = append(diags, localDiags...)
+	if localDiags.HasErrors() {
+		return nil, diags
+	}
+	machines := make(map[string]*AmazonStatesLanguage)
+	machineRange := make(map[string]*hcl.Range)
+	for _, block := range content.Blocks {
+		if block.Type != "state_machine" {
+			continue
+		}
+		machineName := block.Labels[0]
+		if r, ok := machineRange[machineName]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  `Duplicate "state_machine" name`,
+				Detail:   fmt.Sprintf(`A state machine named "%s" was already declared at %s. State machine names must unique`, machineName, r.String()),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		machineRange[machineName] = block.DefRange.Ptr()
+		machineContent, _, _ := block.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{variableBlockSchema},
+		})
+		for _, nested := range machineContent.Blocks {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  `Unexpected "variable" block`,
+				Detail:   "Input variables can be declared only at the top level.",
+				Subject:  nested.DefRange.Ptr(),
+			})
+		}
+		if len(machineContent.Blocks) > 0 {
+			continue
+		}
+		asl := &AmazonStatesLanguage{sharedModules: moduleBlocks}
+		machineDiags := asl.DecodeBody(block.Body, ctx)
+		if opts.Validate && !machineDiags.HasErrors() {
+			machineDiags = append(machineDiags, asl.Validate()...)
+		}
+		diags = append(diags, machineDiags...)
+		machines[machineName] = asl
+	}
+	return machines, diags
+}
+
+// evalContextWithLocals returns the child context of ctx, that has the locals shared by the state machines.
+// The locals of a state_machine block are merged into them, and override the shared locals of the same name.
+func evalContextWithLocals(blocks hcl.Blocks, ctx *hcl.EvalContext) (*hcl.EvalContext, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	attrs := make(map[string]*hcl.Attribute)
+	var names []string
+	for _, block := range blocks {
+		blockAttrs, attrDiags := block.Body.JustAttributes()
+		diags = append(diags, attrDiags...)
+		for name, attr := range blockAttrs {
+			if prev, ok := attrs[name]; ok {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate local value",
+					Detail:   fmt.Sprintf(`A local value named "%s" was already declared at %s. Local value names must unique`, name, prev.NameRange.String()),
+					Subject:  attr.NameRange.Ptr(),
+				})
+				continue
+			}
+			attrs[name] = attr
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	locals := make(map[string]cty.Value, len(attrs))
+	for _, name := range names {
+		locals[name] = cty.DynamicVal
+	}
+	child := ctx.NewChild()
+	child.Variables = map[string]cty.Value{
+		"local": cty.ObjectVal(locals),
+	}
+	// the locals may refer to each other, each iteration resolves at least one level of the references
+	for i := 0; i <= len(names) && !cty.ObjectVal(locals).IsWhollyKnown(); i++ {
+		next := make(map[string]cty.Value, len(names))
+		for _, name := range names {
+			next[name], _ = attrs[name].Expr.Value(child)
+		}
+		locals = next
+		child.Variables = map[string]cty.Value{
+			"local": cty.ObjectVal(locals),
+		}
+	}
+	for _, name := range names {
+		_, valueDiags := attrs[name].Expr.Value(child)
+		diags = append(diags, valueDiags...)
+	}
+	return child, diags
+}
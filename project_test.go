@@ -0,0 +1,211 @@
+package aslconv_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectFiles(t *testing.T) {
+	filenames, err := aslconv.ProjectFiles("testdata/project")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"testdata/project/end.asl.hcl.json",
+		"testdata/project/main.asl.hcl",
+		"testdata/project/process.asl.hcl",
+	}, filenames)
+}
+
+func TestLoadASLWithProjectDir(t *testing.T) {
+	expected := loadASL(t, "testdata/project.asl.json")
+	actual, err := aslconv.LoadASLWithPath("testdata/project", func(opts *aslconv.LoadOptions) {
+		opts.Validate = true
+	})
+	require.NoError(t, err)
+	requireASLEq(t, expected, actual)
+}
+
+func TestLoadASLWithProjectDirErrors(t *testing.T) {
+	silent := func(opts *aslconv.LoadOptions) {
+		opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+			return nil
+		}
+	}
+	t.Run("duplicate", func(t *testing.T) {
+		_, err := aslconv.LoadASLWithPath("testdata/project_duplicate", silent)
+		var diags hcl.Diagnostics
+		require.ErrorAs(t, err, &diags)
+		summaries := diagnosticSummaries(diags)
+		require.Contains(t, summaries, `Duplicate "state" name`)
+		require.Contains(t, summaries, "Duplicate local value")
+		for _, diag := range diags {
+			require.Equal(t, filepath.Join("testdata", "project_duplicate", "b.asl.hcl"), diag.Subject.Filename, diag.Summary)
+			require.Contains(t, diag.Detail, filepath.Join("testdata", "project_duplicate", "a.asl.hcl"), diag.Summary)
+		}
+	})
+	t.Run("no_files", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.hcl"), []byte(`start_at = "Done"`), 0o644))
+		_, err := aslconv.LoadASLWithPath(dir, silent)
+		var diags hcl.Diagnostics
+		require.ErrorAs(t, err, &diags)
+		require.Equal(t, []string{"No configuration files"}, diagnosticSummaries(diags))
+	})
+}
+
+func TestLoadProject(t *testing.T) {
+	t.Run("state_machines", func(t *testing.T) {
+		machines, err := aslconv.LoadProject("testdata/state_machines", func(opts *aslconv.LoadOptions) {
+			opts.Validate = true
+		})
+		require.NoError(t, err)
+		require.Len(t, machines, 2)
+		for _, name := range []string{"orders", "refunds"} {
+			require.Contains(t, machines, name)
+			requireASLEq(t, loadASL(t, "testdata/state_machines/"+name+".asl.json"), machines[name])
+		}
+	})
+	t.Run("shared_modules", func(t *testing.T) {
+		machines, err := aslconv.LoadProject("testdata/shared_modules", func(opts *aslconv.LoadOptions) {
+			opts.Validate = true
+		})
+		require.NoError(t, err)
+		require.Len(t, machines, 2)
+		for _, name := range []string{"orders", "refunds"} {
+			require.Contains(t, machines, name)
+			requireASLEq(t, loadASL(t, "testdata/shared_modules/"+name+".asl.json"), machines[name])
+		}
+	})
+	t.Run("single_file", func(t *testing.T) {
+		machines, err := aslconv.LoadProject("testdata/sample.asl.hcl")
+		require.NoError(t, err)
+		require.Len(t, machines, 1)
+		requireASLEq(t, loadASL(t, "testdata/sample.asl.json"), machines["sample"])
+	})
+	t.Run("json_file", func(t *testing.T) {
+		machines, err := aslconv.LoadProject("testdata/sample.asl.json")
+		require.NoError(t, err)
+		require.Len(t, machines, 1)
+		require.Contains(t, machines, "sample")
+	})
+}
+
+func TestLoadProjectErrors(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	cases := []struct {
+		casename string
+		source   string
+		expected string
+	}{
+		{
+			casename: "duplicate_state_machine",
+			source: `
+state_machine "orders" {
+  start_at = state.succeed.Done
+  state "succeed" "Done" {}
+}
+
+state_machine "orders" {
+  start_at = state.succeed.Done
+  state "succeed" "Done" {}
+}
+`,
+			expected: `Duplicate "state_machine" name`,
+		},
+		{
+			casename: "variable_in_state_machine",
+			source: `
+state_machine "orders" {
+  start_at = state.succeed.Done
+
+  variable "name" {}
+
+  state "succeed" "Done" {}
+}
+`,
+			expected: `Unexpected "variable" block`,
+		},
+		{
+			casename: "state_outside_of_state_machine",
+			source: `
+state_machine "orders" {
+  start_at = state.succeed.Done
+  state "succeed" "Done" {}
+}
+
+state "succeed" "Done" {}
+`,
+			expected: "Unsupported block type",
+		},
+		{
+			casename: "duplicate_local",
+			source: `
+locals {
+  prefix = "a"
+}
+
+locals {
+  prefix = "b"
+}
+
+state_machine "orders" {
+  start_at = state.succeed.Done
+  state "succeed" "Done" {}
+}
+`,
+			expected: "Duplicate local value",
+		},
+		{
+			casename: "duplicate_shared_module",
+			source: `
+module "notify" {
+  source  = "` + filepath.Join(wd, "testdata/modules/notify") + `"
+  channel = "#a"
+}
+
+state_machine "orders" {
+  start_at = state.succeed.Done
+
+  module "notify" {
+    source  = "` + filepath.Join(wd, "testdata/modules/notify") + `"
+    channel = "#b"
+  }
+
+  state "succeed" "Done" {}
+}
+`,
+			expected: `Duplicate "module" name`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), c.casename+".asl.hcl")
+			require.NoError(t, os.WriteFile(path, []byte(c.source), 0o644))
+			_, err := aslconv.LoadProject(path, func(opts *aslconv.LoadOptions) {
+				opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+					return nil
+				}
+			})
+			var diags hcl.Diagnostics
+			require.ErrorAs(t, err, &diags)
+			require.Contains(t, diagnosticSummaries(diags), c.expected)
+		})
+	}
+}
+
+func TestLoadASLWithMultipleStateMachines(t *testing.T) {
+	_, err := aslconv.LoadASLWithPath("testdata/state_machines", func(opts *aslconv.LoadOptions) {
+		opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+			return nil
+		}
+	})
+	var diags hcl.Diagnostics
+	require.ErrorAs(t, err, &diags)
+	require.Equal(t, []string{"Multiple state machines"}, diagnosticSummaries(diags))
+}
@@ -0,0 +1,249 @@
+package aslconv
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// https://states-language.net/spec.html#retrying-after-error
+type Retrier struct {
+	ErrorEquals     []string `json:"ErrorEquals" hcl:"error_equals"`
+	IntervalSeconds *int64   `json:"IntervalSeconds,omitempty" hcl:"interval_seconds"`
+	MaxAttempts     *int64   `json:"MaxAttempts,omitempty" hcl:"max_attempts"`
+	BackoffRate     *float64 `json:"BackoffRate,omitempty" hcl:"backoff_rate"`
+	MaxDelaySeconds *int64   `json:"MaxDelaySeconds,omitempty" hcl:"max_delay_seconds"`
+	JitterStrategy  *string  `json:"JitterStrategy,omitempty" hcl:"jitter_strategy"`
+	// Extra holds the unknown fields of the retrier.
+	Extra map[string]RawMessage `json:"-" hcl:"extra,optional"`
+
+	ranges sourceRanges
+}
+
+type Retriers []*Retrier
+
+// https://states-language.net/spec.html#fallback-states
+type Catcher struct {
+	ErrorEquals []string   `json:"ErrorEquals" hcl:"error_equals"`
+	Next        string     `json:"Next" hcl:"next"`
+	ResultPath  *string    `json:"ResultPath,omitempty" hcl:"result_path"`
+	Output      RawMessage `json:"Output,omitempty" hcl:"output,optional"`
+	Assign      RawMessage `json:"Assign,omitempty" hcl:"assign,optional"`
+	// Extra holds the unknown fields of the catcher.
+	Extra map[string]RawMessage `json:"-" hcl:"extra,optional"`
+
+	ranges sourceRanges
+}
+
+type Catchers []*Catcher
+
+func (retrier *Retrier) MarshalJSON() ([]byte, error) {
+	type alias Retrier
+	bs, err := json.Marshal((*alias)(retrier))
+	if err != nil {
+		return nil, err
+	}
+	return marshalExtra(bs, retrier.Extra)
+}
+
+func (retrier *Retrier) UnmarshalJSON(bs []byte) error {
+	type alias Retrier
+	if err := json.Unmarshal(bs, (*alias)(retrier)); err != nil {
+		return err
+	}
+	extra, err := unmarshalExtra(bs, retrier)
+	if err != nil {
+		return err
+	}
+	retrier.Extra = extra
+	return nil
+}
+
+func (catcher *Catcher) MarshalJSON() ([]byte, error) {
+	type alias Catcher
+	bs, err := json.Marshal((*alias)(catcher))
+	if err != nil {
+		return nil, err
+	}
+	return marshalExtra(bs, catcher.Extra)
+}
+
+func (catcher *Catcher) UnmarshalJSON(bs []byte) error {
+	type alias Catcher
+	if err := json.Unmarshal(bs, (*alias)(catcher)); err != nil {
+		return err
+	}
+	extra, err := unmarshalExtra(bs, catcher)
+	if err != nil {
+		return err
+	}
+	catcher.Extra = extra
+	return nil
+}
+
+func (retrier *Retrier) decodeBody(block *hcl.Block, ctx *hcl.EvalContext) hcl.Diagnostics {
+	retrier.ranges = sourceRanges{"": block.DefRange.Ptr()}
+	diags := decodeBody(block.Body, ctx, retrier)
+	if attrs, attrDiags := block.Body.JustAttributes(); !attrDiags.HasErrors() {
+		for _, name := range []string{"error_equals", "interval_seconds", "max_attempts", "backoff_rate", "max_delay_seconds", "jitter_strategy"} {
+			if attr, ok := attrs[name]; ok {
+				retrier.ranges[name] = attr.Expr.Range().Ptr()
+			}
+		}
+	}
+	return diags
+}
+
+func (catcher *Catcher) decodeBody(block *hcl.Block, ctx *hcl.EvalContext) hcl.Diagnostics {
+	catcher.ranges = sourceRanges{"": block.DefRange.Ptr()}
+	diags := decodeBody(block.Body, ctx, catcher)
+	if attrs, attrDiags := block.Body.JustAttributes(); !attrDiags.HasErrors() {
+		for _, name := range []string{"next", "result_path"} {
+			if attr, ok := attrs[name]; ok {
+				catcher.ranges[name] = attr.Expr.Range().Ptr()
+			}
+		}
+	}
+	return diags
+}
+
+func (retriers *Retriers) decodeExpression(expr hcl.Expression, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var raws RawMessages
+	diags := decodeExpression(expr, ctx, &raws)
+	if diags.HasErrors() {
+		return diags
+	}
+	for i, raw := range raws {
+		var retrier Retrier
+		if err := json.Unmarshal(raw, &retrier); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid retrier",
+				Detail:   fmt.Sprintf(`retry[%d]:%s`, i, err),
+				Subject:  expr.Range().Ptr(),
+			})
+			continue
+		}
+		retrier.ranges = sourceRanges{"": expr.Range().Ptr()}
+		*retriers = append(*retriers, &retrier)
+	}
+	return diags
+}
+
+func (catchers *Catchers) decodeExpression(expr hcl.Expression, ctx *hcl.EvalContext) hcl.Diagnostics {
+	var raws RawMessages
+	diags := decodeExpression(expr, ctx, &raws)
+	if diags.HasErrors() {
+		return diags
+	}
+	for i, raw := range raws {
+		var catcher Catcher
+		if err := json.Unmarshal(raw, &catcher); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid catcher",
+				Detail:   fmt.Sprintf(`catch[%d]:%s`, i, err),
+				Subject:  expr.Range().Ptr(),
+			})
+			continue
+		}
+		catcher.ranges = sourceRanges{"": expr.Range().Ptr()}
+		*catchers = append(*catchers, &catcher)
+	}
+	return diags
+}
+
+func (retrier *Retrier) EncodeAsBlock() (*hclwrite.Block, error) {
+	return encodeAsBlock(retrier, "retry")
+}
+
+func (catcher *Catcher) EncodeAsBlock(states States) (*hclwrite.Block, error) {
+	block, err := encodeAsBlock(catcher, "catch")
+	if err != nil {
+		return nil, err
+	}
+	traversal, err := states.getTraversal(catcher.Next)
+	if err != nil {
+		return nil, err
+	}
+	block.Body().SetAttributeTraversal("next", traversal)
+	return block, nil
+}
+
+func (retrier *Retrier) validate(path string, isLast bool) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	diags = append(diags, validateErrorEquals(path, retrier.ErrorEquals, isLast, retrier.ranges.get("error_equals"))...)
+	if retrier.IntervalSeconds != nil && *retrier.IntervalSeconds < 1 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid IntervalSeconds",
+			Detail:   fmt.Sprintf(`%s.IntervalSeconds must be a positive integer.`, path),
+			Subject:  retrier.ranges.get("interval_seconds"),
+		})
+	}
+	if retrier.MaxAttempts != nil && *retrier.MaxAttempts < 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid MaxAttempts",
+			Detail:   fmt.Sprintf(`%s.MaxAttempts must be a non-negative integer.`, path),
+			Subject:  retrier.ranges.get("max_attempts"),
+		})
+	}
+	if retrier.BackoffRate != nil && *retrier.BackoffRate < 1.0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid BackoffRate",
+			Detail:   fmt.Sprintf(`%s.BackoffRate must be greater than or equal to 1.0.`, path),
+			Subject:  retrier.ranges.get("backoff_rate"),
+		})
+	}
+	if retrier.MaxDelaySeconds != nil && *retrier.MaxDelaySeconds < 1 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid MaxDelaySeconds",
+			Detail:   fmt.Sprintf(`%s.MaxDelaySeconds must be a positive integer.`, path),
+			Subject:  retrier.ranges.get("max_delay_seconds"),
+		})
+	}
+	if retrier.JitterStrategy != nil && *retrier.JitterStrategy != "FULL" && *retrier.JitterStrategy != "NONE" {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid JitterStrategy",
+			Detail:   fmt.Sprintf(`%s.JitterStrategy must be "FULL" or "NONE", but got "%s".`, path, *retrier.JitterStrategy),
+			Subject:  retrier.ranges.get("jitter_strategy"),
+		})
+	}
+	return diags
+}
+
+func (catcher *Catcher) validate(path string, isLast bool) hcl.Diagnostics {
+	return validateErrorEquals(path, catcher.ErrorEquals, isLast, catcher.ranges.get("error_equals"))
+}
+
+func validateErrorEquals(path string, errorEquals []string, isLast bool, subject *hcl.Range) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if len(errorEquals) == 0 {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing ErrorEquals",
+			Detail:   fmt.Sprintf(`%s.ErrorEquals must be a non-empty array of error names.`, path),
+			Subject:  subject,
+		})
+	}
+	for _, name := range errorEquals {
+		if name != "States.ALL" {
+			continue
+		}
+		if len(errorEquals) != 1 || !isLast {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid ErrorEquals",
+				Detail:   fmt.Sprintf(`%s.ErrorEquals: "States.ALL" must appear alone, and in the last element of the array.`, path),
+				Subject:  subject,
+			})
+		}
+	}
+	return diags
+}
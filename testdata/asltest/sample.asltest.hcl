@@ -0,0 +1,69 @@
+mock_config = "MockConfigFile.json"
+
+test "first match" {
+  input = {
+    foo = 0
+  }
+
+  mock "task.FirstState" {
+    return = {
+      foo = 1
+    }
+  }
+
+  mock "task.FirstMatchState" {
+    return = {
+      matched = "first"
+    }
+  }
+
+  mock "task.NextState" {
+    return = {
+      result = "done"
+    }
+  }
+
+  expect {
+    path   = ["FirstState", "ChoiceState", "FirstMatchState", "NextState"]
+    output = {
+      result = "done"
+    }
+  }
+}
+
+test "default" {
+  mock "task.FirstState" {
+    return = {
+      foo = 3
+    }
+  }
+
+  expect {
+    path  = ["FirstState", "ChoiceState", "DefaultState"]
+    error = "DefaultStateError"
+    cause = "No Matches!"
+  }
+}
+
+test "task failed" {
+  mock "FirstState" {
+    error = "States.TaskFailed"
+    cause = "boom"
+  }
+
+  expect {
+    path  = ["FirstState"]
+    error = "States.TaskFailed"
+  }
+}
+
+test "second match from mock config" {
+  test_case = "SecondMatch"
+
+  expect {
+    path = ["FirstState", "ChoiceState", "SecondMatchState", "NextState"]
+    output = {
+      result = "second"
+    }
+  }
+}
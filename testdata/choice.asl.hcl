@@ -0,0 +1,47 @@
+comment  = "An example of the Amazon States Language using boolean expressions in choice rules."
+start_at = state.choice.ChoiceState
+
+state "choice" "ChoiceState" {
+  default = state.succeed.Recorded
+
+  choice {
+    next = state.pass.Public
+
+    and {
+      variable   = "$.value"
+      is_present = true
+    }
+
+    and {
+      variable                         = "$.value"
+      numeric_greater_than_equals_path = "$.threshold"
+    }
+  }
+
+  choice {
+    next = state.pass.Private
+
+    or {
+      variable      = "$.type"
+      string_equals = "Private"
+    }
+
+    or {
+      not {
+        variable            = "$.created_at"
+        timestamp_less_than = "2022-01-01T00:00:00Z"
+      }
+    }
+  }
+}
+
+state "pass" "Public" {
+  next = state.succeed.Recorded
+}
+
+state "pass" "Private" {
+  next = state.succeed.Recorded
+}
+
+state "succeed" "Recorded" {
+}
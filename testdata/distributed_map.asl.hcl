@@ -0,0 +1,55 @@
+comment  = "An example of the Amazon States Language using a Distributed Map state."
+start_at = state.map.Map
+
+state "map" "Map" {
+  max_concurrency              = 1000
+  tolerated_failure_percentage = 5
+  label                        = "ProcessObjects"
+  item_selector = {
+    "index.$" = "$$.Map.Item.Index"
+    "value.$" = "$$.Map.Item.Value"
+  }
+  end = true
+
+  item_reader {
+    resource = "arn:aws:states:::s3:getObject"
+    parameters = {
+      Bucket  = "example-bucket"
+      "Key.$" = "$.key"
+    }
+    reader_config {
+      input_type          = "CSV"
+      csv_header_location = "FIRST_ROW"
+      max_items           = 100
+    }
+  }
+
+  item_batcher {
+    max_items_per_batch = 10
+    batch_input = {
+      "execution.$" = "$$.Execution.Id"
+    }
+  }
+
+  result_writer {
+    resource = "arn:aws:states:::s3:putObject"
+    parameters = {
+      Bucket = "example-bucket"
+      Prefix = "results"
+    }
+  }
+
+  item_processor {
+    start_at = state.task.Process
+
+    processor_config {
+      mode           = "DISTRIBUTED"
+      execution_type = "EXPRESS"
+    }
+
+    state "task" "Process" {
+      resource = "arn:aws:states:::lambda:invoke"
+      end      = true
+    }
+  }
+}
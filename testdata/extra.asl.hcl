@@ -0,0 +1,55 @@
+comment  = "An example of the Amazon States Language with unknown fields."
+start_at = state.pass.Pass
+extra = {
+  Annotations = {
+    owner = "team-a"
+    tags  = ["example", "extra"]
+  }
+}
+
+state "pass" "Pass" {
+  next   = state.choice.Choice
+  result = "hello"
+  extra = {
+    Metadata = {
+      "Description.$" = "$.description"
+    }
+    Priority = 10
+  }
+}
+
+state "choice" "Choice" {
+  default = state.succeed.Done
+
+  choice {
+    variable      = "$.x"
+    string_equals = "a"
+    next          = state.task.Call
+    extra = {
+      NewField = 1
+    }
+  }
+}
+
+state "task" "Call" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+  next     = state.succeed.Done
+
+  retry {
+    error_equals = ["X"]
+    extra = {
+      NewField = 1
+    }
+  }
+
+  catch {
+    error_equals = ["States.ALL"]
+    next         = state.succeed.Done
+    extra = {
+      NewField = 2
+    }
+  }
+}
+
+state "succeed" "Done" {
+}
@@ -0,0 +1,47 @@
+comment  = "An example of the states generated by for_each and dynamic blocks."
+start_at = state.parallel.FanOut
+
+locals {
+  regions = ["us-east-1", "eu-west-1"]
+  tenants = {
+    alpha = "arn:aws:lambda:us-east-1:123456789012:function:notify-alpha"
+    beta  = "arn:aws:lambda:us-east-1:123456789012:function:notify-beta"
+  }
+}
+
+state "parallel" "FanOut" {
+  next = state.choice.Route
+
+  dynamic "branch" {
+    for_each = local.regions
+    content {
+      start_at = state.task.Process
+
+      state "task" "Process" {
+        resource = "arn:aws:lambda:${branch.value}:123456789012:function:process"
+        end      = true
+      }
+    }
+  }
+}
+
+state "choice" "Route" {
+  default = state.succeed.Done
+
+  dynamic "choice" {
+    for_each = local.tenants
+    content {
+      variable      = "$.tenant"
+      string_equals = choice.key
+      next          = state.task["Notify_${choice.key}"]
+    }
+  }
+}
+
+state "succeed" "Done" {}
+
+state "task" "Notify_$${each.key}" {
+  for_each = local.tenants
+  resource = each.value
+  next     = state.succeed.Done
+}
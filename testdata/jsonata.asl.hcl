@@ -0,0 +1,44 @@
+comment        = "An example of the Amazon States Language using JSONata."
+query_language = "JSONata"
+start_at       = state.task.Invoke
+
+state "task" "Invoke" {
+  resource = "arn:aws:states:::lambda:invoke"
+  next     = state.choice.Check
+  arguments = {
+    FunctionName = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+    Payload      = "{% $states.input %}"
+  }
+  output = "{% $states.result.Payload %}"
+  assign = {
+    count = "{% $states.result.Payload.count %}"
+  }
+
+  catch {
+    error_equals = ["States.ALL"]
+    next         = state.fail.Failed
+    output = {
+      error = "{% $states.errorOutput.Error %}"
+    }
+  }
+}
+
+state "choice" "Check" {
+  default = state.task.Invoke
+
+  choice {
+    condition = "{% $count > 10 %}"
+    output = {
+      count = "{% $count %}"
+    }
+    next = state.succeed.Done
+  }
+}
+
+state "succeed" "Done" {
+  output = "{% $count %}"
+}
+
+state "fail" "Failed" {
+  error = "InvokeFailed"
+}
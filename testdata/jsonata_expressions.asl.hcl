@@ -0,0 +1,42 @@
+comment         = "JSONata expressions in the numeric and timestamp fields."
+query_language  = "JSONata"
+timeout_seconds = "{% $states.input.timeout %}"
+start_at        = state.task.Invoke
+
+state "task" "Invoke" {
+  resource          = "arn:aws:states:::lambda:invoke"
+  timeout_seconds   = "{% $states.input.taskTimeout %}"
+  heartbeat_seconds = "{% $states.input.heartbeat %}"
+  next              = state.wait.WaitSeconds
+}
+
+state "wait" "WaitSeconds" {
+  seconds = "{% $states.input.n %}"
+  next    = state.wait.WaitUntil
+}
+
+state "wait" "WaitUntil" {
+  timestamp = "{% $states.input.until %}"
+  next      = state.map.Process
+}
+
+state "map" "Process" {
+  max_concurrency              = "{% $states.input.concurrency %}"
+  tolerated_failure_count      = "{% $states.input.failures %}"
+  tolerated_failure_percentage = "{% $states.input.percentage %}"
+  items                        = "{% $states.input.items %}"
+  end                          = true
+
+  item_processor {
+    start_at = state.pass.Item
+
+    processor_config {
+      mode           = "DISTRIBUTED"
+      execution_type = "STANDARD"
+    }
+
+    state "pass" "Item" {
+      end = true
+    }
+  }
+}
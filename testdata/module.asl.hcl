@@ -0,0 +1,28 @@
+comment  = "An example of the modules."
+start_at = state.task.Process
+
+state "task" "Process" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:process"
+  next     = module.notify.start
+
+  catch {
+    error_equals = ["States.ALL"]
+    next         = module.notify_failure.start
+  }
+}
+
+module "notify" {
+  source  = "./modules/notify"
+  next    = state.succeed.Done
+  channel = "#success"
+}
+
+module "notify_failure" {
+  source  = "./modules/notify"
+  next    = state.fail.Failed
+  channel = "#failure"
+}
+
+state "succeed" "Done" {}
+
+state "fail" "Failed" {}
@@ -0,0 +1,5 @@
+start_at = module.self.start
+
+module "self" {
+  source = "."
+}
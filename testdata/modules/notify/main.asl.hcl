@@ -0,0 +1,32 @@
+comment  = "Notifies the result to Slack, with retries."
+start_at = state.task.SendSlack
+
+variable "channel" {
+  type = string
+}
+
+state "task" "SendSlack" {
+  resource = "arn:aws:states:::lambda:invoke"
+  end      = true
+  parameters = jsonencode({
+    FunctionName = "arn:aws:lambda:us-east-1:123456789012:function:notify-slack"
+    Payload = {
+      channel    = var.channel
+      "result.$" = "$"
+    }
+  })
+
+  retry {
+    error_equals = ["States.ALL"]
+    max_attempts = 3
+  }
+
+  catch {
+    error_equals = ["States.ALL"]
+    next         = state.pass.Ignore
+  }
+}
+
+state "pass" "Ignore" {
+  end = true
+}
@@ -12,13 +12,32 @@ state "map" "Validate-All" {
     start_at = state.task.Validate
 
     state "task" "Validate" {
-      resource        = "arn:aws:lambda:us-east-1:123456789012:function:ship-val"
-      next            = state.wait.Wait
-      output_path     = "$.items"
-      retry           = ["{\"ErrorEquals\":[\"ErrorA\",\"ErrorB\"],\"IntervalSeconds\":1,\"BackoffRate\":2,\"MaxAttempts\":2}", "{\"ErrorEquals\":[\"ErrorC\"],\"IntervalSeconds\":5}"]
-      catch           = ["{\"ErrorEquals\":[\"States.ALL\"],\"Next\":\"Wait\"}"]
-      parameters      = "{\"input.$\":\"$\"}"
-      result_selector = "{\"data.$\":\"$\"}"
+      resource    = "arn:aws:lambda:us-east-1:123456789012:function:ship-val"
+      next        = state.wait.Wait
+      output_path = "$.items"
+      parameters = {
+        "input.$" = "$"
+      }
+      result_selector = {
+        "data.$" = "$"
+      }
+
+      retry {
+        error_equals     = ["ErrorA", "ErrorB"]
+        interval_seconds = 1
+        max_attempts     = 2
+        backoff_rate     = 2
+      }
+
+      retry {
+        error_equals     = ["ErrorC"]
+        interval_seconds = 5
+      }
+
+      catch {
+        error_equals = ["States.ALL"]
+        next         = state.wait.Wait
+      }
     }
 
     state "wait" "Wait" {
@@ -29,7 +48,10 @@ state "map" "Validate-All" {
     state "pass" "Pass" {
       next        = state.succeed.Success
       result_path = "$.coords"
-      result      = "{\"x-datum\":0.381018,\"y-datum\":622.2269926397355}"
+      result = {
+        x-datum = 0.381018
+        y-datum = 622.2269926397355
+      }
     }
 
     state "succeed" "Success" {
@@ -0,0 +1,3 @@
+state "pass" "Ignored" {
+  end = true
+}
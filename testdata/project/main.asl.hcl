@@ -0,0 +1,11 @@
+comment  = "An example of the project directory."
+start_at = state.task.Process
+
+variable "account" {
+  type    = string
+  default = "123456789012"
+}
+
+locals {
+  region = "us-east-1"
+}
@@ -0,0 +1,17 @@
+locals {
+  function = lambda_arn("process", local.region, var.account)
+}
+
+state "task" "Process" {
+  resource = local.function
+  next     = state.choice.Check
+}
+
+state "choice" "Check" {
+  choice {
+    variable      = "$.status"
+    string_equals = "ok"
+    next          = state.succeed.Done
+  }
+  default = state.fail.Failed
+}
@@ -0,0 +1,12 @@
+start_at = state.pass.Start
+
+locals {
+  message = "a"
+}
+
+state "pass" "Start" {
+  result = jsonencode(local.message)
+  next   = state.succeed.Done
+}
+
+state "succeed" "Done" {}
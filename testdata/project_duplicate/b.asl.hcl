@@ -0,0 +1,5 @@
+locals {
+  message = "b"
+}
+
+state "succeed" "Done" {}
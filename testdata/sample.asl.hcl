@@ -8,7 +8,18 @@ state "task" "FirstState" {
 
 state "choice" "ChoiceState" {
   default = state.fail.DefaultState
-  choices = ["{\"Variable\":\"$.foo\",\"NumericEquals\":1,\"Next\":\"FirstMatchState\"}", "{\"Variable\":\"$.foo\",\"NumericEquals\":2,\"Next\":\"SecondMatchState\"}"]
+
+  choice {
+    variable       = "$.foo"
+    numeric_equals = 1
+    next           = state.task.FirstMatchState
+  }
+
+  choice {
+    variable       = "$.foo"
+    numeric_equals = 2
+    next           = state.task.SecondMatchState
+  }
 }
 
 state "task" "FirstMatchState" {
@@ -0,0 +1,22 @@
+module "notify" {
+  source  = "../modules/notify"
+  channel = "#orders"
+}
+
+state_machine "orders" {
+  start_at = state.task.Process
+
+  state "task" "Process" {
+    resource = "arn:aws:lambda:us-east-1:123456789012:function:process"
+    next     = module.notify.start
+  }
+}
+
+state_machine "refunds" {
+  start_at = state.task.Refund
+
+  state "task" "Refund" {
+    resource = "arn:aws:lambda:us-east-1:123456789012:function:refund"
+    next     = module.notify.start
+  }
+}
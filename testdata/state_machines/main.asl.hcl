@@ -0,0 +1,40 @@
+variable "account" {
+  type    = string
+  default = "123456789012"
+}
+
+locals {
+  region  = "us-east-1"
+  prefix  = "orders"
+  timeout = 300
+}
+
+state_machine "orders" {
+  comment         = "Processes an order."
+  start_at        = state.task.Process
+  timeout_seconds = local.timeout
+
+  state "task" "Process" {
+    resource = lambda_arn("${local.prefix}-process", local.region, var.account)
+    next     = module.notify.start
+  }
+
+  module "notify" {
+    source  = "../modules/notify"
+    channel = "#orders"
+  }
+}
+
+state_machine "refunds" {
+  comment  = "Refunds an order."
+  start_at = state.task.Refund
+
+  locals {
+    function = lambda_arn("${local.prefix}-refund", local.region, var.account)
+  }
+
+  state "task" "Refund" {
+    resource = local.function
+    end      = true
+  }
+}
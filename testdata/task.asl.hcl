@@ -0,0 +1,23 @@
+comment         = "An example of the Amazon States Language using task timeouts."
+timeout_seconds = 3600
+start_at        = state.task.Static
+
+state "task" "Static" {
+  resource          = "arn:aws:states:::lambda:invoke"
+  timeout_seconds   = 300
+  heartbeat_seconds = 60
+  credentials = {
+    RoleArn = "arn:aws:iam::123456789012:role/CrossAccountRole"
+  }
+  next = state.task.Dynamic
+}
+
+state "task" "Dynamic" {
+  resource               = "arn:aws:states:::lambda:invoke.waitForTaskToken"
+  timeout_seconds_path   = "$.timeout"
+  heartbeat_seconds_path = "$.heartbeat"
+  credentials = {
+    "RoleArn.$" = "$.roleArn"
+  }
+  end = true
+}
@@ -0,0 +1,36 @@
+comment  = "An example of the input variables."
+start_at = state.task.Invoke
+
+variable "env" {
+  description = "deployment stage"
+  type        = string
+  default     = "dev"
+
+  validation {
+    condition     = var.env == "dev" || var.env == "prod"
+    error_message = "The env must be dev or prod."
+  }
+}
+
+variable "max_attempts" {
+  type = number
+}
+
+variable "errors" {
+  type    = list(string)
+  default = ["States.ALL"]
+}
+
+locals {
+  function_arn = "arn:aws:lambda:us-east-1:123456789012:function:${var.env}-invoke"
+}
+
+state "task" "Invoke" {
+  resource = local.function_arn
+  end      = true
+
+  retry {
+    error_equals = var.errors
+    max_attempts = var.max_attempts
+  }
+}
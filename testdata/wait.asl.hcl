@@ -0,0 +1,22 @@
+comment  = "An example of the Amazon States Language using wait states."
+start_at = state.wait.WaitSeconds
+
+state "wait" "WaitSeconds" {
+  seconds = 10
+  next    = state.wait.WaitTimestamp
+}
+
+state "wait" "WaitTimestamp" {
+  timestamp = "2016-03-14T01:59:00Z"
+  next      = state.wait.WaitSecondsPath
+}
+
+state "wait" "WaitSecondsPath" {
+  seconds_path = "$.seconds"
+  next         = state.wait.WaitTimestampPath
+}
+
+state "wait" "WaitTimestampPath" {
+  timestamp_path = "$.expirydate"
+  end            = true
+}
@@ -0,0 +1,312 @@
+package aslconv
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Validate checks the structure of the state machine.
+// It reports a missing StartAt target, fields not allowed for the state type, transitions to undeclared states,
+// states without a valid Next or End, states that can not be reached from StartAt, and JSONPath-only fields in JSONata states (or vice versa).
+// Branches, Iterator and ItemProcessor are validated recursively.
+func (top *AmazonStatesLanguage) Validate() hcl.Diagnostics {
+	diags := top.validateNoProcessorConfig("")
+	return append(diags, top.validate("", QueryLanguageJSONPath)...)
+}
+
+// validateNoProcessorConfig reports ProcessorConfig, which is allowed only in ItemProcessor.
+func (top *AmazonStatesLanguage) validateNoProcessorConfig(path string) hcl.Diagnostics {
+	if top.ProcessorConfig == nil {
+		return nil
+	}
+	return hcl.Diagnostics{&hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Unsupported field",
+		Detail:   fmt.Sprintf(`%sProcessorConfig is allowed only in ItemProcessor of a Map state.`, path),
+		Subject:  top.ranges.get("processor_config"),
+	}}
+}
+
+func (top *AmazonStatesLanguage) validate(path string, queryLanguage string) hcl.Diagnostics {
+	queryLanguage, diags := resolveQueryLanguage(path, queryLanguage, top.QueryLanguage, top.ranges.get("query_language"))
+	states := make(map[string]*State, len(top.States))
+	for _, state := range top.States {
+		if _, ok := states[state.Name]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate state name",
+				Detail:   fmt.Sprintf(`%s is declared more than once. State names must unique`, state.path(path)),
+				Subject:  state.ranges.get(""),
+			})
+			continue
+		}
+		states[state.Name] = state
+	}
+	if top.StartAt == "" {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing StartAt",
+			Detail:   fmt.Sprintf(`%sStartAt is required.`, path),
+			Subject:  top.ranges.get(""),
+		})
+	} else if _, ok := states[top.StartAt]; !ok {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid StartAt",
+			Detail:   fmt.Sprintf(`%sStartAt refers to the state "%s", but it is not declared.`, path, top.StartAt),
+			Subject:  top.ranges.get("start_at"),
+		})
+	}
+	if top.TimeoutSeconds.IsExpression() && queryLanguage != QueryLanguageJSONata {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported JSONata expression",
+			Detail:   fmt.Sprintf(`%sTimeoutSeconds can be a JSONata expression only in a JSONata state machine.`, path),
+			Subject:  top.ranges.get("timeout_seconds"),
+		})
+	}
+	for _, state := range top.States {
+		diags = append(diags, state.validate(path, states, queryLanguage)...)
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+
+	reachable := map[string]bool{top.StartAt: true}
+	queue := []string{top.StartAt}
+	for len(queue) > 0 {
+		state := states[queue[0]]
+		queue = queue[1:]
+		for _, t := range state.transitions() {
+			if !reachable[t.next] {
+				reachable[t.next] = true
+				queue = append(queue, t.next)
+			}
+		}
+	}
+	for _, state := range top.States {
+		if !reachable[state.Name] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unreachable state",
+				Detail:   fmt.Sprintf(`%s can not be reached from StartAt "%s".`, state.path(path), top.StartAt),
+				Subject:  state.ranges.get(""),
+			})
+		}
+	}
+	return diags
+}
+
+func (state *State) path(parent string) string {
+	return fmt.Sprintf(`%sStates["%s"]`, parent, state.Name)
+}
+
+func (state *State) validate(parent string, states map[string]*State, queryLanguage string) hcl.Diagnostics {
+	path := state.path(parent)
+	diags := state.validateFields(path)
+	if diags.HasErrors() {
+		return diags
+	}
+	queryLanguage, qlDiags := resolveQueryLanguage(path+".", queryLanguage, state.QueryLanguage, state.ranges.get("query_language"))
+	diags = append(diags, qlDiags...)
+	diags = append(diags, state.validateQueryLanguage(path, queryLanguage)...)
+	if queryLanguage == QueryLanguageJSONPath {
+		diags = append(diags, state.validatePaths(path)...)
+	}
+	for _, t := range state.transitions() {
+		if _, ok := states[t.next]; !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid transition",
+				Detail:   fmt.Sprintf(`%s.%s refers to the state "%s", but it is not declared.`, path, t.field, t.next),
+				Subject:  t.subject,
+			})
+		}
+	}
+	switch state.Type {
+	case "Choice":
+		diags = append(diags, state.validateChoice(path)...)
+	case "Wait":
+		diags = append(diags, state.validateWait(path)...)
+	case "Task":
+		diags = append(diags, state.validateTask(path)...)
+	case "Map":
+		diags = append(diags, state.validateMap(path)...)
+	}
+	switch state.Type {
+	case "Choice", "Succeed", "Fail":
+	default:
+		hasNext := state.Next != nil
+		isEnd := state.End != nil && *state.End
+		if hasNext && isEnd {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Conflicting transition",
+				Detail:   fmt.Sprintf(`%s has both Next and End. Only one of them can be used.`, path),
+				Subject:  state.ranges.get("end"),
+			})
+		}
+		if !hasNext && !isEnd {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing transition",
+				Detail:   fmt.Sprintf(`%s has neither Next nor End. A %s state must have one of them.`, path, state.Type),
+				Subject:  state.ranges.get(""),
+			})
+		}
+	}
+	for i, retrier := range state.Retry {
+		diags = append(diags, retrier.validate(fmt.Sprintf("%s.Retry[%d]", path, i), i == len(state.Retry)-1)...)
+	}
+	for i, catcher := range state.Catch {
+		diags = append(diags, catcher.validate(fmt.Sprintf("%s.Catch[%d]", path, i), i == len(state.Catch)-1)...)
+	}
+	for i, branch := range state.Branches {
+		branchPath := fmt.Sprintf("%s.Branches[%d].", path, i)
+		diags = append(diags, branch.validateNoProcessorConfig(branchPath)...)
+		diags = append(diags, branch.validate(branchPath, queryLanguage)...)
+	}
+	if state.Iterator != nil {
+		diags = append(diags, state.Iterator.validateNoProcessorConfig(path+".Iterator.")...)
+		diags = append(diags, state.Iterator.validate(path+".Iterator.", queryLanguage)...)
+	}
+	if state.ItemProcessor != nil {
+		diags = append(diags, state.ItemProcessor.validate(path+".ItemProcessor.", queryLanguage)...)
+	}
+	return diags
+}
+
+func (state *State) validateChoice(path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if len(state.Choices) == 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing choices",
+			Detail:   fmt.Sprintf(`%s has no Choices. A Choice state must have at least one choice rule.`, path),
+			Subject:  state.ranges.get(""),
+		})
+	}
+	for i, rule := range state.Choices {
+		diags = append(diags, rule.validate(fmt.Sprintf("%s.Choices[%d]", path, i), true)...)
+	}
+	return diags
+}
+
+func (state *State) validateWait(path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	var count int
+	for _, isSet := range []bool{state.Seconds != nil, state.Timestamp != nil, state.SecondsPath != nil, state.TimestampPath != nil} {
+		if isSet {
+			count++
+		}
+	}
+	if count != 1 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid wait duration",
+			Detail:   fmt.Sprintf(`%s must have exactly one of Seconds, Timestamp, SecondsPath or TimestampPath.`, path),
+			Subject:  state.ranges.get(""),
+		})
+	}
+	if state.Seconds != nil && !state.Seconds.IsExpression() && state.Seconds.Value < 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid Seconds",
+			Detail:   fmt.Sprintf(`%s.Seconds must be a non-negative integer.`, path),
+			Subject:  state.ranges.get("seconds"),
+		})
+	}
+	if state.Timestamp != nil && !isJSONataExpression(*state.Timestamp) {
+		if _, err := time.Parse(time.RFC3339, *state.Timestamp); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid Timestamp",
+				Detail:   fmt.Sprintf(`%s.Timestamp must be a RFC3339 timestamp, e.g. "2016-03-14T01:59:00Z": %s`, path, err),
+				Subject:  state.ranges.get("timestamp"),
+			})
+		}
+	}
+	return diags
+}
+
+func (state *State) validateTask(path string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if state.Resource == nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing Resource",
+			Detail:   fmt.Sprintf(`%s has no Resource. A Task state must have Resource.`, path),
+			Subject:  state.ranges.get(""),
+		})
+	}
+	if state.TimeoutSeconds != nil && state.TimeoutSecondsPath != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Conflicting timeout",
+			Detail:   fmt.Sprintf(`%s has both TimeoutSeconds and TimeoutSecondsPath. Only one of them can be used.`, path),
+			Subject:  state.ranges.get("timeout_seconds_path"),
+		})
+	}
+	if state.HeartbeatSeconds != nil && state.HeartbeatSecondsPath != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Conflicting heartbeat",
+			Detail:   fmt.Sprintf(`%s has both HeartbeatSeconds and HeartbeatSecondsPath. Only one of them can be used.`, path),
+			Subject:  state.ranges.get("heartbeat_seconds_path"),
+		})
+	}
+	if state.TimeoutSeconds != nil && !state.TimeoutSeconds.IsExpression() && state.TimeoutSeconds.Value < 1 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid TimeoutSeconds",
+			Detail:   fmt.Sprintf(`%s.TimeoutSeconds must be a positive integer.`, path),
+			Subject:  state.ranges.get("timeout_seconds"),
+		})
+	}
+	if state.HeartbeatSeconds != nil && !state.HeartbeatSeconds.IsExpression() && state.HeartbeatSeconds.Value < 1 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid HeartbeatSeconds",
+			Detail:   fmt.Sprintf(`%s.HeartbeatSeconds must be a positive integer.`, path),
+			Subject:  state.ranges.get("heartbeat_seconds"),
+		})
+	}
+	if state.TimeoutSeconds != nil && state.HeartbeatSeconds != nil && !state.TimeoutSeconds.IsExpression() && !state.HeartbeatSeconds.IsExpression() &&
+		state.HeartbeatSeconds.Value >= state.TimeoutSeconds.Value {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid HeartbeatSeconds",
+			Detail:   fmt.Sprintf(`%s.HeartbeatSeconds(%d) must be smaller than TimeoutSeconds(%d).`, path, state.HeartbeatSeconds.Value, state.TimeoutSeconds.Value),
+			Subject:  state.ranges.get("heartbeat_seconds"),
+		})
+	}
+	return diags
+}
+
+type transition struct {
+	field   string
+	next    string
+	subject *hcl.Range
+}
+
+// transitions returns all transitions from the state, e.g. Next, Default and Next of Choices and Catch.
+func (state *State) transitions() []transition {
+	var ts []transition
+	if state.Next != nil {
+		ts = append(ts, transition{field: "Next", next: *state.Next, subject: state.ranges.get("next")})
+	}
+	if state.Default != nil {
+		ts = append(ts, transition{field: "Default", next: *state.Default, subject: state.ranges.get("default")})
+	}
+	for i, rule := range state.Choices {
+		if rule.Next != nil {
+			ts = append(ts, transition{field: fmt.Sprintf("Choices[%d].Next", i), next: *rule.Next, subject: rule.ranges.get("next")})
+		}
+	}
+	for i, catcher := range state.Catch {
+		ts = append(ts, transition{field: fmt.Sprintf("Catch[%d].Next", i), next: catcher.Next, subject: catcher.ranges.get("next")})
+	}
+	return ts
+}
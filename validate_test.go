@@ -0,0 +1,436 @@
+package aslconv_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+)
+
+func diagnosticSummaries(diags hcl.Diagnostics) []string {
+	summaries := make([]string, 0, len(diags))
+	for _, diag := range diags {
+		summaries = append(summaries, diag.Summary)
+	}
+	return summaries
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		casename string
+		asl      *aslconv.AmazonStatesLanguage
+		expected []string
+	}{
+		{
+			casename: "sample",
+			asl:      sampleASL,
+		},
+		{
+			casename: "parallel",
+			asl:      parallelASL,
+		},
+		{
+			casename: "others",
+			asl:      othersASL,
+		},
+		{
+			casename: "map_and_parallel",
+			asl:      loadASL(t, "testdata/map_and_parallel.asl.json"),
+		},
+		{
+			casename: "missing_start_at",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Missing",
+				States: aslconv.States{
+					{Name: "Pass", Type: "Pass", End: ptr(true)},
+				},
+			},
+			expected: []string{"Invalid StartAt"},
+		},
+		{
+			casename: "dangling_transitions",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Choice",
+				States: aslconv.States{
+					{
+						Name: "Choice",
+						Type: "Choice",
+						Choices: aslconv.ChoiceRules{
+							{Variable: ptr("$.foo"), Operator: "IsPresent", Value: aslconv.RawMessage(`true`), Next: ptr("Task")},
+						},
+						Default: ptr("Missing"),
+					},
+					{
+						Name:     "Task",
+						Type:     "Task",
+						Resource: ptr("arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"),
+						Catch: aslconv.Catchers{
+							{ErrorEquals: []string{"States.ALL"}, Next: "Handler"},
+						},
+						End: ptr(true),
+					},
+				},
+			},
+			expected: []string{"Invalid transition", "Invalid transition"},
+		},
+		{
+			casename: "next_and_end",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "First",
+				States: aslconv.States{
+					{Name: "First", Type: "Pass", Next: ptr("Second"), End: ptr(true)},
+					{Name: "Second", Type: "Pass"},
+				},
+			},
+			expected: []string{"Conflicting transition", "Missing transition"},
+		},
+		{
+			casename: "unreachable",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "First",
+				States: aslconv.States{
+					{Name: "First", Type: "Succeed"},
+					{Name: "Orphan", Type: "Succeed"},
+				},
+			},
+			expected: []string{"Unreachable state"},
+		},
+		{
+			casename: "unsupported_field",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Task",
+				States: aslconv.States{
+					{
+						Name:     "Task",
+						Type:     "Task",
+						Resource: ptr("arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"),
+						Seconds:  &aslconv.Integer{Value: 10},
+						End:      ptr(true),
+					},
+				},
+			},
+			expected: []string{"Unsupported field"},
+		},
+		{
+			casename: "invalid_choice_rules",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Choice",
+				States: aslconv.States{
+					{
+						Name: "Choice",
+						Type: "Choice",
+						Choices: aslconv.ChoiceRules{
+							{Variable: ptr("$.foo"), Operator: "NumericEquals", Value: aslconv.RawMessage(`"1"`), Next: ptr("Done")},
+							{
+								Not:  &aslconv.ChoiceRule{Variable: ptr("$.foo"), Operator: "IsNull", Value: aslconv.RawMessage(`true`), Next: ptr("Done")},
+								Next: ptr("Done"),
+							},
+							{Variable: ptr("$.foo"), Operator: "IsPresent", Value: aslconv.RawMessage(`true`)},
+						},
+					},
+					{Name: "Done", Type: "Succeed"},
+				},
+			},
+			expected: []string{"Invalid comparison operand", "Unexpected transition", "Missing transition"},
+		},
+		{
+			casename: "invalid_retry_and_catch",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Task",
+				States: aslconv.States{
+					{
+						Name:     "Task",
+						Type:     "Task",
+						Resource: ptr("arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"),
+						Retry: aslconv.Retriers{
+							{ErrorEquals: []string{"States.ALL"}},
+							{ErrorEquals: []string{"ErrorA"}, BackoffRate: ptr(0.5), JitterStrategy: ptr("HALF")},
+						},
+						Catch: aslconv.Catchers{
+							{Next: "Task"},
+						},
+						End: ptr(true),
+					},
+				},
+			},
+			expected: []string{"Invalid ErrorEquals", "Invalid BackoffRate", "Invalid JitterStrategy", "Missing ErrorEquals"},
+		},
+		{
+			casename: "invalid_wait",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Both",
+				States: aslconv.States{
+					{Name: "Both", Type: "Wait", Seconds: &aslconv.Integer{Value: 10}, SecondsPath: ptr("$.seconds"), Next: ptr("None")},
+					{Name: "None", Type: "Wait", Next: ptr("InvalidTimestamp")},
+					{Name: "InvalidTimestamp", Type: "Wait", Timestamp: ptr("2016-03-14 01:59:00"), End: ptr(true)},
+				},
+			},
+			expected: []string{"Invalid wait duration", "Invalid wait duration", "Invalid Timestamp"},
+		},
+		{
+			casename: "invalid_task_timeouts",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Conflicting",
+				States: aslconv.States{
+					{
+						Name:                 "Conflicting",
+						Type:                 "Task",
+						Resource:             ptr("arn:aws:states:::lambda:invoke"),
+						TimeoutSeconds:       &aslconv.Integer{Value: 60},
+						TimeoutSecondsPath:   ptr("$.timeout"),
+						HeartbeatSeconds:     &aslconv.Integer{Value: 30},
+						HeartbeatSecondsPath: ptr("$.heartbeat"),
+						Next:                 ptr("TooLongHeartbeat"),
+					},
+					{
+						Name:             "TooLongHeartbeat",
+						Type:             "Task",
+						Resource:         ptr("arn:aws:states:::lambda:invoke"),
+						TimeoutSeconds:   &aslconv.Integer{Value: 60},
+						HeartbeatSeconds: &aslconv.Integer{Value: 60},
+						End:              ptr(true),
+					},
+				},
+			},
+			expected: []string{"Conflicting timeout", "Conflicting heartbeat", "Invalid HeartbeatSeconds"},
+		},
+		{
+			casename: "distributed_map",
+			asl:      loadASL(t, "testdata/distributed_map.asl.json"),
+		},
+		{
+			casename: "invalid_map",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Inline",
+				States: aslconv.States{
+					{
+						Name:                  "Inline",
+						Type:                  "Map",
+						ToleratedFailureCount: &aslconv.Integer{Value: 1},
+						ItemProcessor: &aslconv.AmazonStatesLanguage{
+							StartAt: "Pass",
+							States:  aslconv.States{{Name: "Pass", Type: "Pass", End: ptr(true)}},
+						},
+						Next: ptr("Distributed"),
+					},
+					{
+						Name:                       "Distributed",
+						Type:                       "Map",
+						MaxConcurrency:             &aslconv.Integer{Value: 10},
+						MaxConcurrencyPath:         ptr("$.concurrency"),
+						ToleratedFailurePercentage: &aslconv.Number{Value: 120.0},
+						ItemProcessor: &aslconv.AmazonStatesLanguage{
+							ProcessorConfig: &aslconv.ProcessorConfig{Mode: ptr("DISTRIBUTED")},
+							StartAt:         "Pass",
+							States:          aslconv.States{{Name: "Pass", Type: "Pass", End: ptr(true)}},
+						},
+						Next: ptr("Neither"),
+					},
+					{Name: "Neither", Type: "Map", End: ptr(true)},
+				},
+			},
+			expected: []string{"Unsupported field", "Missing ExecutionType", "Conflicting fields", "Invalid ToleratedFailurePercentage", "Invalid item processor"},
+		},
+		{
+			casename: "jsonata",
+			asl:      loadASL(t, "testdata/jsonata.asl.json"),
+		},
+		{
+			casename: "jsonpath_fields_in_jsonata",
+			asl: &aslconv.AmazonStatesLanguage{
+				QueryLanguage: ptr("JSONata"),
+				StartAt:       "Task",
+				States: aslconv.States{
+					{
+						Name:       "Task",
+						Type:       "Task",
+						Resource:   ptr("arn:aws:states:::lambda:invoke"),
+						InputPath:  ptr("$.input"),
+						Parameters: aslconv.RawMessage(`{"Payload.$":"$"}`),
+						ResultPath: ptr("$.result"),
+						Next:       ptr("Choice"),
+					},
+					{
+						Name: "Choice",
+						Type: "Choice",
+						Choices: aslconv.ChoiceRules{
+							{Variable: ptr("$.foo"), Operator: "IsPresent", Value: aslconv.RawMessage(`true`), Next: ptr("JSONPath")},
+						},
+						Default: ptr("JSONPath"),
+					},
+					{Name: "JSONPath", Type: "Pass", QueryLanguage: ptr("JSONPath"), End: ptr(true)},
+				},
+			},
+			expected: []string{"Unsupported field", "Unsupported field", "Unsupported field", "Missing Condition", "Invalid QueryLanguage"},
+		},
+		{
+			casename: "jsonpath_path_fields_in_jsonata",
+			asl: &aslconv.AmazonStatesLanguage{
+				QueryLanguage: ptr("JSONata"),
+				StartAt:       "Task",
+				States: aslconv.States{
+					{
+						Name:                 "Task",
+						Type:                 "Task",
+						Resource:             ptr("arn:aws:states:::lambda:invoke"),
+						TimeoutSecondsPath:   ptr("$.timeout"),
+						HeartbeatSecondsPath: ptr("$.heartbeat"),
+						Next:                 ptr("Wait"),
+					},
+					{Name: "Wait", Type: "Wait", SecondsPath: ptr("$.seconds"), Next: ptr("Map")},
+					{
+						Name:               "Map",
+						Type:               "Map",
+						MaxConcurrencyPath: ptr("$.concurrency"),
+						ItemReader: &aslconv.ItemReader{
+							Resource:     "arn:aws:states:::s3:getObject",
+							ReaderConfig: &aslconv.ReaderConfig{InputType: ptr("JSON"), MaxItemsPath: ptr("$.maxItems")},
+						},
+						ItemBatcher: &aslconv.ItemBatcher{MaxItemsPerBatchPath: ptr("$.batchSize")},
+						ItemProcessor: &aslconv.AmazonStatesLanguage{
+							ProcessorConfig: &aslconv.ProcessorConfig{Mode: ptr("DISTRIBUTED"), ExecutionType: ptr("STANDARD")},
+							StartAt:         "Pass",
+							States:          aslconv.States{{Name: "Pass", Type: "Pass", End: ptr(true)}},
+						},
+						End: ptr(true),
+					},
+				},
+			},
+			expected: []string{"Unsupported field", "Unsupported field", "Unsupported field", "Unsupported field", "Unsupported field", "Unsupported field"},
+		},
+		{
+			casename: "jsonata_fields_in_jsonpath",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Task",
+				States: aslconv.States{
+					{
+						Name:      "Task",
+						Type:      "Task",
+						Resource:  ptr("arn:aws:states:::lambda:invoke"),
+						Arguments: aslconv.RawMessage(`{"Payload":"{% $states.input %}"}`),
+						Next:      ptr("JSONata"),
+					},
+					{Name: "JSONata", Type: "Pass", QueryLanguage: ptr("JSONata"), Output: aslconv.RawMessage(`"{% $states.input %}"`), End: ptr(true)},
+				},
+			},
+			expected: []string{"Unsupported field"},
+		},
+		{
+			casename: "jsonata_expressions",
+			asl:      loadASL(t, "testdata/jsonata_expressions.asl.json"),
+		},
+		{
+			casename: "jsonata_expressions_in_jsonpath",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt:        "Task",
+				TimeoutSeconds: &aslconv.Integer{Expression: "{% $states.input.timeout %}"},
+				States: aslconv.States{
+					{
+						Name:           "Task",
+						Type:           "Task",
+						Resource:       ptr("arn:aws:states:::lambda:invoke"),
+						TimeoutSeconds: &aslconv.Integer{Expression: "{% $states.input.timeout %}"},
+						Next:           ptr("Wait"),
+					},
+					{Name: "Wait", Type: "Wait", Timestamp: ptr("{% $states.input.until %}"), End: ptr(true)},
+				},
+			},
+			expected: []string{"Unsupported JSONata expression", "Unsupported JSONata expression", "Unsupported JSONata expression"},
+		},
+		{
+			casename: "invalid_branch",
+			asl: &aslconv.AmazonStatesLanguage{
+				StartAt: "Parallel",
+				States: aslconv.States{
+					{
+						Name: "Parallel",
+						Type: "Parallel",
+						End:  ptr(true),
+						Branches: []*aslconv.AmazonStatesLanguage{
+							{
+								StartAt: "Branch",
+								States: aslconv.States{
+									{Name: "Branch", Type: "Pass", Next: ptr("Missing")},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: []string{"Invalid transition"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			diags := c.asl.Validate()
+			for _, diag := range diags {
+				t.Log(diag.Error())
+			}
+			require.ElementsMatch(t, c.expected, diagnosticSummaries(diags))
+		})
+	}
+}
+
+func TestValidateWithLoadOptions(t *testing.T) {
+	src := []byte(`
+start_at = state.pass.First
+
+state "pass" "First" {
+  next = "Missing"
+}
+`)
+	var diags hcl.Diagnostics
+	_, err := aslconv.FormatHCL.LoadASLWithBytes(src, "invalid.asl.hcl", func(opts *aslconv.LoadOptions) {
+		opts.Validate = true
+		opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+			return nil
+		}
+	})
+	require.ErrorAs(t, err, &diags)
+	require.Len(t, diags, 1)
+	require.Equal(t, "Invalid transition", diags[0].Summary)
+	require.NotNil(t, diags[0].Subject)
+	require.Equal(t, 5, diags[0].Subject.Start.Line)
+}
+
+func TestValidateRetrierSubjects(t *testing.T) {
+	src := []byte(`
+start_at = state.task.Call
+
+state "task" "Call" {
+  resource = "arn:aws:lambda:us-east-1:123456789012:function:FUNCTION_NAME"
+  end      = true
+
+  retry {
+    error_equals      = ["States.ALL"]
+    interval_seconds  = 0
+    max_attempts      = -1
+    backoff_rate      = 0.5
+    max_delay_seconds = 0
+    jitter_strategy   = "PARTIAL"
+  }
+}
+`)
+	var diags hcl.Diagnostics
+	_, err := aslconv.FormatHCL.LoadASLWithBytes(src, "invalid.asl.hcl", func(opts *aslconv.LoadOptions) {
+		opts.Validate = true
+		opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+			return nil
+		}
+	})
+	require.ErrorAs(t, err, &diags)
+	lines := make(map[string]int, len(diags))
+	for _, diag := range diags {
+		require.NotNil(t, diag.Subject, diag.Summary)
+		lines[diag.Summary] = diag.Subject.Start.Line
+	}
+	require.Equal(t, map[string]int{
+		"Invalid IntervalSeconds": 10,
+		"Invalid MaxAttempts":     11,
+		"Invalid BackoffRate":     12,
+		"Invalid MaxDelaySeconds": 13,
+		"Invalid JitterStrategy":  14,
+	}, lines)
+}
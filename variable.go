@@ -0,0 +1,344 @@
+package aslconv
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// VariableEnvPrefix is the prefix of the environment variables that set the input variables, e.g. ASLCONV_VAR_env=prod.
+const VariableEnvPrefix = "ASLCONV_VAR_"
+
+// inputVariable is the input variable declared by a `variable` block.
+type inputVariable struct {
+	Name        string
+	Type        cty.Type
+	Default     *cty.Value
+	Validations []*variableValidation
+	DeclRange   hcl.Range
+}
+
+type variableValidation struct {
+	Condition    hcl.Expression
+	ErrorMessage hcl.Expression
+}
+
+var (
+	variableBlockSchema = hcl.BlockHeaderSchema{
+		Type:       "variable",
+		LabelNames: []string{"name"},
+	}
+	variableBodySchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "description"},
+			{Name: "type"},
+			{Name: "default"},
+		},
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "validation"},
+		},
+	}
+	variableValidationSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "condition", Required: true},
+			{Name: "error_message", Required: true},
+		},
+	}
+)
+
+// EvalContextWithVariables returns the child context of ctx, that has `var` for the input variables declared by the `variable` blocks in the body.
+// The values are taken from values first and then from the default of the declaration.
+// A string value for a variable of a collection or structural type, such as the value of -var option, is parsed as an HCL expression.
+func EvalContextWithVariables(body hcl.Body, ctx *hcl.EvalContext, values map[string]cty.Value) (*hcl.EvalContext, hcl.Diagnostics) {
+	variables, diags := decodeVariableBlocks(body)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	vars := make(map[string]cty.Value, len(variables))
+	for _, v := range variables {
+		value, valueDiags := v.value(values, ctx)
+		diags = append(diags, valueDiags...)
+		vars[v.Name] = value
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := vars[name]; !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Value for undeclared variable",
+				Detail:   fmt.Sprintf(`A value was given for the variable "%s", but it is not declared by a "variable" block.`, name),
+			})
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	child := ctx.NewChild()
+	child.Variables = map[string]cty.Value{
+		"var": cty.ObjectVal(vars),
+	}
+	return child, diags
+}
+
+func decodeVariableBlocks(body hcl.Body) ([]*inputVariable, hcl.Diagnostics) {
+	content, _, diags := body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{variableBlockSchema},
+	})
+	declared := make(map[string]*hcl.Range)
+	var variables []*inputVariable
+	for _, block := range content.Blocks {
+		name := block.Labels[0]
+		if !hclsyntax.ValidIdentifier(name) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid variable name",
+				Detail:   fmt.Sprintf(`The variable name "%s" is invalid. A name must start with a letter or underscore and may contain only letters, digits, underscores, and dashes.`, name),
+				Subject:  block.LabelRanges[0].Ptr(),
+			})
+			continue
+		}
+		if r, ok := declared[name]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate variable declaration",
+				Detail:   fmt.Sprintf(`A variable named "%s" was already declared at %s. Variable names must be unique.`, name, r.String()),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		declared[name] = block.DefRange.Ptr()
+		v, decodeDiags := decodeVariableBlock(block)
+		diags = append(diags, decodeDiags...)
+		variables = append(variables, v)
+	}
+	return variables, diags
+}
+
+func decodeVariableBlock(block *hcl.Block) (*inputVariable, hcl.Diagnostics) {
+	v := &inputVariable{
+		Name:      block.Labels[0],
+		Type:      cty.DynamicPseudoType,
+		DeclRange: block.DefRange,
+	}
+	content, diags := block.Body.Content(variableBodySchema)
+	if attr, ok := content.Attributes["type"]; ok {
+		ty, typeDiags := typeexpr.TypeConstraint(attr.Expr)
+		diags = append(diags, typeDiags...)
+		if !typeDiags.HasErrors() {
+			v.Type = ty
+		}
+	}
+	if attr, ok := content.Attributes["default"]; ok {
+		value, valueDiags := attr.Expr.Value(nil)
+		diags = append(diags, valueDiags...)
+		if !valueDiags.HasErrors() {
+			converted, err := convert.Convert(value, v.Type)
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid default value for variable",
+					Detail:   fmt.Sprintf(`The default value of the variable "%s" is not compatible with the type constraint: %s.`, v.Name, err),
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				v.Default = &converted
+			}
+		}
+	}
+	for _, validationBlock := range content.Blocks {
+		validationContent, validationDiags := validationBlock.Body.Content(variableValidationSchema)
+		diags = append(diags, validationDiags...)
+		if validationDiags.HasErrors() {
+			continue
+		}
+		v.Validations = append(v.Validations, &variableValidation{
+			Condition:    validationContent.Attributes["condition"].Expr,
+			ErrorMessage: validationContent.Attributes["error_message"].Expr,
+		})
+	}
+	return v, diags
+}
+
+// value returns the value of the variable converted to the type constraint, and checks the validation rules.
+func (v *inputVariable) value(values map[string]cty.Value, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	given, ok := values[v.Name]
+	if !ok {
+		if v.Default == nil {
+			return cty.DynamicVal, hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "No value for required variable",
+				Detail:   fmt.Sprintf(`The variable "%s" is required, but no value was given. Set the value with -var, -var-file or %s%s.`, v.Name, VariableEnvPrefix, v.Name),
+				Subject:  v.DeclRange.Ptr(),
+			}}
+		}
+		return v.validate(*v.Default, ctx)
+	}
+	if given.Type() == cty.String && given.IsKnown() && !given.IsNull() && !v.Type.IsPrimitiveType() && v.Type != cty.DynamicPseudoType {
+		expr, diags := hclsyntax.ParseExpression([]byte(given.AsString()), fmt.Sprintf("<value for var.%s>", v.Name), hcl.InitialPos)
+		if diags.HasErrors() {
+			return cty.DynamicVal, diags
+		}
+		parsed, diags := expr.Value(nil)
+		if diags.HasErrors() {
+			return cty.DynamicVal, diags
+		}
+		given = parsed
+	}
+	converted, err := convert.Convert(given, v.Type)
+	if err != nil {
+		return cty.DynamicVal, hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value for input variable",
+			Detail:   fmt.Sprintf(`The given value is not suitable for the variable "%s" declared at %s: %s.`, v.Name, v.DeclRange.String(), err),
+		}}
+	}
+	return v.validate(converted, ctx)
+}
+
+func (v *inputVariable) validate(value cty.Value, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	child := ctx.NewChild()
+	child.Variables = map[string]cty.Value{
+		"var": cty.ObjectVal(map[string]cty.Value{v.Name: value}),
+	}
+	for _, validation := range v.Validations {
+		result, conditionDiags := validation.Condition.Value(child)
+		diags = append(diags, conditionDiags...)
+		if conditionDiags.HasErrors() {
+			continue
+		}
+		result, err := convert.Convert(result, cty.Bool)
+		if err != nil || result.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid validation result",
+				Detail:   "The condition of the validation must be true or false.",
+				Subject:  validation.Condition.Range().Ptr(),
+			})
+			continue
+		}
+		if !result.IsKnown() || result.True() {
+			continue
+		}
+		var message string
+		messageDiags := decodeErrorMessage(validation.ErrorMessage, child, &message)
+		diags = append(diags, messageDiags...)
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value for variable",
+			Detail:   message,
+			Subject:  validation.Condition.Range().Ptr(),
+		})
+	}
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+	return value, diags
+}
+
+func decodeErrorMessage(expr hcl.Expression, ctx *hcl.EvalContext, message *string) hcl.Diagnostics {
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return diags
+	}
+	value, err := convert.Convert(value, cty.String)
+	if err != nil || value.IsNull() || !value.IsKnown() {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid error message",
+			Detail:   "The error message of the validation must be a string.",
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	*message = value.AsString()
+	return diags
+}
+
+// rejectTopLevelOnlyBlocks reports the `variable` and `module` blocks in the body of branch, iterator and item_processor,
+// because input variables and modules can be declared only at the top level.
+func rejectTopLevelOnlyBlocks(body hcl.Body) hcl.Diagnostics {
+	content, _, _ := body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{variableBlockSchema, moduleBlockSchema},
+	})
+	var diags hcl.Diagnostics
+	for _, block := range content.Blocks {
+		detail := "Input variables can be declared only at the top level."
+		if block.Type == "module" {
+			detail = "Modules can be declared only at the top level."
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf(`Unexpected "%s" block`, block.Type),
+			Detail:   detail,
+			Subject:  block.DefRange.Ptr(),
+		})
+	}
+	return diags
+}
+
+// LoadVariablesFile reads the values of the input variables from the file, that has the same syntax as *.tfvars of Terraform.
+// The file is read as JSON when the extension is .json, otherwise as HCL.
+func LoadVariablesFile(path string) (map[string]cty.Value, error) {
+	parser := hclparse.NewParser()
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if filepath.Ext(path) == ".json" {
+		file, diags = parser.ParseJSONFile(path)
+	} else {
+		file, diags = parser.ParseHCLFile(path)
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	values := make(map[string]cty.Value, len(attrs))
+	for name, attr := range attrs {
+		value, valueDiags := attr.Expr.Value(nil)
+		diags = append(diags, valueDiags...)
+		values[name] = value
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return values, nil
+}
+
+// VariablesFromEnviron returns the values of the input variables set by the environment variables with VariableEnvPrefix.
+// environ is in the form of os.Environ, and the values are strings.
+func VariablesFromEnviron(environ []string) map[string]cty.Value {
+	values := make(map[string]cty.Value)
+	for _, env := range environ {
+		key, value, ok := strings.Cut(env, "=")
+		if !ok || !strings.HasPrefix(key, VariableEnvPrefix) {
+			continue
+		}
+		if name := strings.TrimPrefix(key, VariableEnvPrefix); name != "" {
+			values[name] = cty.StringVal(value)
+		}
+	}
+	return values
+}
+
+// ParseVariableFlag parses the value of -var option in the form of `name=value`.
+func ParseVariableFlag(s string) (string, cty.Value, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return "", cty.NilVal, fmt.Errorf("invalid variable %q, the value must be in the form of name=value", s)
+	}
+	return name, cty.StringVal(value), nil
+}
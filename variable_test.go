@@ -0,0 +1,149 @@
+package aslconv_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mashiike/aslconv"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestLoadASLWithVariables(t *testing.T) {
+	fileValues, err := aslconv.LoadVariablesFile("testdata/variables.tfvars")
+	require.NoError(t, err)
+	cases := []struct {
+		casename         string
+		values           map[string]cty.Value
+		environ          []string
+		expectedResource string
+		expectedErrors   []string
+		expectedAttempts int64
+		expectedSummary  string
+	}{
+		{
+			casename: "default",
+			values: map[string]cty.Value{
+				"max_attempts": cty.NumberIntVal(3),
+			},
+			expectedResource: "arn:aws:lambda:us-east-1:123456789012:function:dev-invoke",
+			expectedErrors:   []string{"States.ALL"},
+			expectedAttempts: 3,
+		},
+		{
+			casename:         "variables_file",
+			values:           fileValues,
+			expectedResource: "arn:aws:lambda:us-east-1:123456789012:function:prod-invoke",
+			expectedErrors:   []string{"States.ALL"},
+			expectedAttempts: 5,
+		},
+		{
+			casename: "environ",
+			environ: []string{
+				"ASLCONV_VAR_env=prod",
+				"ASLCONV_VAR_max_attempts=2",
+				`ASLCONV_VAR_errors=["States.Timeout", "States.TaskFailed"]`,
+				"ASLCONV_VAR_undeclared=ignored",
+				"HOME=/root",
+			},
+			expectedResource: "arn:aws:lambda:us-east-1:123456789012:function:prod-invoke",
+			expectedErrors:   []string{"States.Timeout", "States.TaskFailed"},
+			expectedAttempts: 2,
+		},
+		{
+			casename: "environ_overridden",
+			values: map[string]cty.Value{
+				"max_attempts": cty.NumberIntVal(4),
+			},
+			environ: []string{
+				"ASLCONV_VAR_env=prod",
+				"ASLCONV_VAR_max_attempts=2",
+			},
+			expectedResource: "arn:aws:lambda:us-east-1:123456789012:function:prod-invoke",
+			expectedErrors:   []string{"States.ALL"},
+			expectedAttempts: 4,
+		},
+		{
+			casename:        "required",
+			values:          map[string]cty.Value{},
+			expectedSummary: "No value for required variable",
+		},
+		{
+			casename: "validation",
+			values: map[string]cty.Value{
+				"env":          cty.StringVal("stg"),
+				"max_attempts": cty.NumberIntVal(3),
+			},
+			expectedSummary: "Invalid value for variable",
+		},
+		{
+			casename: "invalid_type",
+			values: map[string]cty.Value{
+				"max_attempts": cty.StringVal("three"),
+			},
+			expectedSummary: "Invalid value for input variable",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.casename, func(t *testing.T) {
+			asl, err := aslconv.LoadASLWithPath("testdata/variables.asl.hcl", func(opts *aslconv.LoadOptions) {
+				opts.Variables = c.values
+				opts.EnvironVariables = aslconv.VariablesFromEnviron(c.environ)
+				opts.HCLDiagnosticWriterInitializer = func(_ *hclparse.Parser) hcl.DiagnosticWriter {
+					return nil
+				}
+			})
+			if c.expectedSummary != "" {
+				var diags hcl.Diagnostics
+				require.ErrorAs(t, err, &diags)
+				require.Equal(t, []string{c.expectedSummary}, diagnosticSummaries(diags))
+				return
+			}
+			require.NoError(t, err)
+			state := asl.States[0]
+			require.Equal(t, c.expectedResource, *state.Resource)
+			require.Len(t, state.Retry, 1)
+			require.Equal(t, c.expectedErrors, state.Retry[0].ErrorEquals)
+			require.Equal(t, c.expectedAttempts, *state.Retry[0].MaxAttempts)
+		})
+	}
+}
+
+func TestEvalContextWithVariables(t *testing.T) {
+	src := []byte(`
+variable "env" {
+  default = "dev"
+}
+
+start_at = state.parallel.Parallel
+
+state "parallel" "Parallel" {
+  end = true
+
+  branch {
+    start_at = state.pass.Pass
+
+    variable "nested" {}
+
+    state "pass" "Pass" {
+      end = true
+    }
+  }
+}
+`)
+	file, diags := hclparse.NewParser().ParseHCL(src, "variables.asl.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+	ctx, diags := aslconv.EvalContextWithVariables(file.Body, &hcl.EvalContext{}, map[string]cty.Value{
+		"undeclared": cty.StringVal("value"),
+	})
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Equal(t, []string{"Value for undeclared variable"}, diagnosticSummaries(diags))
+	require.Equal(t, cty.ObjectVal(map[string]cty.Value{
+		"env": cty.StringVal("dev"),
+	}), ctx.Variables["var"])
+
+	var asl aslconv.AmazonStatesLanguage
+	diags = asl.DecodeBody(file.Body, ctx)
+	require.Equal(t, []string{`Unexpected "variable" block`}, diagnosticSummaries(diags))
+}